@@ -2,6 +2,7 @@ package ast
 
 import (
 	"fmt"
+	"math/big"
 	"strconv"
 	"strings"
 
@@ -12,6 +13,7 @@ var (
 	_ Node       = (*Program)(nil)
 	_ Node       = (*Identifier)(nil)
 	_ Node       = (*IntegerLiteral)(nil)
+	_ Node       = (*FloatLiteral)(nil)
 	_ Node       = (*PrefixExpression)(nil)
 	_ Node       = (*InfixExpression)(nil)
 	_ Node       = (*Boolean)(nil)
@@ -21,13 +23,16 @@ var (
 	_ Expression = (*InfixExpression)(nil)
 	_ Expression = (*PrefixExpression)(nil)
 	_ Expression = (*IntegerLiteral)(nil)
+	_ Expression = (*FloatLiteral)(nil)
 	_ Expression = (*StringLiteral)(nil)
 	_ Expression = (*IfExpression)(nil)
 	_ Expression = (*FunctionLiteral)(nil)
 	_ Expression = (*IndexExpression)(nil)
+	_ Expression = (*SliceExpression)(nil)
 	_ Expression = (*ArrayLiteral)(nil)
 	_ Expression = (*HashLiteral)(nil)
 	_ Expression = (*WhileExpression)(nil)
+	_ Expression = (*TypeExpr)(nil)
 	_ Statement  = (*LetStatement)(nil)
 	_ Statement  = (*ReturnStatement)(nil)
 	_ Statement  = (*ExpressionStatement)(nil)
@@ -73,6 +78,9 @@ func (p *Program) String() string {
 type Identifier struct {
 	Token token.Token // the token.IDENT token
 	Value []byte
+	// Type is the optional annotation of identifiers in let statements and
+	// function parameters, e.g. the int in `let x: int = 5`
+	Type *TypeExpr
 }
 
 func (*Identifier) expressionNode()        {}
@@ -85,6 +93,40 @@ func (i *Identifier) String() string {
 	return string(i.Value)
 }
 
+// TypeExpr is a type annotation, e.g. int, array<int> or hash<string, int>.
+type TypeExpr struct {
+	Token  token.Token // the token.IDENT token of the type name
+	Name   string
+	Params []*TypeExpr
+}
+
+func (*TypeExpr) expressionNode()        {}
+func (t *TypeExpr) TokenLiteral() string { return string(t.Token.Literal) }
+func (t *TypeExpr) String() string {
+	if t == nil {
+		return ""
+	}
+	if len(t.Params) == 0 {
+		return t.Name
+	}
+
+	params := make([]string, len(t.Params))
+	for i, p := range t.Params {
+		params[i] = p.String()
+	}
+
+	return fmt.Sprintf("%s<%s>", t.Name, strings.Join(params, ", "))
+}
+
+// declaration returns the identifier along with its type annotation, if any.
+func declaration(i *Identifier) string {
+	if i.Type == nil {
+		return i.String()
+	}
+
+	return fmt.Sprintf("%s: %s", i.String(), i.Type.String())
+}
+
 /* Statements */
 
 type ReturnStatement struct {
@@ -118,7 +160,7 @@ func (ls *LetStatement) String() string {
 	return fmt.Sprintf(
 		"%s %s = %s;",
 		ls.TokenLiteral(),
-		ls.Name.String(),
+		declaration(ls.Name),
 		ls.Value.String(),
 	)
 }
@@ -142,6 +184,8 @@ func (ex *ExpressionStatement) String() string {
 type IntegerLiteral struct {
 	Token token.Token
 	Value int
+	// Big holds the value of literals that don't fit an int, Value is 0 then.
+	Big *big.Int
 }
 
 func (*IntegerLiteral) expressionNode()        {}
@@ -150,10 +194,33 @@ func (i *IntegerLiteral) String() string {
 	if i == nil {
 		return ""
 	}
+	if i.Big != nil {
+		return i.Big.String()
+	}
 
 	return strconv.Itoa(i.Value)
 }
 
+type FloatLiteral struct {
+	Token token.Token
+	Value float64
+}
+
+func (*FloatLiteral) expressionNode()        {}
+func (f *FloatLiteral) TokenLiteral() string { return string(f.Token.Literal) }
+func (f *FloatLiteral) String() string       { return FormatFloat(f.Value) }
+
+// FormatFloat formats f the shortest way that reads back as the same float,
+// always with a fraction or exponent so it can't be confused with an integer.
+func FormatFloat(f float64) string {
+	s := strconv.FormatFloat(f, 'g', -1, 64)
+	if strings.ContainsAny(s, ".eIN") {
+		return s
+	}
+
+	return s + ".0"
+}
+
 type StringLiteral struct {
 	Token token.Token
 	Value string
@@ -246,6 +313,8 @@ func (ix *IfExpression) String() string {
 type FunctionLiteral struct {
 	Token      token.Token // the 'fn' token
 	Parameters []*Identifier
+	// ReturnType is the optional annotation of the returned value
+	ReturnType *TypeExpr
 	Body       *BlockStatement
 }
 
@@ -255,7 +324,17 @@ func (fl *FunctionLiteral) String() string {
 	params := make([]string, len(fl.Parameters))
 
 	for i, param := range fl.Parameters {
-		params[i] = param.String()
+		params[i] = declaration(param)
+	}
+
+	if fl.ReturnType != nil {
+		return fmt.Sprintf(
+			"%s(%s): %s%s",
+			fl.TokenLiteral(),
+			strings.Join(params, ","),
+			fl.ReturnType.String(),
+			fl.Body.String(),
+		)
 	}
 
 	return fmt.Sprintf("%s(%s)%s", fl.TokenLiteral(), strings.Join(params, ","), fl.Body.String())
@@ -296,8 +375,9 @@ func (al *ArrayLiteral) String() string {
 	return fmt.Sprintf("[%s]", strings.Join(args, ", "))
 }
 
+// IndexExpression is xs[index], or x.name which indexes x with the string name.
 type IndexExpression struct {
-	Token token.Token // the [ token
+	Token token.Token // the [ or . token
 	Left  Expression
 	Index Expression
 }
@@ -305,7 +385,42 @@ type IndexExpression struct {
 func (*IndexExpression) expressionNode()         {}
 func (ie *IndexExpression) TokenLiteral() string { return string(ie.Token.Literal) }
 
-func (ie *IndexExpression) String() string { return fmt.Sprintf("(%s[%s])", ie.Left, ie.Index) }
+func (ie *IndexExpression) String() string {
+	if ie.Token.Type == token.DOT {
+		return fmt.Sprintf("(%s.%s)", ie.Left, ie.Index)
+	}
+
+	return fmt.Sprintf("(%s[%s])", ie.Left, ie.Index)
+}
+
+// SliceExpression is xs[start:end] or xs[start:end:step], each part is nil when
+// omitted.
+type SliceExpression struct {
+	Token token.Token // the [ token
+	Left  Expression
+	Start Expression
+	End   Expression
+	Step  Expression
+}
+
+func (*SliceExpression) expressionNode()         {}
+func (se *SliceExpression) TokenLiteral() string { return string(se.Token.Literal) }
+
+func (se *SliceExpression) String() string {
+	part := func(e Expression) string {
+		if e == nil {
+			return ""
+		}
+		return e.String()
+	}
+
+	s := part(se.Start) + ":" + part(se.End)
+	if se.Step != nil {
+		s += ":" + se.Step.String()
+	}
+
+	return fmt.Sprintf("(%s[%s])", se.Left, s)
+}
 
 type HashLiteral struct {
 	Token token.Token // the '{' token
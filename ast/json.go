@@ -0,0 +1,511 @@
+package ast
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/gkampitakis/monkey/token"
+)
+
+// operators maps the operators of prefix and infix expressions to their tokens.
+var operators = map[string]token.TokenType{
+	"+":  token.PLUS,
+	"-":  token.MINUS,
+	"!":  token.BANG,
+	"*":  token.ASTERISK,
+	"/":  token.SLASH,
+	"<":  token.LT,
+	">":  token.GT,
+	"==": token.EQ,
+	"!=": token.NEQ,
+	"**": token.POW,
+}
+
+type jsonObject = map[string]interface{}
+
+// MarshalJSON encodes node as JSON. Every node is an object with a "kind" field
+// holding its type name, e.g. "LetStatement", and a "pos" field with the line
+// and column of its token.
+func MarshalJSON(node Node) ([]byte, error) {
+	return json.Marshal(encode(node))
+}
+
+func encode(node Node) interface{} {
+	var o jsonObject
+
+	switch node := node.(type) {
+	case *Program:
+		return jsonObject{"kind": "Program", "statements": encodeStatements(node.Statements)}
+	case *LetStatement:
+		o = jsonObject{"name": encode(node.Name), "value": encode(node.Value)}
+	case *ReturnStatement:
+		o = jsonObject{"value": encode(node.ReturnValue)}
+	case *ExpressionStatement:
+		o = jsonObject{"expression": encode(node.Expression)}
+	case *BlockStatement:
+		o = jsonObject{"statements": encodeStatements(node.Statements)}
+	case *Identifier:
+		o = jsonObject{"value": string(node.Value)}
+		if node.Type != nil {
+			o["type"] = encode(node.Type)
+		}
+	case *TypeExpr:
+		params := make([]interface{}, len(node.Params))
+		for i, p := range node.Params {
+			params[i] = encode(p)
+		}
+		o = jsonObject{"name": node.Name, "params": params}
+	case *IntegerLiteral:
+		o = jsonObject{"value": node.Value}
+		if node.Big != nil {
+			o["value"] = json.Number(node.Big.String())
+		}
+	case *FloatLiteral:
+		o = jsonObject{"value": node.Value}
+	case *StringLiteral:
+		o = jsonObject{"value": node.Value}
+	case *Boolean:
+		o = jsonObject{"value": node.Value}
+	case *PrefixExpression:
+		o = jsonObject{"operator": node.Operator, "right": encode(node.Right)}
+	case *InfixExpression:
+		o = jsonObject{"operator": node.Operator, "left": encode(node.Left), "right": encode(node.Right)}
+	case *IfExpression:
+		o = jsonObject{"condition": encode(node.Condition), "consequence": encode(node.Consequence)}
+		if node.Alternative != nil {
+			o["alternative"] = encode(node.Alternative)
+		}
+	case *WhileExpression:
+		o = jsonObject{"condition": encode(node.Condition), "body": encode(node.Consequence)}
+	case *FunctionLiteral:
+		params := make([]interface{}, len(node.Parameters))
+		for i, p := range node.Parameters {
+			params[i] = encode(p)
+		}
+		o = jsonObject{"parameters": params, "body": encode(node.Body)}
+		if node.ReturnType != nil {
+			o["returnType"] = encode(node.ReturnType)
+		}
+	case *CallExpression:
+		o = jsonObject{"function": encode(node.Function), "arguments": encodeExpressions(node.Arguments)}
+	case *ArrayLiteral:
+		o = jsonObject{"elements": encodeExpressions(node.Elements)}
+	case *IndexExpression:
+		o = jsonObject{"left": encode(node.Left), "index": encode(node.Index)}
+		if node.Token.Type == token.DOT {
+			o["operator"] = "."
+		}
+	case *SliceExpression:
+		o = jsonObject{"left": encode(node.Left)}
+		if node.Start != nil {
+			o["start"] = encode(node.Start)
+		}
+		if node.End != nil {
+			o["end"] = encode(node.End)
+		}
+		if node.Step != nil {
+			o["step"] = encode(node.Step)
+		}
+	case *HashLiteral:
+		pairs := []interface{}{}
+		for _, key := range HashKeys(node) {
+			pairs = append(pairs, jsonObject{"key": encode(key), "value": encode(node.Pairs[key])})
+		}
+		o = jsonObject{"pairs": pairs}
+	default:
+		return nil
+	}
+
+	o["kind"] = kind(node)
+	pos := Pos(node)
+	o["pos"] = jsonObject{"line": pos.Line, "column": pos.Column}
+
+	return o
+}
+
+// kind returns the type name of node, e.g. LetStatement.
+func kind(node Node) string {
+	name := fmt.Sprintf("%T", node)
+	return name[len("*ast."):]
+}
+
+func encodeStatements(stmts []Statement) []interface{} {
+	out := make([]interface{}, len(stmts))
+	for i, stmt := range stmts {
+		out[i] = encode(stmt)
+	}
+
+	return out
+}
+
+func encodeExpressions(exps []Expression) []interface{} {
+	out := make([]interface{}, len(exps))
+	for i, exp := range exps {
+		out[i] = encode(exp)
+	}
+
+	return out
+}
+
+// HashKeys returns the keys of a hash literal in source order.
+func HashKeys(h *HashLiteral) []Expression {
+	keys := make([]Expression, 0, len(h.Pairs))
+	for k := range h.Pairs {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := Pos(keys[i]), Pos(keys[j])
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+
+	return keys
+}
+
+// UnmarshalJSON decodes a node encoded by MarshalJSON.
+func UnmarshalJSON(data []byte) (Node, error) {
+	var raw rawNode
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	return decode(&raw)
+}
+
+// rawNode holds the fields of every kind of encoded node.
+type rawNode struct {
+	Kind string `json:"kind"`
+	Pos  struct {
+		Line   int `json:"line"`
+		Column int `json:"column"`
+	} `json:"pos"`
+	Value       json.RawMessage `json:"value"`
+	Name        json.RawMessage `json:"name"`
+	Type        *rawNode        `json:"type"`
+	Operator    string          `json:"operator"`
+	Expression  *rawNode        `json:"expression"`
+	Left        *rawNode        `json:"left"`
+	Right       *rawNode        `json:"right"`
+	Condition   *rawNode        `json:"condition"`
+	Consequence *rawNode        `json:"consequence"`
+	Alternative *rawNode        `json:"alternative"`
+	Body        *rawNode        `json:"body"`
+	Function    *rawNode        `json:"function"`
+	Index       *rawNode        `json:"index"`
+	Start       *rawNode        `json:"start"`
+	End         *rawNode        `json:"end"`
+	Step        *rawNode        `json:"step"`
+	ReturnType  *rawNode        `json:"returnType"`
+	Statements  []*rawNode      `json:"statements"`
+	Parameters  []*rawNode      `json:"parameters"`
+	Arguments   []*rawNode      `json:"arguments"`
+	Elements    []*rawNode      `json:"elements"`
+	Params      []*rawNode      `json:"params"`
+	Pairs       []struct {
+		Key   *rawNode `json:"key"`
+		Value *rawNode `json:"value"`
+	} `json:"pairs"`
+}
+
+func (r *rawNode) token(t token.TokenType, literal string) token.Token {
+	return token.Token{
+		Type:    t,
+		Literal: []byte(literal),
+		Pos:     token.Position{Line: r.Pos.Line, Column: r.Pos.Column},
+	}
+}
+
+// decoder records the first error while decoding a tree.
+type decoder struct {
+	err error
+}
+
+func decode(r *rawNode) (Node, error) {
+	d := &decoder{}
+	node := d.node(r)
+
+	return node, d.err
+}
+
+func (d *decoder) fail(format string, a ...interface{}) {
+	if d.err == nil {
+		d.err = fmt.Errorf(format, a...)
+	}
+}
+
+func (d *decoder) value(r *rawNode, v interface{}) {
+	if err := json.Unmarshal(r.Value, v); err != nil {
+		d.fail("invalid value of %s at %d:%d: %v", r.Kind, r.Pos.Line, r.Pos.Column, err)
+	}
+}
+
+func (d *decoder) node(r *rawNode) Node {
+	if r == nil {
+		return nil
+	}
+
+	switch r.Kind {
+	case "Program":
+		return &Program{Statements: d.statements(r.Statements)}
+	case "LetStatement":
+		var name rawNode
+		if err := json.Unmarshal(r.Name, &name); err != nil {
+			d.fail("invalid name of LetStatement at %d:%d: %v", r.Pos.Line, r.Pos.Column, err)
+		}
+		ident, _ := d.node(&name).(*Identifier)
+		return &LetStatement{Token: r.token(token.LET, "let"), Name: ident, Value: d.expression(r.valueNode(d))}
+	case "ReturnStatement":
+		return &ReturnStatement{Token: r.token(token.RETURN, "return"), ReturnValue: d.expression(r.valueNode(d))}
+	case "ExpressionStatement":
+		exp := d.expression(r.Expression)
+		tok := firstToken(exp)
+		tok.Pos = token.Position{Line: r.Pos.Line, Column: r.Pos.Column}
+		return &ExpressionStatement{Token: tok, Expression: exp}
+	case "BlockStatement":
+		return d.block(r)
+	case "Identifier":
+		var value string
+		d.value(r, &value)
+		ident := &Identifier{Token: r.token(token.IDENT, value), Value: []byte(value)}
+		if r.Type != nil {
+			ident.Type = d.typeExpr(r.Type)
+		}
+		return ident
+	case "TypeExpr":
+		return d.typeExpr(r)
+	case "FloatLiteral":
+		var value float64
+		d.value(r, &value)
+		return &FloatLiteral{Token: r.token(token.FLOAT, FormatFloat(value)), Value: value}
+	case "IntegerLiteral":
+		lit := &IntegerLiteral{}
+		// values that don't fit an int are big integers
+		if n, ok := new(big.Int).SetString(string(r.Value), 10); ok && !n.IsInt64() {
+			lit.Big = n
+		} else {
+			d.value(r, &lit.Value)
+		}
+		lit.Token = r.token(token.INT, lit.String())
+		return lit
+	case "StringLiteral":
+		var value string
+		d.value(r, &value)
+		return &StringLiteral{Token: r.token(token.STRING_QUOTE, value), Value: value}
+	case "Boolean":
+		var value bool
+		d.value(r, &value)
+		if value {
+			return &Boolean{Token: r.token(token.TRUE, "true"), Value: true}
+		}
+		return &Boolean{Token: r.token(token.FALSE, "false"), Value: false}
+	case "PrefixExpression":
+		return &PrefixExpression{
+			Token:    d.operator(r),
+			Operator: r.Operator,
+			Right:    d.expression(r.Right),
+		}
+	case "InfixExpression":
+		return &InfixExpression{
+			Token:    d.operator(r),
+			Operator: r.Operator,
+			Left:     d.expression(r.Left),
+			Right:    d.expression(r.Right),
+		}
+	case "IfExpression":
+		exp := &IfExpression{
+			Token:       r.token(token.IF, "if"),
+			Condition:   d.expression(r.Condition),
+			Consequence: d.block(r.Consequence),
+		}
+		if r.Alternative != nil {
+			exp.Alternative = d.block(r.Alternative)
+		}
+		return exp
+	case "WhileExpression":
+		return &WhileExpression{
+			Token:       r.token(token.WHILE, "while"),
+			Condition:   d.expression(r.Condition),
+			Consequence: d.block(r.Body),
+		}
+	case "FunctionLiteral":
+		fn := &FunctionLiteral{Token: r.token(token.FUNCTION, "fn"), Body: d.block(r.Body)}
+		fn.Parameters = make([]*Identifier, len(r.Parameters))
+		for i, p := range r.Parameters {
+			fn.Parameters[i], _ = d.node(p).(*Identifier)
+		}
+		if r.ReturnType != nil {
+			fn.ReturnType = d.typeExpr(r.ReturnType)
+		}
+		return fn
+	case "CallExpression":
+		return &CallExpression{
+			Token:     r.token(token.LPAREN, "("),
+			Function:  d.expression(r.Function),
+			Arguments: d.expressions(r.Arguments),
+		}
+	case "ArrayLiteral":
+		return &ArrayLiteral{Token: r.token(token.LBRACKET, "["), Elements: d.expressions(r.Elements)}
+	case "IndexExpression":
+		tok := r.token(token.LBRACKET, "[")
+		if r.Operator == "." {
+			tok = r.token(token.DOT, ".")
+		}
+		return &IndexExpression{
+			Token: tok,
+			Left:  d.expression(r.Left),
+			Index: d.expression(r.Index),
+		}
+	case "SliceExpression":
+		return &SliceExpression{
+			Token: r.token(token.LBRACKET, "["),
+			Left:  d.expression(r.Left),
+			Start: d.expression(r.Start),
+			End:   d.expression(r.End),
+			Step:  d.expression(r.Step),
+		}
+	case "HashLiteral":
+		pairs := make(map[Expression]Expression, len(r.Pairs))
+		for _, p := range r.Pairs {
+			pairs[d.expression(p.Key)] = d.expression(p.Value)
+		}
+		return &HashLiteral{Token: r.token(token.LBRACE, "{"), Pairs: pairs}
+	}
+
+	d.fail("unknown node kind %q at %d:%d", r.Kind, r.Pos.Line, r.Pos.Column)
+	return nil
+}
+
+// valueNode decodes the value field holding a node, as in let and return
+// statements.
+func (r *rawNode) valueNode(d *decoder) *rawNode {
+	if len(r.Value) == 0 || string(r.Value) == "null" {
+		return nil
+	}
+
+	var value rawNode
+	if err := json.Unmarshal(r.Value, &value); err != nil {
+		d.fail("invalid value of %s at %d:%d: %v", r.Kind, r.Pos.Line, r.Pos.Column, err)
+		return nil
+	}
+
+	return &value
+}
+
+// operator returns the token of the operator of a prefix or infix expression.
+func (d *decoder) operator(r *rawNode) token.Token {
+	tType, ok := operators[r.Operator]
+	if !ok {
+		d.fail("unknown operator %q of %s at %d:%d", r.Operator, r.Kind, r.Pos.Line, r.Pos.Column)
+	}
+
+	return r.token(tType, r.Operator)
+}
+
+func (d *decoder) expression(r *rawNode) Expression {
+	node := d.node(r)
+	if node == nil {
+		return nil
+	}
+
+	exp, ok := node.(Expression)
+	if !ok {
+		d.fail("expected an expression at %d:%d, got %s", r.Pos.Line, r.Pos.Column, r.Kind)
+	}
+
+	return exp
+}
+
+func (d *decoder) expressions(rs []*rawNode) []Expression {
+	exps := make([]Expression, len(rs))
+	for i, r := range rs {
+		exps[i] = d.expression(r)
+	}
+
+	return exps
+}
+
+func (d *decoder) statements(rs []*rawNode) []Statement {
+	stmts := make([]Statement, 0, len(rs))
+	for _, r := range rs {
+		if r == nil {
+			d.fail("unexpected null statement")
+			continue
+		}
+		stmt, ok := d.node(r).(Statement)
+		if !ok {
+			d.fail("expected a statement at %d:%d, got %s", r.Pos.Line, r.Pos.Column, r.Kind)
+			continue
+		}
+		stmts = append(stmts, stmt)
+	}
+
+	return stmts
+}
+
+func (d *decoder) block(r *rawNode) *BlockStatement {
+	if r == nil {
+		return nil
+	}
+
+	return &BlockStatement{Token: r.token(token.LBRACE, "{"), Statements: d.statements(r.Statements)}
+}
+
+func (d *decoder) typeExpr(r *rawNode) *TypeExpr {
+	var name string
+	if err := json.Unmarshal(r.Name, &name); err != nil {
+		d.fail("invalid name of TypeExpr at %d:%d: %v", r.Pos.Line, r.Pos.Column, err)
+	}
+
+	t := &TypeExpr{Token: r.token(token.IDENT, name), Name: name}
+	if name == "fn" {
+		t.Token.Type = token.FUNCTION
+	}
+	for _, p := range r.Params {
+		t.Params = append(t.Params, d.typeExpr(p))
+	}
+
+	return t
+}
+
+// firstToken returns the token an expression starts with, used as the token of
+// expression statements.
+func firstToken(exp Expression) token.Token {
+	switch exp := exp.(type) {
+	case *InfixExpression:
+		return firstToken(exp.Left)
+	case *CallExpression:
+		return firstToken(exp.Function)
+	case *IndexExpression:
+		return firstToken(exp.Left)
+	case *SliceExpression:
+		return firstToken(exp.Left)
+	case *Identifier:
+		return exp.Token
+	case *IntegerLiteral:
+		return exp.Token
+	case *FloatLiteral:
+		return exp.Token
+	case *StringLiteral:
+		return exp.Token
+	case *Boolean:
+		return exp.Token
+	case *PrefixExpression:
+		return exp.Token
+	case *IfExpression:
+		return exp.Token
+	case *WhileExpression:
+		return exp.Token
+	case *FunctionLiteral:
+		return exp.Token
+	case *ArrayLiteral:
+		return exp.Token
+	case *HashLiteral:
+		return exp.Token
+	}
+
+	return token.Token{}
+}
@@ -0,0 +1,135 @@
+package ast_test
+
+import (
+	"testing"
+
+	"github.com/gkampitakis/monkey/ast"
+	"github.com/gkampitakis/monkey/lexer"
+	"github.com/gkampitakis/monkey/parser"
+	"github.com/stretchr/testify/require"
+)
+
+func parse(t *testing.T, input string) *ast.Program {
+	t.Helper()
+
+	p := parser.New(lexer.New([]byte(input)))
+	program := p.ParseProgram()
+	require.Empty(t, p.Errors())
+
+	return program
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	tests := []string{
+		"let x = 5;",
+		"let x: hash<string, array<int>> = {};",
+		"return a + b * -c;",
+		`let s = "hello" + " " + "world";`,
+		"if (a < b) { true } else { !false };",
+		"while (i > 0) { let i = i - 1; }",
+		"let add = fn(a: int, b): int { return a + b; };",
+		"let f: fn = fn() {};",
+		`add(1, [2, 3][0], {}["a"]);`,
+		"xs[1:-1:2]; xs[:]; xs[::2];",
+		"let area = math.pi * r ** 2 + 0.5;",
+		"let big = 99999999999999999999 * -9223372036854775808;",
+		"let nested = fn(x) { fn(y) { if (x == y) { x } } };",
+	}
+
+	for _, input := range tests {
+		t.Run(input, func(t *testing.T) {
+			program := parse(t, input)
+
+			data, err := ast.MarshalJSON(program)
+			require.NoError(t, err)
+
+			decoded, err := ast.UnmarshalJSON(data)
+			require.NoError(t, err)
+			require.Equal(t, program, decoded)
+
+			again, err := ast.MarshalJSON(decoded)
+			require.NoError(t, err)
+			require.JSONEq(t, string(data), string(again))
+		})
+	}
+}
+
+func TestJSONRoundTripTree(t *testing.T) {
+	// the '(' token of grouped expressions isn't encoded and hash literals are
+	// keyed by pointers, so only the tree and the positions are compared
+	tests := []string{
+		"(1 + 2) * 3;",
+		`let h = {"a": 1, 2: true, false: "c"};`,
+	}
+
+	for _, input := range tests {
+		t.Run(input, func(t *testing.T) {
+			program := parse(t, input)
+
+			data, err := ast.MarshalJSON(program)
+			require.NoError(t, err)
+
+			decoded, err := ast.UnmarshalJSON(data)
+			require.NoError(t, err)
+			require.Equal(t, ast.Sprint(program), ast.Sprint(decoded))
+
+			again, err := ast.MarshalJSON(decoded)
+			require.NoError(t, err)
+			require.JSONEq(t, string(data), string(again))
+		})
+	}
+}
+
+func TestMarshalJSON(t *testing.T) {
+	data, err := ast.MarshalJSON(parse(t, "-a;"))
+	require.NoError(t, err)
+
+	require.JSONEq(t, `{
+  "kind": "Program",
+  "statements": [
+    {
+      "kind": "ExpressionStatement",
+      "pos": {"line": 1, "column": 1},
+      "expression": {
+        "kind": "PrefixExpression",
+        "pos": {"line": 1, "column": 1},
+        "operator": "-",
+        "right": {"kind": "Identifier", "pos": {"line": 1, "column": 2}, "value": "a"}
+      }
+    }
+  ]
+}`, string(data))
+}
+
+func TestUnmarshalJSONErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{"kind": "Program", "statements": [{"kind": "Unknown"}]}`, `unknown node kind "Unknown" at 0:0`},
+		{
+			`{"kind": "ExpressionStatement", "expression": {"kind": "LetStatement", "pos": {"line": 1, "column": 2}}}`,
+			"invalid name of LetStatement at 1:2: unexpected end of JSON input",
+		},
+		{
+			`{"kind": "IntegerLiteral", "pos": {"line": 3, "column": 4}, "value": "5"}`,
+			"invalid value of IntegerLiteral at 3:4: json: cannot unmarshal string into Go value of type int",
+		},
+		{
+			`{"kind": "InfixExpression", "pos": {"line": 1, "column": 3}, "operator": "%", "left": {"kind": "IntegerLiteral", "value": 1}, "right": {"kind": "IntegerLiteral", "value": 2}}`,
+			`unknown operator "%" of InfixExpression at 1:3`,
+		},
+		{
+			`{"kind": "PrefixExpression", "operator": "", "right": {"kind": "Boolean", "value": true}}`,
+			`unknown operator "" of PrefixExpression at 0:0`,
+		},
+		{`[]`, "json: cannot unmarshal array into Go value of type ast.rawNode"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.input, func(t *testing.T) {
+			_, err := ast.UnmarshalJSON([]byte(tc.input))
+			require.EqualError(t, err, tc.expected)
+		})
+	}
+}
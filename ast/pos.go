@@ -0,0 +1,56 @@
+package ast
+
+import "github.com/gkampitakis/monkey/token"
+
+// Pos returns the position of the token node starts with, or of its operator
+// for infix, call and index expressions.
+func Pos(node Node) token.Position {
+	switch node := node.(type) {
+	case *Program:
+		if len(node.Statements) > 0 {
+			return Pos(node.Statements[0])
+		}
+	case *Identifier:
+		return node.Token.Pos
+	case *LetStatement:
+		return node.Token.Pos
+	case *ReturnStatement:
+		return node.Token.Pos
+	case *ExpressionStatement:
+		return node.Token.Pos
+	case *BlockStatement:
+		return node.Token.Pos
+	case *IntegerLiteral:
+		return node.Token.Pos
+	case *FloatLiteral:
+		return node.Token.Pos
+	case *StringLiteral:
+		return node.Token.Pos
+	case *Boolean:
+		return node.Token.Pos
+	case *PrefixExpression:
+		return node.Token.Pos
+	case *InfixExpression:
+		return node.Token.Pos
+	case *IfExpression:
+		return node.Token.Pos
+	case *WhileExpression:
+		return node.Token.Pos
+	case *FunctionLiteral:
+		return node.Token.Pos
+	case *CallExpression:
+		return node.Token.Pos
+	case *ArrayLiteral:
+		return node.Token.Pos
+	case *IndexExpression:
+		return node.Token.Pos
+	case *SliceExpression:
+		return node.Token.Pos
+	case *HashLiteral:
+		return node.Token.Pos
+	case *TypeExpr:
+		return node.Token.Pos
+	}
+
+	return token.Position{}
+}
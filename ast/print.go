@@ -0,0 +1,167 @@
+package ast
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Fprint writes an indented tree representation of node to w.
+func Fprint(w io.Writer, node Node) {
+	p := &printer{w: w}
+	p.print("", node)
+}
+
+// Sprint returns the indented tree representation of node.
+func Sprint(node Node) string {
+	s := strings.Builder{}
+	Fprint(&s, node)
+
+	return s.String()
+}
+
+type printer struct {
+	w      io.Writer
+	indent int
+}
+
+func (p *printer) line(label, format string, a ...interface{}) {
+	io.WriteString(p.w, strings.Repeat("  ", p.indent))
+	if label != "" {
+		io.WriteString(p.w, label+": ")
+	}
+	fmt.Fprintf(p.w, format+"\n", a...)
+}
+
+func (p *printer) nested(fn func()) {
+	p.indent++
+	fn()
+	p.indent--
+}
+
+func (p *printer) print(label string, node Node) {
+	switch node := node.(type) {
+	case *Program:
+		p.line(label, "Program")
+		p.nested(func() {
+			for _, stmt := range node.Statements {
+				p.print("", stmt)
+			}
+		})
+	case *LetStatement:
+		p.line(label, "LetStatement %s", declaration(node.Name))
+		p.nested(func() { p.print("Value", node.Value) })
+	case *ReturnStatement:
+		p.line(label, "ReturnStatement")
+		p.nested(func() { p.print("Value", node.ReturnValue) })
+	case *ExpressionStatement:
+		p.line(label, "ExpressionStatement")
+		p.nested(func() { p.print("", node.Expression) })
+	case *BlockStatement:
+		p.line(label, "BlockStatement")
+		p.nested(func() {
+			for _, stmt := range node.Statements {
+				p.print("", stmt)
+			}
+		})
+	case *Identifier:
+		p.line(label, "Identifier %s", node.Value)
+	case *IntegerLiteral:
+		p.line(label, "IntegerLiteral %s", node)
+	case *FloatLiteral:
+		p.line(label, "FloatLiteral %s", FormatFloat(node.Value))
+	case *StringLiteral:
+		p.line(label, "StringLiteral %q", node.Value)
+	case *Boolean:
+		p.line(label, "Boolean %t", node.Value)
+	case *PrefixExpression:
+		p.line(label, "PrefixExpression %s", node.Operator)
+		p.nested(func() { p.print("Right", node.Right) })
+	case *InfixExpression:
+		p.line(label, "InfixExpression %s", node.Operator)
+		p.nested(func() {
+			p.print("Left", node.Left)
+			p.print("Right", node.Right)
+		})
+	case *IfExpression:
+		p.line(label, "IfExpression")
+		p.nested(func() {
+			p.print("Condition", node.Condition)
+			p.print("Consequence", node.Consequence)
+			if node.Alternative != nil {
+				p.print("Alternative", node.Alternative)
+			}
+		})
+	case *WhileExpression:
+		p.line(label, "WhileExpression")
+		p.nested(func() {
+			p.print("Condition", node.Condition)
+			p.print("Body", node.Consequence)
+		})
+	case *FunctionLiteral:
+		params := make([]string, len(node.Parameters))
+		for i, param := range node.Parameters {
+			params[i] = declaration(param)
+		}
+
+		if node.ReturnType != nil {
+			p.line(label, "FunctionLiteral (%s): %s", strings.Join(params, ", "), node.ReturnType)
+		} else {
+			p.line(label, "FunctionLiteral (%s)", strings.Join(params, ", "))
+		}
+		p.nested(func() { p.print("Body", node.Body) })
+	case *CallExpression:
+		p.line(label, "CallExpression")
+		p.nested(func() {
+			p.print("Function", node.Function)
+			for _, arg := range node.Arguments {
+				p.print("Argument", arg)
+			}
+		})
+	case *ArrayLiteral:
+		p.line(label, "ArrayLiteral")
+		p.nested(func() {
+			for _, el := range node.Elements {
+				p.print("", el)
+			}
+		})
+	case *IndexExpression:
+		p.line(label, "IndexExpression")
+		p.nested(func() {
+			p.print("Left", node.Left)
+			p.print("Index", node.Index)
+		})
+	case *SliceExpression:
+		p.line(label, "SliceExpression")
+		p.nested(func() {
+			p.print("Left", node.Left)
+			for _, part := range []struct {
+				label string
+				exp   Expression
+			}{{"Start", node.Start}, {"End", node.End}, {"Step", node.Step}} {
+				if part.exp != nil {
+					p.print(part.label, part.exp)
+				}
+			}
+		})
+	case *HashLiteral:
+		keys := make([]Expression, 0, len(node.Pairs))
+		for k := range node.Pairs {
+			keys = append(keys, k)
+		}
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+
+		p.line(label, "HashLiteral")
+		p.nested(func() {
+			for _, k := range keys {
+				p.print("Key", k)
+				p.print("Value", node.Pairs[k])
+			}
+		})
+	case nil:
+		p.line(label, "<nil>")
+	default:
+		p.line(label, "%T", node)
+	}
+}
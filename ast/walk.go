@@ -0,0 +1,237 @@
+package ast
+
+// Visitor's Visit method is called for every node found by Walk. If the result
+// visitor w is not nil, Walk visits the children of node with w, followed by
+// a call of w.Visit(nil).
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// Walk traverses the tree rooted at node in depth-first order, children are
+// visited in source order.
+func Walk(v Visitor, node Node) {
+	if v = v.Visit(node); v == nil {
+		return
+	}
+
+	for _, child := range children(node) {
+		Walk(v, child)
+	}
+
+	v.Visit(nil)
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+
+	return nil
+}
+
+// Inspect traverses the tree rooted at node in depth-first order, calling f for
+// every node. The children of a node are skipped when f returns false. After
+// the children, f is called with nil.
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
+
+// children returns the child nodes of node in source order, skipping the
+// optional ones that are missing.
+func children(node Node) []Node {
+	var nodes []Node
+	add := func(n Node) {
+		// avoid typed nil pointers of missing nodes
+		switch n := n.(type) {
+		case *Identifier:
+			if n == nil {
+				return
+			}
+		case *BlockStatement:
+			if n == nil {
+				return
+			}
+		case *TypeExpr:
+			if n == nil {
+				return
+			}
+		case nil:
+			return
+		}
+		nodes = append(nodes, n)
+	}
+
+	switch node := node.(type) {
+	case *Program:
+		for _, stmt := range node.Statements {
+			add(stmt)
+		}
+	case *LetStatement:
+		add(node.Name)
+		add(node.Value)
+	case *ReturnStatement:
+		add(node.ReturnValue)
+	case *ExpressionStatement:
+		add(node.Expression)
+	case *BlockStatement:
+		for _, stmt := range node.Statements {
+			add(stmt)
+		}
+	case *Identifier:
+		add(node.Type)
+	case *TypeExpr:
+		for _, p := range node.Params {
+			add(p)
+		}
+	case *PrefixExpression:
+		add(node.Right)
+	case *InfixExpression:
+		add(node.Left)
+		add(node.Right)
+	case *IfExpression:
+		add(node.Condition)
+		add(node.Consequence)
+		add(node.Alternative)
+	case *WhileExpression:
+		add(node.Condition)
+		add(node.Consequence)
+	case *FunctionLiteral:
+		for _, p := range node.Parameters {
+			add(p)
+		}
+		add(node.ReturnType)
+		add(node.Body)
+	case *CallExpression:
+		add(node.Function)
+		for _, arg := range node.Arguments {
+			add(arg)
+		}
+	case *ArrayLiteral:
+		for _, el := range node.Elements {
+			add(el)
+		}
+	case *IndexExpression:
+		add(node.Left)
+		add(node.Index)
+	case *SliceExpression:
+		add(node.Left)
+		add(node.Start)
+		add(node.End)
+		add(node.Step)
+	case *HashLiteral:
+		for _, key := range HashKeys(node) {
+			add(key)
+			add(node.Pairs[key])
+		}
+	}
+
+	return nodes
+}
+
+// ModifierFunc returns the node replacing its argument.
+type ModifierFunc func(Node) Node
+
+// Modify rewrites the tree rooted at node bottom-up, replacing every node with
+// the result of modifier after its children have been modified. It returns the
+// replacement of node. Replacements must fit the field they are stored in, e.g.
+// the name of a let statement stays an *Identifier, else the field is set to nil.
+func Modify(node Node, modifier ModifierFunc) Node {
+	switch node := node.(type) {
+	case *Program:
+		node.Statements = modifyStatements(node.Statements, modifier)
+	case *LetStatement:
+		node.Name, _ = modify(node.Name, modifier).(*Identifier)
+		node.Value, _ = modify(node.Value, modifier).(Expression)
+	case *ReturnStatement:
+		node.ReturnValue, _ = modify(node.ReturnValue, modifier).(Expression)
+	case *ExpressionStatement:
+		node.Expression, _ = modify(node.Expression, modifier).(Expression)
+	case *BlockStatement:
+		node.Statements = modifyStatements(node.Statements, modifier)
+	case *Identifier:
+		if node.Type != nil {
+			node.Type, _ = Modify(node.Type, modifier).(*TypeExpr)
+		}
+	case *TypeExpr:
+		for i, p := range node.Params {
+			node.Params[i], _ = Modify(p, modifier).(*TypeExpr)
+		}
+	case *PrefixExpression:
+		node.Right, _ = modify(node.Right, modifier).(Expression)
+	case *InfixExpression:
+		node.Left, _ = modify(node.Left, modifier).(Expression)
+		node.Right, _ = modify(node.Right, modifier).(Expression)
+	case *IfExpression:
+		node.Condition, _ = modify(node.Condition, modifier).(Expression)
+		node.Consequence, _ = Modify(node.Consequence, modifier).(*BlockStatement)
+		if node.Alternative != nil {
+			node.Alternative, _ = Modify(node.Alternative, modifier).(*BlockStatement)
+		}
+	case *WhileExpression:
+		node.Condition, _ = modify(node.Condition, modifier).(Expression)
+		node.Consequence, _ = Modify(node.Consequence, modifier).(*BlockStatement)
+	case *FunctionLiteral:
+		for i, p := range node.Parameters {
+			node.Parameters[i], _ = Modify(p, modifier).(*Identifier)
+		}
+		if node.ReturnType != nil {
+			node.ReturnType, _ = Modify(node.ReturnType, modifier).(*TypeExpr)
+		}
+		node.Body, _ = Modify(node.Body, modifier).(*BlockStatement)
+	case *CallExpression:
+		node.Function, _ = modify(node.Function, modifier).(Expression)
+		node.Arguments = modifyExpressions(node.Arguments, modifier)
+	case *ArrayLiteral:
+		node.Elements = modifyExpressions(node.Elements, modifier)
+	case *IndexExpression:
+		node.Left, _ = modify(node.Left, modifier).(Expression)
+		node.Index, _ = modify(node.Index, modifier).(Expression)
+	case *SliceExpression:
+		node.Left, _ = modify(node.Left, modifier).(Expression)
+		node.Start, _ = modify(node.Start, modifier).(Expression)
+		node.End, _ = modify(node.End, modifier).(Expression)
+		node.Step, _ = modify(node.Step, modifier).(Expression)
+	case *HashLiteral:
+		pairs := make(map[Expression]Expression, len(node.Pairs))
+		for _, key := range HashKeys(node) {
+			value, _ := modify(node.Pairs[key], modifier).(Expression)
+			newKey, _ := modify(key, modifier).(Expression)
+			pairs[newKey] = value
+		}
+		node.Pairs = pairs
+	}
+
+	return modifier(node)
+}
+
+// modify is Modify for fields holding an interface, which are left nil when
+// missing.
+func modify(node Node, modifier ModifierFunc) Node {
+	if node == nil {
+		return nil
+	}
+
+	return Modify(node, modifier)
+}
+
+func modifyStatements(stmts []Statement, modifier ModifierFunc) []Statement {
+	out := make([]Statement, 0, len(stmts))
+	for _, stmt := range stmts {
+		// statements replaced with nil are removed
+		if s, ok := Modify(stmt, modifier).(Statement); ok {
+			out = append(out, s)
+		}
+	}
+
+	return out
+}
+
+func modifyExpressions(exps []Expression, modifier ModifierFunc) []Expression {
+	for i, exp := range exps {
+		exps[i], _ = modify(exp, modifier).(Expression)
+	}
+
+	return exps
+}
@@ -0,0 +1,146 @@
+package ast_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/gkampitakis/monkey/ast"
+	"github.com/gkampitakis/monkey/token"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInspect(t *testing.T) {
+	program := parse(t, `
+let f = fn(a: int, b): hash<string, int> {
+	while (a > 0) { {"k": b, c: [d, -e]}[g(h)] }
+};
+if (i) { j } else { return k; };`)
+
+	var names []string
+	ast.Inspect(program, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.Identifier:
+			names = append(names, string(node.Value))
+		case *ast.TypeExpr:
+			names = append(names, node.Name)
+		}
+		return true
+	})
+
+	require.Equal(
+		t,
+		[]string{"f", "a", "int", "b", "hash", "string", "int", "a", "b", "c", "d", "e", "g", "h", "i", "j", "k"},
+		names,
+	)
+}
+
+func TestInspectSkipsChildren(t *testing.T) {
+	program := parse(t, "let a = fn(b) { c }; d;")
+
+	var names []string
+	ast.Inspect(program, func(node ast.Node) bool {
+		if ident, ok := node.(*ast.Identifier); ok {
+			names = append(names, string(ident.Value))
+		}
+		_, isFn := node.(*ast.FunctionLiteral)
+		return !isFn
+	})
+
+	require.Equal(t, []string{"a", "d"}, names)
+}
+
+type depthVisitor struct {
+	depth int
+	lines *[]string
+}
+
+func (v depthVisitor) Visit(node ast.Node) ast.Visitor {
+	if node == nil {
+		*v.lines = append(*v.lines, fmt.Sprintf("%d end", v.depth))
+		return nil
+	}
+	*v.lines = append(*v.lines, fmt.Sprintf("%d %T", v.depth, node))
+
+	return depthVisitor{depth: v.depth + 1, lines: v.lines}
+}
+
+func TestWalk(t *testing.T) {
+	program := parse(t, "-x;")
+
+	var lines []string
+	ast.Walk(depthVisitor{lines: &lines}, program)
+
+	require.Equal(t, []string{
+		"0 *ast.Program",
+		"1 *ast.ExpressionStatement",
+		"2 *ast.PrefixExpression",
+		"3 *ast.Identifier",
+		"4 end",
+		"3 end",
+		"2 end",
+		"1 end",
+	}, lines)
+}
+
+func TestModify(t *testing.T) {
+	one := func() ast.Expression { return &ast.IntegerLiteral{Token: token.Token{Literal: []byte("1")}, Value: 1} }
+	turnOneIntoTwo := func(node ast.Node) ast.Node {
+		integer, ok := node.(*ast.IntegerLiteral)
+		if !ok || integer.Value != 1 {
+			return node
+		}
+
+		return &ast.IntegerLiteral{Token: token.Token{Literal: []byte("2")}, Value: 2}
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1;", "2"},
+		{"1 + 1;", "(2 + 2)"},
+		{"-1;", "(-2)"},
+		{"[1, 3][1];", "([2, 3][2])"},
+		{"if (1) { 1 } else { 1 };", "if2 2else2"},
+		{"while (1) { 1 };", "while(2) 2"},
+		{"return 1;", "return 2;"},
+		{"let x = 1;", "let x = 2;"},
+		{"fn(x) { 1 };", "fn(x)2"},
+		{"f(1, x);", "f(2, x)"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.input, func(t *testing.T) {
+			program := parse(t, tc.input)
+
+			modified := ast.Modify(program, turnOneIntoTwo)
+
+			require.Equal(t, tc.expected, modified.String())
+		})
+	}
+
+	t.Run("hash literal", func(t *testing.T) {
+		hash := &ast.HashLiteral{Pairs: map[ast.Expression]ast.Expression{one(): one()}}
+
+		modified := ast.Modify(hash, turnOneIntoTwo).(*ast.HashLiteral)
+
+		require.Len(t, modified.Pairs, 1)
+		for key, value := range modified.Pairs {
+			require.Equal(t, 2, key.(*ast.IntegerLiteral).Value)
+			require.Equal(t, 2, value.(*ast.IntegerLiteral).Value)
+		}
+	})
+}
+
+func TestModifyRemovesStatements(t *testing.T) {
+	program := parse(t, "let a = 1; a; fn() { a; 2 };")
+
+	modified := ast.Modify(program, func(node ast.Node) ast.Node {
+		if stmt, ok := node.(*ast.ExpressionStatement); ok && stmt.String() == "a" {
+			return nil
+		}
+		return node
+	})
+
+	require.Equal(t, "let a = 1;fn()2", modified.String())
+}
@@ -0,0 +1,699 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gkampitakis/monkey/ast"
+	"github.com/gkampitakis/monkey/coverage"
+	"github.com/gkampitakis/monkey/evaluator"
+	"github.com/gkampitakis/monkey/lexer"
+	"github.com/gkampitakis/monkey/lint"
+	"github.com/gkampitakis/monkey/object"
+	"github.com/gkampitakis/monkey/optimizer"
+	"github.com/gkampitakis/monkey/parser"
+	"github.com/gkampitakis/monkey/profiler"
+	"github.com/gkampitakis/monkey/repl"
+	"github.com/gkampitakis/monkey/tester"
+	"github.com/gkampitakis/monkey/token"
+	"github.com/gkampitakis/monkey/types"
+	cli "github.com/openengineer/go-repl"
+)
+
+func runRepl(args []string, s *stdio) int {
+	fs := newFlagSet("repl", s)
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	user, err := user.Current()
+	if err != nil {
+		fmt.Fprintln(s.err, err)
+		return 1
+	}
+
+	fmt.Fprintf(s.out, "Hello %s! This is the monkey programming language!\n", user.Username)
+	fmt.Fprint(s.out, MONKEY_FACE)
+	fmt.Fprintf(s.out, "Feel free to type in monkey repl or type \".help\" for more info\n")
+	h := &repl.ReplHandler{
+		Env:    object.NewEnvironment(),
+		Stderr: s.err,
+	}
+	if path, err := repl.DefaultHistoryPath(); err == nil {
+		if h.History, err = repl.NewHistory(path); err != nil {
+			fmt.Fprintf(s.err, "could not load history: %s\n", err)
+		}
+	}
+	h.Repl = cli.NewRepl(h)
+
+	// start the terminal loop
+	if err := h.Repl.Loop(); err != nil {
+		fmt.Fprintln(s.err, err)
+		return 1
+	}
+
+	return 0
+}
+
+func runRun(args []string, s *stdio) int {
+	fs := newFlagSet("run", s)
+	profile := fs.String("profile", "", "profile function calls and write folded stacks to the given file")
+	profileTop := fs.Int("profile-top", 10, "number of functions printed in the profile table")
+	noOpt := fs.Bool("no-opt", false, "evaluate the program without optimizing it")
+	allowFS := &allowFSFlag{}
+	fs.Var(allowFS, "allow-fs", "allow the program to access the files under the given directory, every file when no directory is given")
+	cover := addCoverFlags(fs)
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+	if allowFS.enabled {
+		access, err := evaluator.NewFileAccess(allowFS.root)
+		if err != nil {
+			fmt.Fprintln(s.err, err)
+			return 1
+		}
+		evaluator.SetFileAccess(access)
+		defer evaluator.SetFileAccess(nil)
+	}
+
+	src, err := readSource(fs.Arg(0), s)
+	if err != nil {
+		fmt.Fprintln(s.err, err)
+		return 1
+	}
+	program, errs := parse(src)
+	if len(errs) != 0 {
+		printParserErrors(s.err, fs.Arg(0), errs)
+		return 1
+	}
+
+	cov := cover.coverage()
+	if cov != nil {
+		cov.Add(fs.Arg(0), src, program)
+		evaluator.SetCoverage(cov)
+		defer evaluator.SetCoverage(nil)
+	}
+
+	var prof *profiler.Profiler
+	if *profile != "" {
+		prof = profiler.New()
+		evaluator.SetTracer(prof)
+		defer evaluator.SetTracer(nil)
+	}
+
+	// profiles and coverage report the program as written
+	if !*noOpt && cov == nil && prof == nil {
+		optimizer.Optimize(program)
+	}
+
+	evaluated := evaluator.Eval(program, scriptEnvironment(fs.Args()[1:]))
+	code := exitCode(evaluated, s)
+
+	if prof != nil {
+		prof.WriteTable(s.err, *profileTop)
+		if err := writeFile(*profile, prof.WriteFolded); err != nil {
+			fmt.Fprintln(s.err, err)
+			return 1
+		}
+	}
+	if !cover.report(cov, s) {
+		return 1
+	}
+
+	return code
+}
+
+func runEval(args []string, s *stdio) int {
+	fs := newFlagSet("eval", s)
+	expr := fs.String("e", "", "expression to evaluate")
+	noOpt := fs.Bool("no-opt", false, "evaluate the program without optimizing it")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if *expr == "" {
+		fs.Usage()
+		return 2
+	}
+
+	program, errs := parse([]byte(*expr))
+	if len(errs) != 0 {
+		printParserErrors(s.err, "", errs)
+		return 1
+	}
+	if !*noOpt {
+		optimizer.Optimize(program)
+	}
+
+	evaluated := evaluator.Eval(program, scriptEnvironment(nil))
+	if evaluated != nil && evaluated.Type() != object.NULL && !isAbort(evaluated) {
+		fmt.Fprintln(s.out, evaluated.Inspect())
+	}
+
+	return exitCode(evaluated, s)
+}
+
+func runCheck(args []string, s *stdio) int {
+	fs := newFlagSet("check", s)
+	checkTypes := fs.Bool("types", false, "verify type annotations")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	code := 0
+	for _, file := range fs.Args() {
+		program, c, ok := parseSource(file, s)
+		if !ok {
+			code = c
+			continue
+		}
+		if !*checkTypes {
+			continue
+		}
+
+		for _, err := range types.Check(program) {
+			fmt.Fprintf(s.err, "%s:%s\n", file, err)
+			code = 1
+		}
+	}
+
+	return code
+}
+
+func runLint(args []string, s *stdio) int {
+	fs := newFlagSet("lint", s)
+	disable := fs.String("disable", "", "comma separated rules to disable")
+	enable := fs.String("enable", "", "comma separated rules to run, disabling all the others")
+	listRules := fs.Bool("rules", false, "list the available rules")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	if *listRules {
+		names := make([]string, 0, len(lint.Rules))
+		for name := range lint.Rules {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(s.out, "%-20s %s\n", name, lint.Rules[name])
+		}
+		return 0
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	cfg := lint.Config{Disabled: map[string]bool{}}
+	if *enable != "" {
+		for rule := range lint.Rules {
+			cfg.Disabled[rule] = true
+		}
+	}
+	for _, list := range []string{*enable, *disable} {
+		for _, rule := range strings.Split(list, ",") {
+			if _, ok := lint.Rules[strings.TrimSpace(rule)]; !ok && rule != "" {
+				fmt.Fprintf(s.err, "unknown rule %q\n", rule)
+				return 2
+			}
+		}
+	}
+	for _, rule := range strings.Split(*enable, ",") {
+		delete(cfg.Disabled, strings.TrimSpace(rule))
+	}
+	for _, rule := range strings.Split(*disable, ",") {
+		if rule = strings.TrimSpace(rule); rule != "" {
+			cfg.Disabled[rule] = true
+		}
+	}
+
+	code := 0
+	for _, file := range fs.Args() {
+		src, err := readSource(file, s)
+		if err != nil {
+			fmt.Fprintln(s.err, err)
+			code = 1
+			continue
+		}
+		program, errs := parse(src)
+		if len(errs) != 0 {
+			printParserErrors(s.err, file, errs)
+			code = 1
+			continue
+		}
+
+		for _, d := range lint.Lint(src, program, cfg) {
+			fmt.Fprintf(s.out, "%s:%s\n", file, d)
+			code = 1
+		}
+	}
+
+	return code
+}
+
+func runInfer(args []string, s *stdio) int {
+	fs := newFlagSet("infer", s)
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	code := 0
+	for _, file := range fs.Args() {
+		program, c, ok := parseSource(file, s)
+		if !ok {
+			code = c
+			continue
+		}
+
+		signatures, errs := types.Infer(program)
+		for _, sig := range signatures {
+			fmt.Fprintln(s.out, sig)
+		}
+		for _, err := range errs {
+			fmt.Fprintf(s.err, "%s:%s\n", file, err)
+			code = 1
+		}
+	}
+
+	return code
+}
+
+func runTokens(args []string, s *stdio) int {
+	fs := newFlagSet("tokens", s)
+	src := fs.String("e", "", "source to tokenize instead of a file")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	var l *lexer.Lexer
+	if *src != "" {
+		l = lexer.New([]byte(*src))
+	} else {
+		if fs.NArg() != 1 {
+			fs.Usage()
+			return 2
+		}
+
+		// files are streamed, so large generated scripts are not read in full
+		r, err := openSource(fs.Arg(0), s)
+		if err != nil {
+			fmt.Fprintln(s.err, err)
+			return 1
+		}
+		defer r.Close()
+
+		l = lexer.NewReader(r)
+	}
+
+	for {
+		tok := l.NextToken()
+		if tok.Type == token.EOF {
+			break
+		}
+		fmt.Fprintf(s.out, "[%s]=>%q\n", tok.Type, tok.Literal)
+	}
+	if err := l.Err(); err != nil {
+		fmt.Fprintln(s.err, err)
+		return 1
+	}
+
+	return 0
+}
+
+func runAST(args []string, s *stdio) int {
+	fs := newFlagSet("ast", s)
+	src := fs.String("e", "", "source to parse instead of a file")
+	asJSON := fs.Bool("json", false, "print the syntax tree as JSON")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	var program *ast.Program
+	if *src != "" {
+		var errs []string
+		if program, errs = parse([]byte(*src)); len(errs) != 0 {
+			printParserErrors(s.err, "", errs)
+			return 1
+		}
+	} else {
+		if fs.NArg() != 1 {
+			fs.Usage()
+			return 2
+		}
+
+		var code int
+		var ok bool
+		if program, code, ok = parseSource(fs.Arg(0), s); !ok {
+			return code
+		}
+	}
+
+	if !*asJSON {
+		ast.Fprint(s.out, program)
+		return 0
+	}
+
+	data, err := ast.MarshalJSON(program)
+	if err != nil {
+		fmt.Fprintln(s.err, err)
+		return 1
+	}
+	var out bytes.Buffer
+	if err := json.Indent(&out, data, "", "  "); err != nil {
+		fmt.Fprintln(s.err, err)
+		return 1
+	}
+	fmt.Fprintln(s.out, out.String())
+
+	return 0
+}
+
+func runTest(args []string, s *stdio) int {
+	fs := newFlagSet("test", s)
+	pattern := fs.String("run", "", "run only the tests whose name matches the regular expression")
+	junit := fs.String("junit", "", "write a JUnit XML report to the given file")
+	verbose := fs.Bool("v", false, "print the result of every test")
+	cover := addCoverFlags(fs)
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return 2
+	}
+
+	var filter *regexp.Regexp
+	if *pattern != "" {
+		var err error
+		if filter, err = regexp.Compile(*pattern); err != nil {
+			fmt.Fprintf(s.err, "invalid -run pattern: %s\n", err)
+			return 2
+		}
+	}
+
+	dir := "."
+	if fs.NArg() == 1 {
+		dir = fs.Arg(0)
+	}
+	files, err := tester.Discover(dir)
+	if err != nil {
+		fmt.Fprintln(s.err, err)
+		return 1
+	}
+
+	code := 0
+	results := []tester.Result{}
+	opts := tester.Options{Filter: filter, Coverage: cover.coverage()}
+	for _, file := range files {
+		fileResults, err := tester.RunFile(file, opts)
+		if err != nil {
+			fmt.Fprintln(s.err, err)
+			code = 1
+			continue
+		}
+		results = append(results, fileResults...)
+	}
+
+	failures := 0
+	for _, r := range results {
+		if !r.Passed() {
+			failures++
+			fmt.Fprintf(s.out, "--- FAIL: %s (%s)\n    %s: %s\n", r.Name, r.Duration, r.Location(), r.Failure)
+		} else if *verbose {
+			fmt.Fprintf(s.out, "--- PASS: %s (%s)\n", r.Name, r.Duration)
+		}
+	}
+
+	if *junit != "" {
+		err := writeFile(*junit, func(w io.Writer) error { return tester.WriteJUnit(w, results) })
+		if err != nil {
+			fmt.Fprintln(s.err, err)
+			return 1
+		}
+	}
+
+	if !cover.report(opts.Coverage, s) {
+		return 1
+	}
+
+	if failures > 0 {
+		code = 1
+	}
+	if code != 0 {
+		fmt.Fprintf(s.out, "FAIL: %d passed, %d failed\n", len(results)-failures, failures)
+	} else {
+		fmt.Fprintf(s.out, "PASS: %d passed\n", len(results))
+	}
+
+	return code
+}
+
+/* Start helper methods */
+
+// allowFSFlag is the -allow-fs flag, which can be set alone or to a directory
+// confining the access.
+type allowFSFlag struct {
+	enabled bool
+	root    string
+}
+
+func (f *allowFSFlag) String() string {
+	if f == nil || !f.enabled {
+		return ""
+	}
+
+	return f.root
+}
+
+func (f *allowFSFlag) Set(value string) error {
+	switch value {
+	case "true":
+		f.enabled, f.root = true, ""
+	case "false":
+		f.enabled, f.root = false, ""
+	default:
+		f.enabled, f.root = true, value
+	}
+
+	return nil
+}
+
+// IsBoolFlag lets the flag be set without a value.
+func (*allowFSFlag) IsBoolFlag() bool {
+	return true
+}
+
+type coverFlags struct {
+	enabled *bool
+	profile *string
+	html    *string
+}
+
+func addCoverFlags(fs *flag.FlagSet) *coverFlags {
+	return &coverFlags{
+		enabled: fs.Bool("cover", false, "record statement and branch coverage and print a summary"),
+		profile: fs.String("coverprofile", "", "write the coverage profile to the given file, implies -cover"),
+		html:    fs.String("coverhtml", "", "write an HTML coverage report to the given file, implies -cover"),
+	}
+}
+
+// coverage returns a new coverage recorder if any of the flags are set, else nil.
+func (c *coverFlags) coverage() *coverage.Coverage {
+	if !*c.enabled && *c.profile == "" && *c.html == "" {
+		return nil
+	}
+
+	return coverage.New()
+}
+
+// report writes the coverage reports requested by the flags, returning false if
+// any of them failed.
+func (c *coverFlags) report(cov *coverage.Coverage, s *stdio) bool {
+	if cov == nil {
+		return true
+	}
+
+	cov.WriteSummary(s.err)
+	if *c.profile != "" {
+		if err := writeFile(*c.profile, cov.WriteProfile); err != nil {
+			fmt.Fprintln(s.err, err)
+			return false
+		}
+	}
+	if *c.html != "" {
+		if err := writeFile(*c.html, cov.WriteHTML); err != nil {
+			fmt.Fprintln(s.err, err)
+			return false
+		}
+	}
+
+	return true
+}
+
+func newFlagSet(name string, s *stdio) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(s.err)
+	fs.Usage = func() {
+		cmd := commands[name]
+		fmt.Fprintf(s.err, "Usage: monkey %s\n\n%s\n", cmd.usage, cmd.description)
+
+		hasFlags := false
+		fs.VisitAll(func(*flag.Flag) { hasFlags = true })
+		if hasFlags {
+			fmt.Fprintln(s.err, "\nFlags:")
+			fs.PrintDefaults()
+		}
+	}
+
+	return fs
+}
+
+// parseFlags parses args into fs, on failure it returns false along with the
+// exit code the command should return.
+func parseFlags(fs *flag.FlagSet, args []string) (int, bool) {
+	err := fs.Parse(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return 0, false
+	}
+	if err != nil {
+		return 2, false
+	}
+
+	return 0, true
+}
+
+// readSource reads the file with the given name, or stdin if name is "-".
+func readSource(name string, s *stdio) ([]byte, error) {
+	if name == "-" {
+		return io.ReadAll(s.in)
+	}
+
+	return os.ReadFile(name)
+}
+
+// openSource opens the file with the given name, or stdin if name is "-".
+func openSource(name string, s *stdio) (io.ReadCloser, error) {
+	if name == "-" {
+		return io.NopCloser(s.in), nil
+	}
+
+	return os.Open(name)
+}
+
+// writeFile creates the file with the given name and fills it with write.
+func writeFile(name string, write func(io.Writer) error) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// parseSource parses the file with the given name as it's read, reporting any
+// failures to stderr. Commands that only need the syntax tree use it, so large
+// scripts are not read in full.
+func parseSource(name string, s *stdio) (*ast.Program, int, bool) {
+	r, err := openSource(name, s)
+	if err != nil {
+		fmt.Fprintln(s.err, err)
+		return nil, 1, false
+	}
+	defer r.Close()
+
+	p := parser.New(lexer.NewReader(r))
+	program := p.ParseProgram()
+	if errs := p.Errors(); len(errs) != 0 {
+		printParserErrors(s.err, name, errs)
+		return nil, 1, false
+	}
+
+	return program, 0, true
+}
+
+// scriptEnvironment returns the environment scripts are evaluated in, exposing
+// the script arguments as `args` and the environment variables as `env`.
+func scriptEnvironment(args []string) *object.Environment {
+	env := object.NewEnvironment()
+
+	arguments := &object.Array{Elements: make([]object.Object, len(args))}
+	for i, arg := range args {
+		arguments.Elements[i] = &object.String{Value: arg}
+	}
+	env.Set("args", arguments)
+
+	variables := &object.Hash{}
+	for _, v := range os.Environ() {
+		name, value, _ := strings.Cut(v, "=")
+		variables.Set(&object.String{Value: name}, &object.String{Value: value})
+	}
+	env.Set("env", variables)
+
+	return env
+}
+
+// isAbort reports whether o stopped the evaluation of the program.
+func isAbort(o object.Object) bool {
+	return o.Type() == object.ERROR_VALUE || o.Type() == object.EXIT
+}
+
+// exitCode returns the status code for a program that evaluated to o, reporting
+// errors to stderr.
+func exitCode(o object.Object, s *stdio) int {
+	switch o := o.(type) {
+	case *object.Exit:
+		return o.Code
+	case *object.ErrorValue:
+		fmt.Fprintln(s.err, o.Inspect())
+		return 1
+	default:
+		return 0
+	}
+}
+
+func parse(input []byte) (*ast.Program, []string) {
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+
+	return program, p.Errors()
+}
+
+func printParserErrors(w io.Writer, name string, errors []string) {
+	fmt.Fprintln(w, "Woops! We ran into some monkey business here!")
+	if name != "" {
+		fmt.Fprintf(w, " parser errors in %s:\n", name)
+	} else {
+		fmt.Fprintln(w, " parser errors:")
+	}
+	for _, msg := range errors {
+		fmt.Fprintln(w, "\t"+strings.TrimSpace(msg))
+	}
+}
+
+/* End helper methods */
@@ -0,0 +1,170 @@
+// Package coverage records which statements and branches of monkey programs are
+// executed. It's installed as the evaluator.Coverage and reports the results as a
+// text summary, a machine-readable profile or an HTML page.
+package coverage
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/gkampitakis/monkey/ast"
+	"github.com/gkampitakis/monkey/evaluator"
+	"github.com/gkampitakis/monkey/token"
+)
+
+var branchNames = map[int]string{
+	evaluator.BRANCH_THEN: "then",
+	evaluator.BRANCH_ELSE: "else",
+	evaluator.BRANCH_BODY: "body",
+}
+
+type branch struct {
+	node ast.Expression
+	kind int
+}
+
+// block is a statement or branch of a file along with its execution count.
+type block struct {
+	pos   token.Position
+	kind  string
+	count int
+}
+
+type file struct {
+	name       string
+	src        []byte
+	statements []ast.Statement
+	branches   []branch
+}
+
+type Coverage struct {
+	files      []*file
+	statements map[ast.Statement]int
+	branches   map[branch]int
+}
+
+func New() *Coverage {
+	return &Coverage{
+		statements: make(map[ast.Statement]int),
+		branches:   make(map[branch]int),
+	}
+}
+
+// Add registers the program parsed from src, so its statements and branches are
+// included in the reports. Only registered programs are reported.
+func (c *Coverage) Add(name string, src []byte, program *ast.Program) {
+	f := &file{name: name, src: src}
+	collect(f, program)
+	c.files = append(c.files, f)
+}
+
+func (c *Coverage) Statement(stmt ast.Statement) {
+	c.statements[stmt]++
+}
+
+func (c *Coverage) Branch(node ast.Expression, kind int) {
+	c.branches[branch{node: node, kind: kind}]++
+}
+
+// blocks returns the statements and branches of f in source order.
+func (c *Coverage) blocks(f *file) (statements, branches []block) {
+	for _, stmt := range f.statements {
+		statements = append(statements, block{
+			pos:   ast.Pos(stmt),
+			kind:  "stmt",
+			count: c.statements[stmt],
+		})
+	}
+	for _, b := range f.branches {
+		branches = append(branches, block{
+			pos:   ast.Pos(b.node),
+			kind:  "branch." + branchNames[b.kind],
+			count: c.branches[b],
+		})
+	}
+
+	sortBlocks(statements)
+	sortBlocks(branches)
+
+	return statements, branches
+}
+
+func sortBlocks(blocks []block) {
+	sort.SliceStable(blocks, func(i, j int) bool {
+		if blocks[i].pos.Line != blocks[j].pos.Line {
+			return blocks[i].pos.Line < blocks[j].pos.Line
+		}
+		return blocks[i].pos.Column < blocks[j].pos.Column
+	})
+}
+
+// WriteSummary writes the percentage of statements and branches executed per file.
+func (c *Coverage) WriteSummary(w io.Writer) {
+	for _, f := range c.files {
+		statements, branches := c.blocks(f)
+
+		fmt.Fprintf(
+			w,
+			"%s: statements %s, branches %s\n",
+			f.name,
+			percentage(statements),
+			percentage(branches),
+		)
+	}
+}
+
+// WriteProfile writes the execution count of every statement and branch, one per
+// line in the format "file:line:column kind count".
+func (c *Coverage) WriteProfile(w io.Writer) error {
+	if _, err := io.WriteString(w, "mode: count\n"); err != nil {
+		return err
+	}
+
+	for _, f := range c.files {
+		statements, branches := c.blocks(f)
+		for _, b := range append(statements, branches...) {
+			if _, err := fmt.Fprintf(w, "%s:%s %s %d\n", f.name, b.pos, b.kind, b.count); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func percentage(blocks []block) string {
+	if len(blocks) == 0 {
+		return "100.0% (0/0)"
+	}
+
+	covered := 0
+	for _, b := range blocks {
+		if b.count > 0 {
+			covered++
+		}
+	}
+
+	return fmt.Sprintf("%.1f%% (%d/%d)", float64(covered)*100/float64(len(blocks)), covered, len(blocks))
+}
+
+// collect gathers the statements and branches under node into f.
+func collect(f *file, node ast.Node) {
+	ast.Inspect(node, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.BlockStatement:
+		case ast.Statement:
+			f.statements = append(f.statements, node)
+		case *ast.IfExpression:
+			f.branches = append(
+				f.branches,
+				branch{node: node, kind: evaluator.BRANCH_THEN},
+				branch{node: node, kind: evaluator.BRANCH_ELSE},
+			)
+		case *ast.WhileExpression:
+			f.branches = append(f.branches, branch{node: node, kind: evaluator.BRANCH_BODY})
+		}
+
+		return true
+	})
+}
@@ -0,0 +1,78 @@
+package coverage_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/gkampitakis/monkey/coverage"
+	"github.com/gkampitakis/monkey/evaluator"
+	"github.com/gkampitakis/monkey/lexer"
+	"github.com/gkampitakis/monkey/object"
+	"github.com/gkampitakis/monkey/parser"
+	"github.com/stretchr/testify/require"
+)
+
+const src = `let abs = fn(x) {
+  if (x < 0) {
+    return -x;
+  }
+  x
+};
+let i = 0;
+while (i < 0) { let i = i + 1; }
+abs(5);
+`
+
+func cover(t *testing.T) *coverage.Coverage {
+	t.Helper()
+
+	p := parser.New(lexer.New([]byte(src)))
+	program := p.ParseProgram()
+	require.Empty(t, p.Errors())
+
+	cov := coverage.New()
+	cov.Add("abs.monkey", []byte(src), program)
+	evaluator.SetCoverage(cov)
+	defer evaluator.SetCoverage(nil)
+
+	evaluator.Eval(program, object.NewEnvironment())
+
+	return cov
+}
+
+func TestSummary(t *testing.T) {
+	b := &bytes.Buffer{}
+	cover(t).WriteSummary(b)
+
+	require.Equal(t, "abs.monkey: statements 75.0% (6/8), branches 33.3% (1/3)\n", b.String())
+}
+
+func TestProfile(t *testing.T) {
+	b := &bytes.Buffer{}
+	require.NoError(t, cover(t).WriteProfile(b))
+
+	require.Equal(t, `mode: count
+abs.monkey:1:1 stmt 1
+abs.monkey:2:3 stmt 1
+abs.monkey:3:5 stmt 0
+abs.monkey:5:3 stmt 1
+abs.monkey:7:1 stmt 1
+abs.monkey:8:1 stmt 1
+abs.monkey:8:17 stmt 0
+abs.monkey:9:1 stmt 1
+abs.monkey:2:3 branch.then 0
+abs.monkey:2:3 branch.else 1
+abs.monkey:8:1 branch.body 0
+`, b.String())
+}
+
+func TestHTML(t *testing.T) {
+	b := &bytes.Buffer{}
+	require.NoError(t, cover(t).WriteHTML(b))
+
+	html := b.String()
+	require.Contains(t, html, `<span class="line partial" title="stmt: 1, branch.then: 0, branch.else: 1">`)
+	require.Contains(t, html, `<span class="line uncovered" title="stmt: 0"><span class="lineno">3</span>    return -x;`)
+	require.Contains(t, html, `<span class="line covered" title="stmt: 1"><span class="lineno">9</span>abs(5);`)
+	require.Contains(t, html, `<span class="line " title=""><span class="lineno">4</span>  }`)
+}
@@ -0,0 +1,98 @@
+package coverage
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+)
+
+var htmlTemplate = template.Must(template.New("coverage").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>monkey coverage</title>
+<style>
+body { font-family: sans-serif; }
+pre { line-height: 1.3; }
+.line { display: block; }
+.lineno { color: #999; display: inline-block; width: 4em; }
+.covered { background: #d4f7d4; }
+.uncovered { background: #f7d4d4; }
+.partial { background: #f7f1c4; }
+</style>
+</head>
+<body>
+{{range .}}
+<h2>{{.Name}}</h2>
+<p>statements {{.Statements}}, branches {{.Branches}}</p>
+<pre>{{range .Lines}}<span class="line {{.Class}}" title="{{.Title}}"><span class="lineno">{{.Number}}</span>{{.Text}}</span>{{end}}</pre>
+{{end}}
+</body>
+</html>
+`))
+
+type htmlFile struct {
+	Name       string
+	Statements string
+	Branches   string
+	Lines      []htmlLine
+}
+
+type htmlLine struct {
+	Number int
+	Text   string
+	// Class is covered when every block starting in the line was executed,
+	// uncovered when none of them was and partial otherwise
+	Class string
+	Title string
+}
+
+// WriteHTML writes the source of every file with its lines highlighted based on
+// whether the statements and branches starting there were executed.
+func (c *Coverage) WriteHTML(w io.Writer) error {
+	files := make([]htmlFile, 0, len(c.files))
+
+	for _, f := range c.files {
+		statements, branches := c.blocks(f)
+		lineBlocks := map[int][]block{}
+		for _, b := range append(statements, branches...) {
+			lineBlocks[b.pos.Line] = append(lineBlocks[b.pos.Line], b)
+		}
+
+		hf := htmlFile{Name: f.name, Statements: percentage(statements), Branches: percentage(branches)}
+		for i, text := range strings.Split(string(bytes.TrimSuffix(f.src, []byte("\n"))), "\n") {
+			line := htmlLine{Number: i + 1, Text: text + "\n"}
+			line.Class, line.Title = lineClass(lineBlocks[i+1])
+			hf.Lines = append(hf.Lines, line)
+		}
+		files = append(files, hf)
+	}
+
+	return htmlTemplate.Execute(w, files)
+}
+
+func lineClass(blocks []block) (string, string) {
+	if len(blocks) == 0 {
+		return "", ""
+	}
+
+	covered := 0
+	titles := make([]string, len(blocks))
+	for i, b := range blocks {
+		if b.count > 0 {
+			covered++
+		}
+		titles[i] = fmt.Sprintf("%s: %d", b.kind, b.count)
+	}
+
+	switch covered {
+	case len(blocks):
+		return "covered", strings.Join(titles, ", ")
+	case 0:
+		return "uncovered", strings.Join(titles, ", ")
+	default:
+		return "partial", strings.Join(titles, ", ")
+	}
+}
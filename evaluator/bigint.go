@@ -0,0 +1,93 @@
+package evaluator
+
+import (
+	"math"
+	"math/big"
+
+	"github.com/gkampitakis/monkey/object"
+)
+
+// Integers that overflow are promoted to big integers, and big integers are
+// demoted as soon as they fit again. An integer value therefore always has a
+// single representation.
+
+// maxPowerBits bounds the estimated size in bits of the result of **, larger
+// powers are an error instead of exhausting time and memory.
+const maxPowerBits = 1 << 20
+
+// evalBigIntInfixExpression evaluates an operator on integers that don't both
+// fit an int, or whose result doesn't.
+func evalBigIntInfixExpression(operator string, left, right *big.Int) object.Object {
+	switch operator {
+	case "+":
+		return newInteger(new(big.Int).Add(left, right))
+	case "-":
+		return newInteger(new(big.Int).Sub(left, right))
+	case "*":
+		return newInteger(new(big.Int).Mul(left, right))
+	case "/":
+		if right.Sign() == 0 {
+			return newError("division by zero: %s / 0", left)
+		}
+		// Quo truncates like integer division
+		return newInteger(new(big.Int).Quo(left, right))
+	case "**":
+		if right.Sign() < 0 {
+			return newError("negative exponent: %s ** %s", left, right)
+		}
+		// the result has at most left.BitLen() * right bits, only 0, 1 and -1 can
+		// be raised to any exponent
+		if left.CmpAbs(big.NewInt(1)) > 0 &&
+			(!right.IsInt64() || right.Int64() > int64(maxPowerBits/left.BitLen())) {
+			return newError("exponent too large: %s ** %s", left, right)
+		}
+		return newInteger(new(big.Int).Exp(left, right, nil))
+	case "<":
+		return nativeBoolToBooleanObject(left.Cmp(right) < 0)
+	case ">":
+		return nativeBoolToBooleanObject(left.Cmp(right) > 0)
+	case "==":
+		return nativeBoolToBooleanObject(left.Cmp(right) == 0)
+	case "!=":
+		return nativeBoolToBooleanObject(left.Cmp(right) != 0)
+	default:
+		return newError("unknown operator: %s %s %s", object.BIG_INTEGER, operator, object.BIG_INTEGER)
+	}
+}
+
+// newInteger returns n as an Integer when it fits, as a BigInt otherwise. n
+// must not be modified afterwards.
+func newInteger(n *big.Int) object.Object {
+	if n.IsInt64() && n.Int64() >= math.MinInt && n.Int64() <= math.MaxInt {
+		return &object.Integer{Value: int(n.Int64())}
+	}
+
+	return &object.BigInt{Value: n}
+}
+
+func isInteger(o object.Object) bool {
+	return o.Type() == object.INTEGER || o.Type() == object.BIG_INTEGER
+}
+
+// toBigInt converts an Integer or BigInt to a big.Int, which must not be
+// modified.
+func toBigInt(o object.Object) *big.Int {
+	if i, ok := o.(*object.Integer); ok {
+		return big.NewInt(int64(i.Value))
+	}
+
+	return o.(*object.BigInt).Value
+}
+
+// compareNumbers returns -1, 0 or 1 when a is less than, equal to or greater
+// than b. Integers are compared exactly, floats after converting both to floats.
+func compareNumbers(a, b object.Object) int {
+	switch {
+	case a.Type() == object.INTEGER && b.Type() == object.INTEGER:
+		return compare(a.(*object.Integer).Value, b.(*object.Integer).Value)
+	case isInteger(a) && isInteger(b):
+		return toBigInt(a).Cmp(toBigInt(b))
+	default:
+		return compare(toFloat(a), toFloat(b))
+	}
+}
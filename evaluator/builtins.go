@@ -2,12 +2,18 @@ package evaluator
 
 import (
 	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/gkampitakis/monkey/object"
 )
 
 var builtins = map[string]*object.Builtin{
 	"len": {
+		MinArgs: 1,
+		MaxArgs: 1,
+		Scheme:  "a -> int",
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 1 {
 				return newError("wrong number of arguments. got=%d, want=1", len(args))
@@ -15,15 +21,20 @@ var builtins = map[string]*object.Builtin{
 
 			switch arg := args[0].(type) {
 			case *object.String:
-				return &object.Integer{Value: len(arg.Value)}
+				return &object.Integer{Value: utf8.RuneCountInString(arg.Value)}
 			case *object.Array:
 				return &object.Integer{Value: len(arg.Elements)}
+			case *object.Hash:
+				return &object.Integer{Value: arg.Len()}
 			default:
 				return newError("argument to `len` not supported, got %s", args[0].Type())
 			}
 		},
 	},
 	"first": {
+		MinArgs: 1,
+		MaxArgs: 1,
+		Scheme:  "array<a> -> a",
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 1 {
 				return newError("wrong number of arguments. got=%d, want=1", len(args))
@@ -41,6 +52,9 @@ var builtins = map[string]*object.Builtin{
 		},
 	},
 	"last": {
+		MinArgs: 1,
+		MaxArgs: 1,
+		Scheme:  "array<a> -> a",
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 1 {
 				return newError("wrong number of arguments. got=%d, want=1", len(args))
@@ -58,6 +72,9 @@ var builtins = map[string]*object.Builtin{
 		},
 	},
 	"rest": {
+		MinArgs: 1,
+		MaxArgs: 1,
+		Scheme:  "array<a> -> array<a>",
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 1 {
 				return newError("wrong number of arguments. got=%d, want=1", len(args))
@@ -77,21 +94,213 @@ var builtins = map[string]*object.Builtin{
 		},
 	},
 	"push": {
+		MinArgs: 1,
+		MaxArgs: object.VARIADIC,
+		Scheme:  "(array<a>, a) -> array<a>",
 		Fn: func(args ...object.Object) object.Object {
+			if len(args) == 0 {
+				return newError("wrong number of arguments. got=0, want at least 1")
+			}
+
 			switch arg := args[0].(type) {
 			case *object.Array:
-				if len(arg.Elements) >= 0 {
-					newElements := make([]object.Object, 0, len(arg.Elements)+len(args)-1)
-					copy(newElements, arg.Elements)
-					return &object.Array{Elements: append(newElements, args[1:]...)}
-				}
-				return NULL
+				newElements := make([]object.Object, len(arg.Elements), len(arg.Elements)+len(args)-1)
+				copy(newElements, arg.Elements)
+				return &object.Array{Elements: append(newElements, args[1:]...)}
 			default:
 				return newError("argument to `push` must be ARRAY, got %s", args[0].Type())
 			}
 		},
 	},
+	"keys": {
+		MinArgs: 1,
+		MaxArgs: 1,
+		Scheme:  "hash<k, v> -> array<k>",
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+
+			hash, ok := args[0].(*object.Hash)
+			if !ok {
+				return newError("argument to `keys` must be HASH, got %s", args[0].Type())
+			}
+
+			elements := make([]object.Object, hash.Len())
+			for i, pair := range hash.Pairs() {
+				elements[i] = pair.Key
+			}
+			return &object.Array{Elements: elements}
+		},
+	},
+	"values": {
+		MinArgs: 1,
+		MaxArgs: 1,
+		Scheme:  "hash<k, v> -> array<v>",
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+
+			hash, ok := args[0].(*object.Hash)
+			if !ok {
+				return newError("argument to `values` must be HASH, got %s", args[0].Type())
+			}
+
+			elements := make([]object.Object, hash.Len())
+			for i, pair := range hash.Pairs() {
+				elements[i] = pair.Value
+			}
+			return &object.Array{Elements: elements}
+		},
+	},
+	// entries returns [key, value] arrays, which mix types so it has no scheme
+	"entries": {
+		MinArgs: 1,
+		MaxArgs: 1,
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+
+			hash, ok := args[0].(*object.Hash)
+			if !ok {
+				return newError("argument to `entries` must be HASH, got %s", args[0].Type())
+			}
+
+			elements := make([]object.Object, hash.Len())
+			for i, pair := range hash.Pairs() {
+				elements[i] = &object.Array{Elements: []object.Object{pair.Key, pair.Value}}
+			}
+			return &object.Array{Elements: elements}
+		},
+	},
+	"has": {
+		MinArgs: 2,
+		MaxArgs: 2,
+		Scheme:  "(hash<k, v>, k) -> bool",
+		Fn: func(args ...object.Object) object.Object {
+			hash, key, err := hashAndKey("has", args)
+			if err != nil {
+				return err
+			}
+
+			_, ok := hash.Get(key)
+			return nativeBoolToBooleanObject(ok)
+		},
+	},
+	// delete returns a copy of the hash without the key, delete_in_place removes
+	// it from the hash itself and reports whether it was present.
+	"delete": {
+		MinArgs: 2,
+		MaxArgs: 2,
+		Scheme:  "(hash<k, v>, k) -> hash<k, v>",
+		Fn: func(args ...object.Object) object.Object {
+			hash, key, err := hashAndKey("delete", args)
+			if err != nil {
+				return err
+			}
+
+			result := &object.Hash{}
+			mergeHash(result, hash)
+			result.Delete(key)
+			return result
+		},
+	},
+	"delete_in_place": {
+		MinArgs: 2,
+		MaxArgs: 2,
+		Scheme:  "(hash<k, v>, k) -> bool",
+		Fn: func(args ...object.Object) object.Object {
+			hash, key, err := hashAndKey("delete_in_place", args)
+			if err != nil {
+				return err
+			}
+			if hash.Frozen() {
+				return newError("`delete_in_place` cannot modify a read-only hash")
+			}
+
+			return nativeBoolToBooleanObject(hash.Delete(key))
+		},
+	},
+	// merge returns a new hash with the pairs of all its arguments, values of
+	// later hashes win.
+	"merge": {
+		MinArgs: 1,
+		MaxArgs: object.VARIADIC,
+		Scheme:  "(hash<k, v>, hash<k, v>) -> hash<k, v>",
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) == 0 {
+				return newError("wrong number of arguments. got=0, want at least 1")
+			}
+
+			result := &object.Hash{}
+			for i, arg := range args {
+				hash, ok := arg.(*object.Hash)
+				if !ok {
+					return newError("argument %d to `merge` must be HASH, got %s", i+1, arg.Type())
+				}
+				mergeHash(result, hash)
+			}
+			return result
+		},
+	},
+	"exit": {
+		MinArgs: 0,
+		MaxArgs: 1,
+		Scheme:  "int -> null",
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) > 1 {
+				return newError("wrong number of arguments. got=%d, want=0 or 1", len(args))
+			}
+			if len(args) == 0 {
+				return &object.Exit{Code: 0}
+			}
+
+			code, ok := args[0].(*object.Integer)
+			if !ok {
+				return newError("argument to `exit` must be INTEGER, got %s", args[0].Type())
+			}
+
+			return &object.Exit{Code: code.Value}
+		},
+	},
+	"assert": {
+		MinArgs: 1,
+		MaxArgs: 2,
+		Scheme:  "(a, string) -> null",
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 && len(args) != 2 {
+				return newError("wrong number of arguments. got=%d, want=1 or 2", len(args))
+			}
+			if IsTruthy(args[0]) {
+				return NULL
+			}
+			if len(args) == 2 {
+				return newError("assertion failed: %s", args[1].Inspect())
+			}
+
+			return newError("assertion failed")
+		},
+	},
+	"assert_eq": {
+		MinArgs: 2,
+		MaxArgs: 2,
+		Scheme:  "(a, a) -> null",
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return newError("wrong number of arguments. got=%d, want=2", len(args))
+			}
+			if objectsEqual(args[0], args[1]) {
+				return NULL
+			}
+
+			return newError("assertion failed: expected %s, got %s", inspect(args[1]), inspect(args[0]))
+		},
+	},
 	"print": {
+		MinArgs: 0,
+		MaxArgs: object.VARIADIC,
 		Fn: func(args ...object.Object) object.Object {
 			for _, a := range args {
 				fmt.Println(a.Inspect())
@@ -101,3 +310,154 @@ var builtins = map[string]*object.Builtin{
 		},
 	},
 }
+
+func init() {
+	// assert_error calls back into the evaluator, so it's registered here to avoid
+	// an initialization cycle with builtins.
+	builtins["assert_error"] = &object.Builtin{
+		MinArgs: 1,
+		MaxArgs: 2,
+		Scheme:  "(() -> a, string) -> null",
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 && len(args) != 2 {
+				return newError("wrong number of arguments. got=%d, want=1 or 2", len(args))
+			}
+			if args[0].Type() != object.FUNCTION {
+				return newError("argument to `assert_error` must be FUNCTION, got %s", args[0].Type())
+			}
+
+			evaluated := applyFunction(args[0], nil)
+			if evaluated == nil {
+				evaluated = NULL
+			}
+			result, ok := evaluated.(*object.ErrorValue)
+			if !ok {
+				return newError("assertion failed: expected an error, got %s", inspect(evaluated))
+			}
+			if len(args) == 1 {
+				return NULL
+			}
+
+			msg, ok := args[1].(*object.String)
+			if !ok {
+				return newError("second argument to `assert_error` must be STRING, got %s", args[1].Type())
+			}
+			if !strings.Contains(result.Message, msg.Value) {
+				return newError("assertion failed: expected error containing %q, got %q", msg.Value, result.Message)
+			}
+
+			return NULL
+		},
+	}
+}
+
+// hashAndKey validates the arguments of the builtins taking a hash and a key.
+func hashAndKey(name string, args []object.Object) (*object.Hash, object.Hashable, *object.ErrorValue) {
+	if len(args) != 2 {
+		return nil, nil, newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+
+	hash, ok := args[0].(*object.Hash)
+	if !ok {
+		return nil, nil, newError("first argument to `%s` must be HASH, got %s", name, args[0].Type())
+	}
+	key, ok := args[1].(object.Hashable)
+	if !ok {
+		return nil, nil, newError("unusable as hash key: %s", args[1].Type())
+	}
+
+	return hash, key, nil
+}
+
+// mergeHash sets the pairs of src into dst.
+func mergeHash(dst, src *object.Hash) {
+	for _, pair := range src.Pairs() {
+		dst.Set(pair.Key.(object.Hashable), pair.Value)
+	}
+}
+
+// objectsEqual reports whether a and b hold the same value, comparing arrays and
+// hashes element by element.
+func objectsEqual(a, b object.Object) bool {
+	if a.Type() != b.Type() {
+		return false
+	}
+
+	switch a := a.(type) {
+	case *object.Integer:
+		return a.Value == b.(*object.Integer).Value
+	case *object.BigInt:
+		return a.Value.Cmp(b.(*object.BigInt).Value) == 0
+	case *object.Float:
+		return a.Value == b.(*object.Float).Value
+	case *object.String:
+		return a.Value == b.(*object.String).Value
+	case *object.Boolean:
+		return a.Value == b.(*object.Boolean).Value
+	case *object.Time:
+		return a.Value.Equal(b.(*object.Time).Value)
+	case *object.Duration:
+		return a.Value == b.(*object.Duration).Value
+	case *object.Null:
+		return true
+	case *object.Array:
+		other := b.(*object.Array)
+		if len(a.Elements) != len(other.Elements) {
+			return false
+		}
+		for i := range a.Elements {
+			if !objectsEqual(a.Elements[i], other.Elements[i]) {
+				return false
+			}
+		}
+		return true
+	case *object.Hash:
+		other := b.(*object.Hash)
+		if a.Len() != other.Len() {
+			return false
+		}
+		for _, pair := range a.Pairs() {
+			value, ok := other.Get(pair.Key.(object.Hashable))
+			if !ok || !objectsEqual(pair.Value, value) {
+				return false
+			}
+		}
+		return true
+	default:
+		return a == b
+	}
+}
+
+// inspect returns the representation of o used in messages, quoting strings so
+// they can be told apart from other values.
+func inspect(o object.Object) string {
+	if s, ok := o.(*object.String); ok {
+		return fmt.Sprintf("%q", s.Value)
+	}
+
+	return o.Inspect()
+}
+
+// LookupBuiltin returns the builtin with the given name.
+func LookupBuiltin(name string) (*object.Builtin, bool) {
+	b, ok := builtins[name]
+	return b, ok
+}
+
+// LookupModule returns the module with the given name, e.g. math. Modules are
+// read-only.
+func LookupModule(name string) (*object.Hash, bool) {
+	m, ok := modules[name]
+	return m, ok
+}
+
+// BuiltinNames returns the sorted names of all builtin functions.
+func BuiltinNames() []string {
+	names := make([]string, 0, len(builtins))
+	for name := range builtins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
@@ -0,0 +1,570 @@
+package evaluator
+
+import (
+	"sort"
+	"time"
+
+	"github.com/gkampitakis/monkey/object"
+)
+
+// The collection builtins call back into monkey functions, so like assert_error
+// they are registered on init to avoid an initialization cycle with builtins.
+// Callbacks are called with one element at a time, an error returned by a
+// callback stops the iteration and is returned as is.
+func init() {
+	builtins["map"] = &object.Builtin{
+		MinArgs: 2,
+		MaxArgs: 2,
+		Scheme:  "(array<a>, a -> b) -> array<b>",
+		Fn: func(args ...object.Object) object.Object {
+			array, fn, err := arrayAndFunction("map", args)
+			if err != nil {
+				return err
+			}
+
+			elements := make([]object.Object, len(array.Elements))
+			for i, el := range array.Elements {
+				result := call(fn, el)
+				if isUnwinding(result) {
+					return result
+				}
+				elements[i] = result
+			}
+			return &object.Array{Elements: elements}
+		},
+	}
+	builtins["filter"] = &object.Builtin{
+		MinArgs: 2,
+		MaxArgs: 2,
+		Scheme:  "(array<a>, a -> b) -> array<a>",
+		Fn: func(args ...object.Object) object.Object {
+			array, fn, err := arrayAndFunction("filter", args)
+			if err != nil {
+				return err
+			}
+
+			elements := []object.Object{}
+			for _, el := range array.Elements {
+				result := call(fn, el)
+				if isUnwinding(result) {
+					return result
+				}
+				if IsTruthy(result) {
+					elements = append(elements, el)
+				}
+			}
+			return &object.Array{Elements: elements}
+		},
+	}
+	builtins["reduce"] = &object.Builtin{
+		MinArgs: 2,
+		MaxArgs: 3,
+		Scheme:  "(array<a>, (b, a) -> b, b) -> b",
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 && len(args) != 3 {
+				return newError("wrong number of arguments. got=%d, want=2 or 3", len(args))
+			}
+			array, fn, err := arrayAndFunction("reduce", args[:2])
+			if err != nil {
+				return err
+			}
+
+			elements := array.Elements
+			var acc object.Object
+			if len(args) == 3 {
+				acc = args[2]
+			} else {
+				if len(elements) == 0 {
+					return newError("`reduce` of empty array with no initial value")
+				}
+				acc, elements = elements[0], elements[1:]
+			}
+
+			for _, el := range elements {
+				acc = call(fn, acc, el)
+				if isUnwinding(acc) {
+					return acc
+				}
+			}
+			return acc
+		},
+	}
+	builtins["each"] = &object.Builtin{
+		MinArgs: 2,
+		MaxArgs: 2,
+		Scheme:  "(array<a>, a -> b) -> null",
+		Fn: func(args ...object.Object) object.Object {
+			array, fn, err := arrayAndFunction("each", args)
+			if err != nil {
+				return err
+			}
+
+			for _, el := range array.Elements {
+				if result := call(fn, el); isUnwinding(result) {
+					return result
+				}
+			}
+			return NULL
+		},
+	}
+	builtins["find"] = &object.Builtin{
+		MinArgs: 2,
+		MaxArgs: 2,
+		Scheme:  "(array<a>, a -> b) -> a",
+		Fn: func(args ...object.Object) object.Object {
+			array, fn, err := arrayAndFunction("find", args)
+			if err != nil {
+				return err
+			}
+
+			for _, el := range array.Elements {
+				result := call(fn, el)
+				if isUnwinding(result) {
+					return result
+				}
+				if IsTruthy(result) {
+					return el
+				}
+			}
+			return NULL
+		},
+	}
+	builtins["any"] = &object.Builtin{
+		MinArgs: 2,
+		MaxArgs: 2,
+		Scheme:  "(array<a>, a -> b) -> bool",
+		Fn: func(args ...object.Object) object.Object {
+			array, fn, err := arrayAndFunction("any", args)
+			if err != nil {
+				return err
+			}
+
+			for _, el := range array.Elements {
+				result := call(fn, el)
+				if isUnwinding(result) {
+					return result
+				}
+				if IsTruthy(result) {
+					return TRUE
+				}
+			}
+			return FALSE
+		},
+	}
+	builtins["all"] = &object.Builtin{
+		MinArgs: 2,
+		MaxArgs: 2,
+		Scheme:  "(array<a>, a -> b) -> bool",
+		Fn: func(args ...object.Object) object.Object {
+			array, fn, err := arrayAndFunction("all", args)
+			if err != nil {
+				return err
+			}
+
+			for _, el := range array.Elements {
+				result := call(fn, el)
+				if isUnwinding(result) {
+					return result
+				}
+				if !IsTruthy(result) {
+					return FALSE
+				}
+			}
+			return TRUE
+		},
+	}
+	// sort returns a sorted copy of the array. Without a comparator, elements must
+	// be all integers or all strings, else the comparator reports whether its first
+	// argument goes before the second. Equal elements keep their order.
+	builtins["sort"] = &object.Builtin{
+		MinArgs: 1,
+		MaxArgs: 2,
+		Scheme:  "(array<a>, (a, a) -> bool) -> array<a>",
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 && len(args) != 2 {
+				return newError("wrong number of arguments. got=%d, want=1 or 2", len(args))
+			}
+			array, ok := args[0].(*object.Array)
+			if !ok {
+				return newError("first argument to `sort` must be ARRAY, got %s", args[0].Type())
+			}
+
+			elements := make([]object.Object, len(array.Elements))
+			copy(elements, array.Elements)
+
+			var err object.Object
+			less := func(a, b object.Object) bool {
+				cmp, cmpErr := compareObjects("sort", a, b)
+				if cmpErr != nil {
+					err = cmpErr
+					return false
+				}
+				return cmp < 0
+			}
+			if len(args) == 2 {
+				fn := args[1]
+				if !isCallable(fn) {
+					return newError("second argument to `sort` must be FUNCTION, got %s", fn.Type())
+				}
+				less = func(a, b object.Object) bool {
+					result := call(fn, a, b)
+					if isUnwinding(result) {
+						err = result
+						return false
+					}
+					return IsTruthy(result)
+				}
+			}
+
+			sort.SliceStable(elements, func(i, j int) bool {
+				// once failed, stop calling the comparator and return the error
+				return err == nil && less(elements[i], elements[j])
+			})
+			if err != nil {
+				return err
+			}
+			return &object.Array{Elements: elements}
+		},
+	}
+	builtins["reverse"] = &object.Builtin{
+		MinArgs: 1,
+		MaxArgs: 1,
+		Scheme:  "array<a> -> array<a>",
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+			array, ok := args[0].(*object.Array)
+			if !ok {
+				return newError("argument to `reverse` must be ARRAY, got %s", args[0].Type())
+			}
+
+			n := len(array.Elements)
+			elements := make([]object.Object, n)
+			for i, el := range array.Elements {
+				elements[n-1-i] = el
+			}
+			return &object.Array{Elements: elements}
+		},
+	}
+	// zip pairs up the elements of its arrays, stopping at the shortest one. The
+	// pairs mix element types, so it has no scheme.
+	builtins["zip"] = &object.Builtin{
+		MinArgs: 1,
+		MaxArgs: object.VARIADIC,
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) == 0 {
+				return newError("wrong number of arguments. got=0, want at least 1")
+			}
+
+			arrays := make([]*object.Array, len(args))
+			n := -1
+			for i, arg := range args {
+				array, ok := arg.(*object.Array)
+				if !ok {
+					return newError("argument %d to `zip` must be ARRAY, got %s", i+1, arg.Type())
+				}
+				arrays[i] = array
+				if n == -1 || len(array.Elements) < n {
+					n = len(array.Elements)
+				}
+			}
+
+			elements := make([]object.Object, n)
+			for i := range elements {
+				tuple := make([]object.Object, len(arrays))
+				for j, array := range arrays {
+					tuple[j] = array.Elements[i]
+				}
+				elements[i] = &object.Array{Elements: tuple}
+			}
+			return &object.Array{Elements: elements}
+		},
+	}
+	// flatten inlines the elements of nested arrays one level deep, other elements
+	// are kept as they are.
+	builtins["flatten"] = &object.Builtin{
+		MinArgs: 1,
+		MaxArgs: 1,
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+			array, ok := args[0].(*object.Array)
+			if !ok {
+				return newError("argument to `flatten` must be ARRAY, got %s", args[0].Type())
+			}
+
+			elements := []object.Object{}
+			for _, el := range array.Elements {
+				if nested, ok := el.(*object.Array); ok {
+					elements = append(elements, nested.Elements...)
+				} else {
+					elements = append(elements, el)
+				}
+			}
+			return &object.Array{Elements: elements}
+		},
+	}
+	// range returns the integers from start up to, not including, end. With a
+	// single argument start is 0 and a negative step counts down.
+	builtins["range"] = &object.Builtin{
+		MinArgs: 1,
+		MaxArgs: 3,
+		Scheme:  "(int, int, int) -> array<int>",
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) < 1 || len(args) > 3 {
+				return newError("wrong number of arguments. got=%d, want=1 to 3", len(args))
+			}
+
+			bounds := make([]int, len(args))
+			for i, arg := range args {
+				integer, ok := arg.(*object.Integer)
+				if !ok {
+					return newError("argument %d to `range` must be INTEGER, got %s", i+1, arg.Type())
+				}
+				bounds[i] = integer.Value
+			}
+
+			start, end, step := 0, bounds[0], 1
+			if len(bounds) > 1 {
+				start, end = bounds[0], bounds[1]
+			}
+			if len(bounds) > 2 {
+				step = bounds[2]
+			}
+			if step == 0 {
+				return newError("`range` step must not be 0")
+			}
+
+			count := rangeLength(start, end, step)
+			if count > maxRangeLength {
+				return newError("`range` result longer than %d elements", maxRangeLength)
+			}
+			elements := make([]object.Object, count)
+			for i := range elements {
+				elements[i] = &object.Integer{Value: start}
+				// the increment after the last element may overflow, it's unused
+				start += step
+			}
+			return &object.Array{Elements: elements}
+		},
+	}
+	builtins["sum"] = &object.Builtin{
+		MinArgs: 1,
+		MaxArgs: 1,
+		Scheme:  "array<a> -> a",
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+			array, ok := args[0].(*object.Array)
+			if !ok {
+				return newError("argument to `sum` must be ARRAY, got %s", args[0].Type())
+			}
+
+			var total object.Object = &object.Integer{Value: 0}
+			for _, el := range array.Elements {
+				if !isNumber(el) {
+					return newError("`sum` expects INTEGER or FLOAT elements, got %s", el.Type())
+				}
+				if total = evalInfixExpression("+", total, el); isUnwinding(total) {
+					return total
+				}
+			}
+			return total
+		},
+	}
+	builtins["min"] = extremum("min", -1)
+	builtins["max"] = extremum("max", 1)
+	// uniq returns the elements of the array without later duplicates.
+	builtins["uniq"] = &object.Builtin{
+		MinArgs: 1,
+		MaxArgs: 1,
+		Scheme:  "array<a> -> array<a>",
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+			array, ok := args[0].(*object.Array)
+			if !ok {
+				return newError("argument to `uniq` must be ARRAY, got %s", args[0].Type())
+			}
+
+			seen := &object.Hash{}
+			elements := []object.Object{}
+		next:
+			for _, el := range array.Elements {
+				if key, ok := el.(object.Hashable); ok {
+					if _, ok := seen.Get(key); ok {
+						continue
+					}
+					seen.Set(key, TRUE)
+				} else {
+					for _, kept := range elements {
+						if objectsEqual(kept, el) {
+							continue next
+						}
+					}
+				}
+				elements = append(elements, el)
+			}
+			return &object.Array{Elements: elements}
+		},
+	}
+	// group_by groups the elements of the array by the key the function returns
+	// for them, keys keep the order they were first returned in.
+	builtins["group_by"] = &object.Builtin{
+		MinArgs: 2,
+		MaxArgs: 2,
+		Scheme:  "(array<a>, a -> k) -> hash<k, array<a>>",
+		Fn: func(args ...object.Object) object.Object {
+			array, fn, err := arrayAndFunction("group_by", args)
+			if err != nil {
+				return err
+			}
+
+			groups := &object.Hash{}
+			for _, el := range array.Elements {
+				result := call(fn, el)
+				if isUnwinding(result) {
+					return result
+				}
+				key, ok := result.(object.Hashable)
+				if !ok {
+					return newError("unusable as hash key: %s", result.Type())
+				}
+
+				group, ok := groups.Get(key)
+				if !ok {
+					group = &object.Array{}
+					groups.Set(key, group)
+				}
+				group.(*object.Array).Elements = append(group.(*object.Array).Elements, el)
+			}
+			return groups
+		},
+	}
+}
+
+// extremum returns the min or max builtin, which keep the element comparing as
+// sign against the others. Empty arrays return null.
+func extremum(name string, sign int) *object.Builtin {
+	return &object.Builtin{
+		MinArgs: 1,
+		MaxArgs: 1,
+		Scheme:  "array<a> -> a",
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+			array, ok := args[0].(*object.Array)
+			if !ok {
+				return newError("argument to `%s` must be ARRAY, got %s", name, args[0].Type())
+			}
+			if len(array.Elements) == 0 {
+				return NULL
+			}
+
+			result := array.Elements[0]
+			for _, el := range array.Elements[1:] {
+				cmp, err := compareObjects(name, el, result)
+				if err != nil {
+					return err
+				}
+				if cmp == sign {
+					result = el
+				}
+			}
+			return result
+		},
+	}
+}
+
+// maxRangeLength is the number of elements of the longest array range returns.
+const maxRangeLength = 1 << 24
+
+// rangeLength returns the number of integers from start up to, not including,
+// end counting by step. The distance is computed unsigned so it can't overflow.
+func rangeLength(start, end, step int) uint {
+	switch {
+	case step > 0 && start < end:
+		return (uint(end)-uint(start)-1)/uint(step) + 1
+	case step < 0 && start > end:
+		return (uint(start)-uint(end)-1)/(-uint(step)) + 1
+	}
+
+	return 0
+}
+
+// compareObjects compares two numbers, strings, times or durations, returning
+// -1, 0 or 1. Other values can't be compared.
+func compareObjects(name string, a, b object.Object) (int, *object.ErrorValue) {
+	// numbers of different types compare by value
+	if isNumber(a) && isNumber(b) && a.Type() != b.Type() {
+		return compareNumbers(a, b), nil
+	}
+	if a.Type() != b.Type() {
+		return 0, newError("`%s` cannot compare %s and %s", name, a.Type(), b.Type())
+	}
+
+	switch a := a.(type) {
+	case *object.Integer:
+		return compare(a.Value, b.(*object.Integer).Value), nil
+	case *object.BigInt:
+		return a.Value.Cmp(b.(*object.BigInt).Value), nil
+	case *object.Float:
+		return compare(a.Value, b.(*object.Float).Value), nil
+	case *object.String:
+		return compare(a.Value, b.(*object.String).Value), nil
+	case *object.Time:
+		return a.Value.Compare(b.(*object.Time).Value), nil
+	case *object.Duration:
+		return compare(a.Value, b.(*object.Duration).Value), nil
+	default:
+		return 0, newError("`%s` cannot compare %s values", name, a.Type())
+	}
+}
+
+func compare[T int | float64 | string | time.Duration](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// arrayAndFunction validates the arguments of the builtins taking an array and a
+// callback.
+func arrayAndFunction(name string, args []object.Object) (*object.Array, object.Object, *object.ErrorValue) {
+	if len(args) != 2 {
+		return nil, nil, newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+
+	array, ok := args[0].(*object.Array)
+	if !ok {
+		return nil, nil, newError("first argument to `%s` must be ARRAY, got %s", name, args[0].Type())
+	}
+	if !isCallable(args[1]) {
+		return nil, nil, newError("second argument to `%s` must be FUNCTION, got %s", name, args[1].Type())
+	}
+
+	return array, args[1], nil
+}
+
+func isCallable(o object.Object) bool {
+	return o.Type() == object.FUNCTION || o.Type() == object.BUILTIN
+}
+
+// call applies fn to args, functions with an empty body return null.
+func call(fn object.Object, args ...object.Object) object.Object {
+	result := applyFunction(fn, args)
+	if result == nil {
+		return NULL
+	}
+
+	return result
+}
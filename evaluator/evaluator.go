@@ -2,9 +2,12 @@ package evaluator
 
 import (
 	"fmt"
+	"math"
+	"math/big"
 
 	"github.com/gkampitakis/monkey/ast"
 	"github.com/gkampitakis/monkey/object"
+	"github.com/gkampitakis/monkey/token"
 )
 
 var (
@@ -13,6 +16,41 @@ var (
 	NULL  = &object.Null{}
 )
 
+// Tracer is notified every time a monkey function is entered and exited.
+type Tracer interface {
+	Enter(fn *object.Function)
+	Exit(fn *object.Function)
+}
+
+var tracer Tracer
+
+// SetTracer installs t to be notified of function calls, nil removes it.
+func SetTracer(t Tracer) {
+	tracer = t
+}
+
+// Branches of IfExpression and WhileExpression reported to Coverage.
+const (
+	BRANCH_THEN = iota
+	BRANCH_ELSE
+	BRANCH_BODY
+)
+
+// Coverage is notified every time a statement is executed or a branch is taken.
+// The else branch of an IfExpression is reported when its condition is false,
+// even if it has no alternative.
+type Coverage interface {
+	Statement(stmt ast.Statement)
+	Branch(node ast.Expression, branch int)
+}
+
+var coverage Coverage
+
+// SetCoverage installs c to be notified of executed statements, nil removes it.
+func SetCoverage(c Coverage) {
+	coverage = c
+}
+
 func Eval(node ast.Node, env *object.Environment) object.Object {
 	switch node := node.(type) {
 	case *ast.Program:
@@ -23,17 +61,17 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		return Eval(node.Expression, env)
 	case *ast.ReturnStatement:
 		val := Eval(node.ReturnValue, env)
-		if isError(val) {
+		if isUnwinding(val) {
 			return val
 		}
 		return &object.ReturnValue{Value: val}
 	case *ast.InfixExpression:
 		left := Eval(node.Left, env)
-		if isError(left) {
+		if isUnwinding(left) {
 			return left
 		}
 		right := Eval(node.Right, env)
-		if isError(right) {
+		if isUnwinding(right) {
 			return right
 		}
 
@@ -44,16 +82,19 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		return evalWhileExpression(node, env)
 	case *ast.LetStatement:
 		val := Eval(node.Value, env)
-		if isError(val) {
+		if isUnwinding(val) {
 			return val
 		}
+		if fn, ok := val.(*object.Function); ok && fn.Name == "" {
+			fn.Name = string(node.Name.Value)
+		}
 
 		env.Set(string(node.Name.Value), val)
 	case *ast.Identifier:
 		return evalIdentifier(node, env)
 	case *ast.PrefixExpression:
 		right := Eval(node.Right, env)
-		if isError(right) {
+		if isUnwinding(right) {
 			return right
 		}
 		return evalPrefixExpression(node.Operator, right)
@@ -61,21 +102,26 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		params := node.Parameters
 		body := node.Body
 
-		return &object.Function{Parameters: params, Body: body, Env: env}
+		return &object.Function{Parameters: params, Body: body, Env: env, Pos: node.Token.Pos}
 	case *ast.CallExpression:
 		function := Eval(node.Function, env)
-		if isError(function) {
+		if isUnwinding(function) {
 			return function
 		}
 		args := evalExpressions(node.Arguments, env)
-		if len(args) == 1 && isError(args[0]) {
+		if len(args) == 1 && isUnwinding(args[0]) {
 			return args[0]
 		}
 
-		return applyFunction(function, args)
+		result := applyFunction(function, args)
+		if err, ok := result.(*object.ErrorValue); ok && !err.Pos.IsValid() {
+			err.Pos = callPosition(node)
+		}
+
+		return result
 	case *ast.ArrayLiteral:
 		elements := evalExpressions(node.Elements, env)
-		if len(elements) == 1 && isError(elements[0]) {
+		if len(elements) == 1 && isUnwinding(elements[0]) {
 			return elements[0]
 		}
 
@@ -84,17 +130,28 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		return evalHashLiteral(node, env)
 	case *ast.IndexExpression:
 		left := Eval(node.Left, env)
-		if isError(left) {
+		if isUnwinding(left) {
 			return left
 		}
 		index := Eval(node.Index, env)
-		if isError(index) {
+		if isUnwinding(index) {
 			return index
 		}
+		// x.name is sugar for x["name"] on hashes, times and durations
+		if node.Token.Type == token.DOT && !hasFields(left) {
+			return newError("%s has no field %s", left.Type(), index.Inspect())
+		}
 
 		return evalIndexExpression(left, index)
+	case *ast.SliceExpression:
+		return evalSliceExpression(node, env)
 	case *ast.IntegerLiteral:
+		if node.Big != nil {
+			return &object.BigInt{Value: node.Big}
+		}
 		return &object.Integer{Value: node.Value}
+	case *ast.FloatLiteral:
+		return &object.Float{Value: node.Value}
 	case *ast.StringLiteral:
 		return &object.String{Value: node.Value}
 	case *ast.Boolean:
@@ -104,9 +161,21 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 	return nil
 }
 
+// Apply calls fn, either a function or a builtin, with args.
+func Apply(fn object.Object, args ...object.Object) object.Object {
+	return applyFunction(fn, args)
+}
+
 func applyFunction(fn object.Object, args []object.Object) object.Object {
 	switch fn := fn.(type) {
 	case *object.Function:
+		if len(args) < len(fn.Parameters) {
+			return newError("wrong number of arguments. got=%d, want=%d", len(args), len(fn.Parameters))
+		}
+		if tracer != nil {
+			tracer.Enter(fn)
+			defer tracer.Exit(fn)
+		}
 		extendedEnv := extendFunctionEnv(fn, args)
 		evaluated := Eval(fn.Body, extendedEnv)
 		return unwrapReturnValue(evaluated)
@@ -137,12 +206,22 @@ func extendFunctionEnv(fn *object.Function, args []object.Object) *object.Enviro
 	return env
 }
 
+// callPosition returns the position of the called expression when it's a name,
+// else the position of the '(' token.
+func callPosition(node *ast.CallExpression) token.Position {
+	if ident, ok := node.Function.(*ast.Identifier); ok {
+		return ident.Token.Pos
+	}
+
+	return node.Token.Pos
+}
+
 func evalExpressions(expressions []ast.Expression, env *object.Environment) []object.Object {
 	result := []object.Object{}
 
 	for _, exp := range expressions {
 		evaluated := Eval(exp, env)
-		if isError(evaluated) {
+		if isUnwinding(evaluated) {
 			return []object.Object{evaluated}
 		}
 
@@ -161,19 +240,29 @@ func evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object
 		return builtin
 	}
 
+	if module, ok := modules[string(node.Value)]; ok {
+		return module
+	}
+
 	return newError("identifier not found: " + string(node.Value))
 }
 
 func evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Object {
 	condition := Eval(ie.Condition, env)
-	if isError(condition) {
+	if isUnwinding(condition) {
 		return condition
 	}
 
-	if isTruthy(condition) {
+	if IsTruthy(condition) {
+		if coverage != nil {
+			coverage.Branch(ie, BRANCH_THEN)
+		}
 		return Eval(ie.Consequence, env)
 	}
 
+	if coverage != nil {
+		coverage.Branch(ie, BRANCH_ELSE)
+	}
 	if ie.Alternative != nil {
 		return Eval(ie.Alternative, env)
 	}
@@ -181,7 +270,9 @@ func evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Obje
 	return NULL
 }
 
-func isTruthy(obj object.Object) bool {
+// IsTruthy reports whether obj is considered true by conditions, only null,
+// false and numbers that aren't greater than 0 are falsy.
+func IsTruthy(obj object.Object) bool {
 	switch obj {
 	case NULL:
 		return false
@@ -190,8 +281,12 @@ func isTruthy(obj object.Object) bool {
 	case FALSE:
 		return false
 	default:
-		if obj.Type() == object.INTEGER {
-			return obj.(*object.Integer).Value > 0
+		if f, ok := obj.(*object.Float); ok {
+			// NaN isn't greater than 0 either
+			return f.Value > 0
+		}
+		if isInteger(obj) {
+			return toBigInt(obj).Sign() > 0
 		}
 		return true
 	}
@@ -201,8 +296,14 @@ func evalInfixExpression(operator string, left, right object.Object) object.Obje
 	switch {
 	case left.Type() == object.INTEGER && right.Type() == object.INTEGER:
 		return evalIntegerInfixExpression(operator, left, right)
+	case isInteger(left) && isInteger(right):
+		return evalBigIntInfixExpression(operator, toBigInt(left), toBigInt(right))
+	case isNumber(left) && isNumber(right):
+		return evalFloatInfixExpression(operator, toFloat(left), toFloat(right))
 	case left.Type() == object.STRING && right.Type() == object.STRING:
 		return evalStringInfixExpression(operator, left, right)
+	case isTimeOperation(left, right):
+		return evalTimeInfixExpression(operator, left, right)
 	case operator == "==":
 		return nativeBoolToBooleanObject(left == right)
 	case operator == "!=":
@@ -219,13 +320,19 @@ func evalIntegerInfixExpression(operator string, left, right object.Object) obje
 	rightVal := right.(*object.Integer).Value
 
 	switch operator {
-	case "+":
-		return &object.Integer{Value: leftVal + rightVal}
-	case "-":
-		return &object.Integer{Value: leftVal - rightVal}
-	case "*":
-		return &object.Integer{Value: leftVal * rightVal}
+	case "+", "-", "*", "**":
+		if value, ok := integerArithmetic(operator, leftVal, rightVal); ok {
+			return &object.Integer{Value: value}
+		}
+		return evalBigIntInfixExpression(operator, toBigInt(left), toBigInt(right))
 	case "/":
+		if rightVal == 0 {
+			return newError("division by zero: %d / 0", leftVal)
+		}
+		// the only quotient that doesn't fit, MinInt / -1
+		if rightVal == -1 && leftVal == math.MinInt {
+			return evalBigIntInfixExpression(operator, toBigInt(left), toBigInt(right))
+		}
 		return &object.Integer{Value: leftVal / rightVal}
 	case "<":
 		return nativeBoolToBooleanObject(leftVal < rightVal)
@@ -268,13 +375,21 @@ func evalPrefixExpression(operator string, o object.Object) object.Object {
 }
 
 func evalMinusPrefixOperatorExpression(o object.Object) object.Object {
-	if o.Type() != object.INTEGER {
+	switch o := o.(type) {
+	case *object.Integer:
+		if o.Value == math.MinInt {
+			return newInteger(new(big.Int).Neg(toBigInt(o)))
+		}
+		return &object.Integer{Value: -o.Value}
+	case *object.BigInt:
+		return newInteger(new(big.Int).Neg(o.Value))
+	case *object.Float:
+		return &object.Float{Value: -o.Value}
+	case *object.Duration:
+		return &object.Duration{Value: -o.Value}
+	default:
 		return newError("unknown operator: -%s", o.Type())
 	}
-
-	return &object.Integer{
-		Value: -o.(*object.Integer).Value,
-	}
 }
 
 func evalBangOperatorExpression(o object.Object) object.Object {
@@ -286,12 +401,8 @@ func evalBangOperatorExpression(o object.Object) object.Object {
 	case NULL:
 		return TRUE
 	default:
-		if o.Type() == object.INTEGER {
-			v := o.(*object.Integer).Value
-			if v > 0 {
-				return FALSE
-			}
-			return TRUE
+		if isNumber(o) {
+			return nativeBoolToBooleanObject(!IsTruthy(o))
 		}
 		return FALSE
 	}
@@ -301,11 +412,12 @@ func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) obje
 	var result object.Object
 
 	for _, stmt := range block.Statements {
+		if coverage != nil {
+			coverage.Statement(stmt)
+		}
 		result = Eval(stmt, env)
-		if result != nil {
-			if result.Type() == object.RETURN_VALUE || result.Type() == object.ERROR_VALUE {
-				return result
-			}
+		if result != nil && (result.Type() == object.RETURN_VALUE || isUnwinding(result)) {
+			return result
 		}
 	}
 
@@ -316,11 +428,14 @@ func evalProgram(stmts []ast.Statement, env *object.Environment) object.Object {
 	var result object.Object
 
 	for _, statement := range stmts {
+		if coverage != nil {
+			coverage.Statement(statement)
+		}
 		result = Eval(statement, env)
 		switch result := result.(type) {
 		case *object.ReturnValue:
 			return result.Value
-		case *object.ErrorValue:
+		case *object.ErrorValue, *object.Exit:
 			return result
 		}
 	}
@@ -334,11 +449,17 @@ func evalIndexExpression(left, index object.Object) object.Object {
 		return evalArrayIndexExpression(left, index)
 	case left.Type() == object.HASH:
 		return evalHashIndexExpression(left, index)
+	case left.Type() == object.TIME || left.Type() == object.DURATION:
+		return timeField(left, index)
 	default:
 		return newError("index operator not supported: %s", left.Type())
 	}
 }
 
+func hasFields(o object.Object) bool {
+	return o.Type() == object.HASH || o.Type() == object.TIME || o.Type() == object.DURATION
+}
+
 func evalHashIndexExpression(hash, index object.Object) object.Object {
 	h := hash.(*object.Hash)
 	i, ok := index.(object.Hashable)
@@ -346,17 +467,22 @@ func evalHashIndexExpression(hash, index object.Object) object.Object {
 		return newError("unusable as hash key: %s", index.Type())
 	}
 
-	pair, ok := h.Pairs[i.HashKey()]
+	value, ok := h.Get(i)
 	if !ok {
 		return NULL
 	}
 
-	return pair.Value
+	return value
 }
 
+// evalArrayIndexExpression returns the element at index, negative indexes count
+// from the end of the array.
 func evalArrayIndexExpression(array, index object.Object) object.Object {
 	arrayObject := array.(*object.Array)
 	idx := index.(*object.Integer).Value
+	if idx < 0 {
+		idx += len(arrayObject.Elements)
+	}
 	max := len(arrayObject.Elements) - 1
 	if idx < 0 || idx > max {
 		return NULL
@@ -365,11 +491,103 @@ func evalArrayIndexExpression(array, index object.Object) object.Object {
 	return arrayObject.Elements[idx]
 }
 
+func evalSliceExpression(node *ast.SliceExpression, env *object.Environment) object.Object {
+	left := Eval(node.Left, env)
+	if isUnwinding(left) {
+		return left
+	}
+
+	// strings are sliced by character like the string builtins count them
+	var length int
+	var runes []rune
+	switch left := left.(type) {
+	case *object.Array:
+		length = len(left.Elements)
+	case *object.String:
+		runes = []rune(left.Value)
+		length = len(runes)
+	default:
+		return newError("slice operator not supported: %s", left.Type())
+	}
+
+	// bounds holds start, end and step, nil when omitted
+	bounds := [3]*int{}
+	for i, exp := range []ast.Expression{node.Start, node.End, node.Step} {
+		if exp == nil {
+			continue
+		}
+		value := Eval(exp, env)
+		if isUnwinding(value) {
+			return value
+		}
+		integer, ok := value.(*object.Integer)
+		if !ok {
+			return newError("slice index must be INTEGER, got %s", value.Type())
+		}
+		bounds[i] = &integer.Value
+	}
+
+	step := 1
+	if bounds[2] != nil {
+		step = *bounds[2]
+	}
+	if step == 0 {
+		return newError("slice step cannot be zero")
+	}
+	start, end := sliceBounds(length, bounds[0], bounds[1], step)
+
+	var indexes []int
+	for i := start; (step > 0 && i < end) || (step < 0 && i > end); i += step {
+		indexes = append(indexes, i)
+	}
+
+	switch left := left.(type) {
+	case *object.Array:
+		elements := make([]object.Object, len(indexes))
+		for i, idx := range indexes {
+			elements[i] = left.Elements[idx]
+		}
+		return &object.Array{Elements: elements}
+	default:
+		if step == 1 {
+			return &object.String{Value: string(runes[start:max(start, end)])}
+		}
+		r := make([]rune, len(indexes))
+		for i, idx := range indexes {
+			r[i] = runes[idx]
+		}
+		return &object.String{Value: string(r)}
+	}
+}
+
+// sliceBounds resolves the start and end of a slice over length elements like
+// Python does: negative bounds count from the end and bounds out of range are
+// clamped. A negative step walks from the end towards the start.
+func sliceBounds(length int, start, end *int, step int) (int, int) {
+	resolve := func(bound *int, def, lower, upper int) int {
+		if bound == nil {
+			return def
+		}
+		b := *bound
+		if b < 0 {
+			b += length
+		}
+		return min(max(b, lower), upper)
+	}
+
+	if step > 0 {
+		return resolve(start, 0, 0, length), resolve(end, length, 0, length)
+	}
+
+	return resolve(start, length-1, -1, length-1), resolve(end, -1, -1, length-1)
+}
+
 func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Object {
-	pairs := make(map[object.HashKey]object.HashPair, len(node.Pairs))
-	for keyNode, valueNode := range node.Pairs {
+	hash := &object.Hash{}
+	// evaluated in source order, which the hash preserves
+	for _, keyNode := range ast.HashKeys(node) {
 		key := Eval(keyNode, env)
-		if isError(key) {
+		if isUnwinding(key) {
 			return key
 		}
 		hashKey, ok := key.(object.Hashable)
@@ -377,28 +595,30 @@ func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Obje
 			return newError("unusable as hash-key: %s", key.Type())
 		}
 
-		value := Eval(valueNode, env)
-		if isError(value) {
+		value := Eval(node.Pairs[keyNode], env)
+		if isUnwinding(value) {
 			return value
 		}
-		hashed := hashKey.HashKey()
-		pairs[hashed] = object.HashPair{Key: key, Value: value}
+		hash.Set(hashKey, value)
 	}
 
-	return &object.Hash{Pairs: pairs}
+	return hash
 }
 
 func evalWhileExpression(node *ast.WhileExpression, env *object.Environment) object.Object {
 	for {
 		condition := Eval(node.Condition, env)
-		if isError(condition) {
+		if isUnwinding(condition) {
 			return condition
 		}
-		if !isTruthy(condition) {
+		if !IsTruthy(condition) {
 			break
 		}
-		if result := Eval(node.Consequence, env); !isError(result) &&
-			(result.Type() == object.RETURN_VALUE || result.Type() == object.ERROR_VALUE) {
+		if coverage != nil {
+			coverage.Branch(node, BRANCH_BODY)
+		}
+		if result := Eval(node.Consequence, env); result != nil &&
+			(result.Type() == object.RETURN_VALUE || isUnwinding(result)) {
 			return result
 		}
 	}
@@ -417,10 +637,12 @@ func newError(v string, a ...interface{}) *object.ErrorValue {
 	return &object.ErrorValue{Message: fmt.Sprintf(v, a...)}
 }
 
-func isError(o object.Object) bool {
+// isUnwinding reports whether o stops the evaluation and is passed up to the
+// program, either an error or a request to exit the program.
+func isUnwinding(o object.Object) bool {
 	if o == nil {
 		return false
 	}
 
-	return o.Type() == object.ERROR_VALUE
+	return o.Type() == object.ERROR_VALUE || o.Type() == object.EXIT
 }
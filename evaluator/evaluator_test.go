@@ -1,12 +1,16 @@
 package evaluator_test
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/gkampitakis/monkey/evaluator"
 	"github.com/gkampitakis/monkey/lexer"
 	"github.com/gkampitakis/monkey/object"
 	"github.com/gkampitakis/monkey/parser"
+	"github.com/gkampitakis/monkey/token"
 	"github.com/stretchr/testify/require"
 )
 
@@ -62,6 +66,12 @@ func TestEvalIntegerExpressions(t *testing.T) {
 		{"3 * 3 * 3 + 10", 37},
 		{"3 * (3 * 3) + 10", 37},
 		{"(5 + 10 * 2 + 15 / 3) * 2 + -10", 50},
+		{"2 ** 10", 1024},
+		{"2 ** 3 ** 2", 512},
+		{"-2 ** 2", -4},
+		{"(-2) ** 3", -8},
+		{"0 ** 0", 1},
+		{"7 / -2", -3},
 	}
 
 	for _, tc := range tests {
@@ -69,6 +79,159 @@ func TestEvalIntegerExpressions(t *testing.T) {
 	}
 }
 
+func TestNumberOperators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1.5", "1.5"},
+		{"-2.0", "-2.0"},
+		{"1.5 + 1.5", "3.0"},
+		{"1 + 0.5", "1.5"},
+		{"1 / 2.0", "0.5"},
+		{"2.0 ** 0.5 * 2.0 ** 0.5", "2.0000000000000004"},
+		{"10.0 ** 300 * 10.0 ** 300", "+Inf"},
+		{"1.0 / 0", "+Inf"},
+		{"1 < 1.5", "true"},
+		{"2 == 2.0", "true"},
+		{"0.1 + 0.2 != 0.3", "true"},
+		{"2 ** 62", "4611686018427387904"},
+		{"2 ** -1", "[error]: negative exponent: 2 ** -1"},
+		{"1 / 0", "[error]: division by zero: 1 / 0"},
+		{"let f = fn(x) { 10 / x }; f(0)", "[error]: division by zero: 10 / 0"},
+		{"1.5 + true", "[error]: type mismatch: FLOAT + BOOLEAN"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.input, func(t *testing.T) {
+			require.Equal(t, tc.expected, testEval(tc.input).Inspect())
+		})
+	}
+}
+
+func TestBigIntegers(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		big      bool
+	}{
+		{"9223372036854775807 + 1", "9223372036854775808", true},
+		{"-9223372036854775807 - 2", "-9223372036854775809", true},
+		{"4294967296 * 4294967296", "18446744073709551616", true},
+		{"2 ** 63", "9223372036854775808", true},
+		{"2 ** 100", "1267650600228229401496703205376", true},
+		{"(-2) ** 63", "-9223372036854775808", false},
+		{"-(-9223372036854775807 - 1)", "9223372036854775808", true},
+		{"(-9223372036854775807 - 1) / -1", "9223372036854775808", true},
+		{"99999999999999999999", "99999999999999999999", true},
+		{"99999999999999999999 - 99999999999999999998", "1", false},
+		{"(2 ** 64 + 5) / 2 ** 62", "4", false},
+		{"-(2 ** 63) / 2 ** 63 - 1", "-2", false},
+		{"9223372036854775808 - 1", "9223372036854775807", false},
+		{"2 ** 64 > 2 ** 63", "true", false},
+		{"2 ** 64 < 1", "false", false},
+		{"2 ** 64 == 18446744073709551616", "true", false},
+		{"2 ** 64 != 2 ** 64 + 1", "true", false},
+		{"2 ** 64 * 0.5", "9.223372036854776e+18", false},
+		{"!(2 ** 64)", "false", false},
+		{"if (-(2 ** 64)) { 1 } else { 2 }", "2", false},
+		{"2 ** 64 / 0", "[error]: division by zero: 18446744073709551616 / 0", false},
+		{"2 ** (2 ** 64)", "[error]: exponent too large: 2 ** 18446744073709551616", false},
+		{"1 ** (2 ** 64)", "1", false},
+		{"2 ** 9223372036854775807", "[error]: exponent too large: 2 ** 9223372036854775807", false},
+		{"10 ** 100000000", "[error]: exponent too large: 10 ** 100000000", false},
+		{"math.pow(2, 9223372036854775807)", "[error]: exponent too large: 2 ** 9223372036854775807", false},
+		{"(-1) ** 9223372036854775807", "-1", false},
+		{"len(to_string(2 ** 100000))", "30103", false},
+		{"2 ** 64 + true", "[error]: type mismatch: BIG_INTEGER + BOOLEAN", false},
+		{`{2 ** 64: "a"}[18446744073709551616]`, "a", false},
+		{`{9223372036854775807: "a"}[2 ** 63 - 1]`, "a", false},
+		{"sort([2 ** 64, 1, 2 ** 63, 1.5])", "[1,1.5,9223372036854775808,18446744073709551616]", false},
+		{"sum([9223372036854775807, 9223372036854775807, -9223372036854775807])", "9223372036854775807", false},
+		{`parse_int("123456789012345678901234567890")`, "123456789012345678901234567890", true},
+		{`parse_int("-ffffffffffffffffff", 16)`, "-4722366482869645213695", true},
+		{"to_string(2 ** 64)", "18446744073709551616", false},
+		{"math.abs(-(2 ** 64))", "18446744073709551616", true},
+		{"math.abs(-9223372036854775807 - 1)", "9223372036854775808", true},
+		{"math.max(2 ** 63, 2 ** 63 + 1, 1.0)", "9223372036854775809", true},
+		{"math.pow(2, 64)", "18446744073709551616", true},
+		{"math.floor(2.0 ** 70)", "1180591620717411303424", true},
+		{"math.gcd(2 ** 64, 2 ** 32 * 3)", "4294967296", false},
+		{"math.gcd(-9223372036854775807 - 1, 0)", "9223372036854775808", true},
+		{"math.lcm(9223372036854775807, 2)", "18446744073709551614", true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.input, func(t *testing.T) {
+			result := testEval(tc.input)
+			require.Equal(t, tc.expected, result.Inspect())
+			_, isBig := result.(*object.BigInt)
+			require.Equal(t, tc.big, isBig)
+		})
+	}
+}
+
+func TestMathModule(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"math.pi", "3.141592653589793"},
+		{"math.e", "2.718281828459045"},
+		{"math.abs(-3)", "3"},
+		{"math.abs(-2.5)", "2.5"},
+		{"math.pow(2, 10)", "1024"},
+		{"math.pow(2, -1)", "0.5"},
+		{"math.pow(4, 0.5)", "2.0"},
+		{"math.sqrt(16)", "4.0"},
+		{"math.floor(2.7)", "2"},
+		{"math.floor(-2.5)", "-3"},
+		{"math.ceil(2.1)", "3"},
+		{"math.ceil(5)", "5"},
+		{"math.min(3, 1.5, 2)", "1.5"},
+		{"math.max(3, 1.5, 2)", "3"},
+		{"math.clamp(15, 0, 10)", "10"},
+		{"math.clamp(-1.5, 0, 10)", "0"},
+		{"math.clamp(5, 0, 10)", "5"},
+		{"math.gcd(12, -18)", "6"},
+		{"math.gcd(0, 0)", "0"},
+		{"math.lcm(4, 6)", "12"},
+		{"math.lcm(-4, 6)", "12"},
+		{"math.sin(0)", "0.0"},
+		{"math.cos(math.pi)", "-1.0"},
+		{"math.atan(1, -1)", "2.356194490192345"},
+		{"math.log(math.e)", "1.0"},
+		{"math.log(8, 2)", "3.0"},
+		{"math.log(1000, 10)", "3.0"},
+		{"math.exp(0)", "1.0"},
+		{"let m = math; m.log(1024, 2)", "10.0"},
+		{`math["sqrt"](4)`, "2.0"},
+		{"let math = 1; math", "1"},
+		{"math.nope", "null"},
+		{`delete_in_place(math, "sqrt")`, "[error]: `delete_in_place` cannot modify a read-only hash"},
+		{`delete(math, "sqrt"); math.sqrt(4)`, "2.0"},
+		{"[1].len", "[error]: ARRAY has no field len"},
+		{"math.sqrt(-1)", "[error]: argument to `math.sqrt` out of domain: -1"},
+		{"math.asin(2)", "[error]: argument to `math.asin` out of domain: 2"},
+		{"math.log(8, 1)", "[error]: `math.log` base must be positive and not 1, got 1"},
+		{"math.floor(10.0 ** 400)", "[error]: `math.floor` of +Inf is not an integer"},
+		{`math.abs("a")`, "[error]: argument to `math.abs` must be INTEGER or FLOAT, got STRING"},
+		{`math.pow(2, "a")`, "[error]: argument 2 to `math.pow` must be INTEGER or FLOAT, got STRING"},
+		{"math.gcd(1.5, 2)", "[error]: first argument to `math.gcd` must be INTEGER, got FLOAT"},
+		{"math.clamp(1, 2, 0)", "[error]: `math.clamp` lower bound 2 is greater than upper bound 0"},
+		{"math.min()", "[error]: wrong number of arguments. got=0, want at least 1"},
+		{"math.sqrt(1, 2)", "[error]: wrong number of arguments. got=2, want=1"},
+		{"sum([1, 2.5])", "3.5"},
+		{"sort([2, 1.5, 1])", "[1,1.5,2]"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.input, func(t *testing.T) {
+			require.Equal(t, tc.expected, testEval(tc.input).Inspect())
+		})
+	}
+}
+
 func TestEvalBooleanExpression(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -111,6 +274,10 @@ func TestBangOperator(t *testing.T) {
 		{"!!true", true},
 		{"!!false", false},
 		{"!!5", true},
+		{"!0", true},
+		{"!0.0", true},
+		{"!-1.5", true},
+		{"!0.5", false},
 	}
 
 	for _, tc := range tests {
@@ -130,6 +297,10 @@ func TestIfExpressions(t *testing.T) {
 		{"if (1 > 2) { 10 }", nil},
 		{"if (1 > 2) { 10 } else { 20 }", 20},
 		{"if (1 < 2) { 10 } else { 20 }", 10},
+		{"if (0) { 10 } else { 20 }", 20},
+		{"if (0.0) { 10 } else { 20 }", 20},
+		{"if (-1.5) { 10 } else { 20 }", 20},
+		{"if (0.5) { 10 } else { 20 }", 10},
 	}
 
 	for _, tc := range tests {
@@ -241,6 +412,10 @@ if (10 > 1) {
 			`{"name": "Monkey"}[fn(x){x}];`,
 			"unusable as hash key: FUNCTION",
 		},
+		{
+			"let i = 0; while (i < 3) { let i = i + true; }",
+			"type mismatch: INTEGER + BOOLEAN",
+		},
 	}
 
 	for _, tc := range tests {
@@ -312,6 +487,23 @@ func TestClosures(t *testing.T) {
 	testIntegerObject(t, testEval(input), 4)
 }
 
+func TestWhileExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let i = 0; while (i < 3) { let i = i + 1; }; i", "3"},
+		{"let f = fn() { let i = 0; while (true) { let i = i + 1; if (i > 4) { return i } } }; f()", "5"},
+		{`let i = 0; while (i < 3) { let i = i + 1; if (i == 2) { i + "a" } }; i`, "[error]: type mismatch: INTEGER + STRING"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.input, func(t *testing.T) {
+			require.Equal(t, tc.expected, testEval(tc.input).Inspect())
+		})
+	}
+}
+
 func TestStringLiteral(t *testing.T) {
 	t.Run("simple", func(t *testing.T) {
 		input := `"Hello World!`
@@ -374,6 +566,7 @@ func TestBuiltinFunctions(t *testing.T) {
 		{`len(1)`, "argument to `len` not supported, got INTEGER"},
 		{`len("one", "two")`, "wrong number of arguments. got=2, want=1"},
 		{`len("four")`, 4},
+		{`len("héllo")`, 5},
 		{`len("hello world")`, 11},
 		{`len(1)`, "argument to `len` not supported, got INTEGER"},
 		{`len("one", "two")`, "wrong number of arguments. got=2, want=1"},
@@ -391,6 +584,9 @@ func TestBuiltinFunctions(t *testing.T) {
 		{`push(1, 1)`, "argument to `push` must be ARRAY, got INTEGER"},
 		{`push([], 1)`, []int{1}},
 		{`push([], 1,2,3,4,5)`, []int{1, 2, 3, 4, 5}},
+		{`push([1, 2], 3)`, []int{1, 2, 3}},
+		{`let a = [1]; push(a, 2); a`, []int{1}},
+		{`push()`, "wrong number of arguments. got=0, want at least 1"},
 	}
 
 	for _, tc := range tests {
@@ -415,6 +611,356 @@ func TestBuiltinFunctions(t *testing.T) {
 	}
 }
 
+func TestHashBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`len({"a": 1, "b": 2})`, "2"},
+		{`keys({"b": 1, "a": 2, 3: 3})`, "[b,a,3]"},
+		{`values({"b": 1, "a": 2})`, "[1,2]"},
+		{`entries({"b": 1, true: if (false) { 1 }})`, "[[b,1],[true,null]]"},
+		{`keys({})`, "[]"},
+		{`has({"a": if (false) { 1 }}, "a")`, "true"},
+		{`has({"a": if (false) { 1 }}, "b")`, "false"},
+		{`let h = {"a": 1, "b": 2}; let d = delete(h, "a"); [keys(h), keys(d)]`, "[[a,b],[b]]"},
+		{`delete({"a": 1}, "b")`, "{\n\"a\": \"1\"\n}"},
+		{`let h = {"a": 1, "b": 2}; [delete_in_place(h, "a"), delete_in_place(h, "a"), keys(h)]`, "[true,false,[b]]"},
+		{`merge({"a": 1, "b": 2}, {"b": 3, "c": 4}, {"a": 5})`, "{\n\"a\": \"5\",\n  \"b\": \"3\",\n  \"c\": \"4\"\n}"},
+		{`let h = {"a": 1}; merge(h, {"a": 2}); h["a"]`, "1"},
+		{`merge({})`, "{\n\n}"},
+		{`keys([1])`, "[error]: argument to `keys` must be HASH, got ARRAY"},
+		{`values(1)`, "[error]: argument to `values` must be HASH, got INTEGER"},
+		{`entries("a")`, "[error]: argument to `entries` must be HASH, got STRING"},
+		{`keys({}, {})`, "[error]: wrong number of arguments. got=2, want=1"},
+		{`has([], 1)`, "[error]: first argument to `has` must be HASH, got ARRAY"},
+		{`has({}, [])`, "[error]: unusable as hash key: ARRAY"},
+		{`delete({})`, "[error]: wrong number of arguments. got=1, want=2"},
+		{`delete_in_place({}, fn() {})`, "[error]: unusable as hash key: FUNCTION"},
+		{`merge({}, 1)`, "[error]: argument 2 to `merge` must be HASH, got INTEGER"},
+		{`merge()`, "[error]: wrong number of arguments. got=0, want at least 1"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.input, func(t *testing.T) {
+			require.Equal(t, tc.expected, testEval(tc.input).Inspect())
+		})
+	}
+}
+
+func TestCollectionBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`map([1, 2, 3], fn(x) { x * 2 })`, "[2,4,6]"},
+		{`map(["a", "bc"], len)`, "[1,2]"},
+		{`map([], fn(x) { x })`, "[]"},
+		{`map([1], fn(x) {})`, "[null]"},
+		{`filter([1, 2, 3, 4], fn(x) { x > 2 })`, "[3,4]"},
+		{`reduce([1, 2, 3], fn(acc, x) { acc + x })`, "6"},
+		{`reduce([1, 2, 3], fn(acc, x) { push(acc, x * x) }, [])`, "[1,4,9]"},
+		{`reduce([], fn(acc, x) { acc + x }, 0)`, "0"},
+		{`each([1, 2], fn(x) { x })`, "null"},
+		{`find([1, 2, 3], fn(x) { x > 1 })`, "2"},
+		{`find([1, 2, 3], fn(x) { x > 5 })`, "null"},
+		{`any([1, 2], fn(x) { x == 2 })`, "true"},
+		{`any([], fn(x) { true })`, "false"},
+		{`all([1, 2], fn(x) { x > 0 })`, "true"},
+		{`all([1, 2], fn(x) { x > 1 })`, "false"},
+		{`sort([3, 1, 2])`, "[1,2,3]"},
+		{`sort(["b", "c", "a"])`, "[a,b,c]"},
+		{`let xs = [3, 1]; sort(xs); xs`, "[3,1]"},
+		{`sort([3, 1, 2], fn(a, b) { a > b })`, "[3,2,1]"},
+		{`map(sort([[2, "a"], [1, "b"], [2, "c"], [1, "d"]], fn(a, b) { a[0] < b[0] }), last)`, "[b,d,a,c]"},
+		{`reverse([1, 2, 3])`, "[3,2,1]"},
+		{`zip([1, 2, 3], ["a", "b"])`, "[[1,a],[2,b]]"},
+		{`zip([1, 2])`, "[[1],[2]]"},
+		{`flatten([1, [2, [3]], []])`, "[1,2,[3]]"},
+		{`range(3)`, "[0,1,2]"},
+		{`range(2, 5)`, "[2,3,4]"},
+		{`range(0, 10, 3)`, "[0,3,6,9]"},
+		{`range(3, 0, -1)`, "[3,2,1]"},
+		{`range(3, 0)`, "[]"},
+		{`range(9223372036854775800, 9223372036854775807, 10)`, "[9223372036854775800]"},
+		{`range(9223372036854775807, -9223372036854775807 - 1, -9223372036854775807 - 1)`, "[9223372036854775807,-1]"},
+		{`range(-9223372036854775807 - 1, 9223372036854775807, 9223372036854775807)`, "[-9223372036854775808,-1,9223372036854775806]"},
+		{`sum([1, 2, 3])`, "6"},
+		{`sum([])`, "0"},
+		{`min([3, 1, 2])`, "1"},
+		{`max(["a", "c", "b"])`, "c"},
+		{`min([])`, "null"},
+		{`uniq([1, 2, 1, "a", "a", [1], [1], true])`, "[1,2,a,[1],true]"},
+		{`group_by([1, 2, 3, 4, 5], fn(x) { x / 2 })`, "{\n\"0\": \"[1]\",\n  \"1\": \"[2,3]\",\n  \"2\": \"[4,5]\"\n}"},
+		{`map(1, len)`, "[error]: first argument to `map` must be ARRAY, got INTEGER"},
+		{`filter([], 1)`, "[error]: second argument to `filter` must be FUNCTION, got INTEGER"},
+		{`map([1, "a"], fn(x) { x + 1 })`, "[error]: type mismatch: STRING + INTEGER"},
+		{`each([1, 2], fn(x) { exit(x) })`, "exit(1)"},
+		{`reduce([], fn(acc, x) { acc })`, "[error]: `reduce` of empty array with no initial value"},
+		{`reduce([1])`, "[error]: wrong number of arguments. got=1, want=2 or 3"},
+		{`sort([1, "a"])`, "[error]: `sort` cannot compare STRING and INTEGER"},
+		{`sort([true, false])`, "[error]: `sort` cannot compare BOOLEAN values"},
+		{`sort([2, 1], fn(a, b) { a + "x" })`, "[error]: type mismatch: INTEGER + STRING"},
+		{`zip([1], 2)`, "[error]: argument 2 to `zip` must be ARRAY, got INTEGER"},
+		{`range(0, 1, 0)`, "[error]: `range` step must not be 0"},
+		{`range(0, 9223372036854775807)`, "[error]: `range` result longer than 16777216 elements"},
+		{`range("a")`, "[error]: argument 1 to `range` must be INTEGER, got STRING"},
+		{`sum([1, "a"])`, "[error]: `sum` expects INTEGER or FLOAT elements, got STRING"},
+		{`max([1, "a"])`, "[error]: `max` cannot compare STRING and INTEGER"},
+		{`group_by([1], fn(x) { [x] })`, "[error]: unusable as hash key: ARRAY"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.input, func(t *testing.T) {
+			require.Equal(t, tc.expected, testEval(tc.input).Inspect())
+		})
+	}
+}
+
+func TestCollectionBuiltinsDeepInput(t *testing.T) {
+	// native loops don't grow the stack with the input size
+	evaluated := testEval(`sum(map(range(100000), fn(x) { x * 2 }))`)
+
+	testIntegerObject(t, evaluated, 9999900000)
+}
+
+// TestStringBuiltins doubles as the reference of the string builtins, each case
+// is an example of their use.
+func TestStringBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`split("a,b,,c", ",")`, "[a,b,,c]"},
+		{`split("héllo", "")`, "[h,é,l,l,o]"},
+		{`join(["a", "b", "c"], ", ")`, "a, b, c"},
+		{`join(["a", "b"])`, "ab"},
+		{`join([], "-")`, ""},
+		{"trim(\"  \t hi \n\")", "hi"},
+		{`trim(" hi　")`, "hi"},
+		{`trim("xxhixx", "x")`, "hi"},
+		{`trim_left("  hi  ")`, "hi  "},
+		{`trim_right("  hi  ")`, "  hi"},
+		{`trim_right("hi!?!", "!?")`, "hi"},
+		{`replace("a-b-c", "-", "+")`, "a+b+c"},
+		{`replace("a-b-c", "-", "+", 1)`, "a+b-c"},
+		{`contains("monkey", "key")`, "true"},
+		{`contains("monkey", "ape")`, "false"},
+		{`starts_with("monkey", "mon")`, "true"},
+		{`ends_with("monkey", "mon")`, "false"},
+		{`index_of("monkey", "key")`, "3"},
+		{`index_of("ñandú", "dú")`, "3"},
+		{`index_of("monkey", "ape")`, "-1"},
+		{`upper("héllo")`, "HÉLLO"},
+		{`lower("ÀÉÎ")`, "àéî"},
+		{`repeat("ab", 3)`, "ababab"},
+		{`repeat("ab", 0)`, ""},
+		{`pad_left("7", 3, "0")`, "007"},
+		{`pad_left("ñ", 3)`, "  ñ"},
+		{`pad_right("ab", 7, "-=")`, "ab-=-=-"},
+		{`pad_right("long", 2)`, "long"},
+		{`chars("añb")`, "[a,ñ,b]"},
+		{`chars("")`, "[]"},
+		{"lines(\"a\r\nb\n\nc\n\")", "[a,b,,c]"},
+		{`lines("")`, "[]"},
+		{`to_string(42)`, "42"},
+		{`to_string([1, "a"])`, "[1,a]"},
+		{`to_string("s") + to_string(true)`, "strue"},
+		{`parse_int("42") + 1`, "43"},
+		{`parse_int(" -7 ")`, "-7"},
+		{`parse_int("ff", 16)`, "255"},
+		{`parse_int("4x")`, "null"},
+		{`upper(1)`, "[error]: argument to `upper` must be STRING, got INTEGER"},
+		{`split("a", 1)`, "[error]: second argument to `split` must be STRING, got INTEGER"},
+		{`replace("a", "b", "c", "d")`, "[error]: fourth argument to `replace` must be INTEGER, got STRING"},
+		{`split("a")`, "[error]: wrong number of arguments. got=1, want=2"},
+		{`trim()`, "[error]: wrong number of arguments. got=0, want=1 or 2"},
+		{`replace("a")`, "[error]: wrong number of arguments. got=1, want=3 or 4"},
+		{`join([1], "")`, "[error]: `join` expects STRING elements, got INTEGER"},
+		{`repeat("a", -1)`, "[error]: `repeat` count must not be negative, got -1"},
+		{`pad_left("a", 3, "")`, "[error]: `pad_left` padding must not be empty"},
+		{`repeat("ab", 4611686018427387904)`, "[error]: `repeat` result longer than 1073741824 bytes"},
+		{`pad_left("x", 4611686018427387904)`, "[error]: `pad_left` result longer than 1073741824 bytes"},
+		{`parse_int("1", 1)`, "[error]: `parse_int` base must be between 2 and 36, got 1"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.input, func(t *testing.T) {
+			require.Equal(t, tc.expected, testEval(tc.input).Inspect())
+		})
+	}
+}
+
+func TestJSONBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`json_encode({"b": [1, 2.5, "x"], "a": true, "n": if (false) { 1 }})`, `{"b":[1,2.5,"x"],"a":true,"n":null}`},
+		{`json_encode(1.0)`, `1.0`},
+		{`json_encode(2 ** 70)`, `1180591620717411303424`},
+		{"json_encode(\"<tab>\t\\é\")", `"<tab>\t\\é"`},
+		{`json_encode([])`, `[]`},
+		{`json_encode({})`, `{}`},
+		{`json_encode({"a": [1, {"b": 2}]}, {"indent": 2})`, "{\n  \"a\": [\n    1,\n    {\n      \"b\": 2\n    }\n  ]\n}"},
+		{`json_encode([1], {"indent": 0})`, `[1]`},
+		{`json_decode(" [true, false, null, 1, -0, 2.5, 1e2] ")`, "[true,false,null,1,0,2.5,100.0]"},
+		{`json_decode("123456789012345678901234567890") + 1`, "123456789012345678901234567891"},
+		{`let h = {"a": [1, "b", {"c": 1.5}]}; to_string(json_decode(json_encode(h))) == to_string(h)`, "true"},
+		{`json_decode(json_encode({"b": 1, "a": 2}))`, "{\n\"b\": \"1\",\n  \"a\": \"2\"\n}"},
+		{`json_decode(json_encode(1.0)) == 1.0`, "true"},
+		{`json_encode(fn(x) { x })`, "[error]: `json_encode` cannot encode FUNCTION"},
+		{`json_encode([len])`, "[error]: `json_encode` cannot encode BUILTIN"},
+		{`json_encode({1: "a"})`, "[error]: `json_encode` hash keys must be STRING, got INTEGER"},
+		{`json_encode({"a": {true: 1}})`, "[error]: `json_encode` hash keys must be STRING, got BOOLEAN"},
+		{`json_encode(1.0 / 0)`, "[error]: `json_encode` cannot encode +Inf"},
+		{`json_encode(1, {"indent": -1})`, "[error]: `json_encode` indent must be a non-negative INTEGER, got -1"},
+		{`json_encode(1, {"pretty": true})`, "[error]: `json_encode` unknown option: pretty"},
+		{`json_encode(1, 2)`, "[error]: second argument to `json_encode` must be HASH, got INTEGER"},
+		{`json_encode()`, "[error]: wrong number of arguments. got=0, want=1 or 2"},
+		{`json_decode("")`, "[error]: `json_decode` invalid JSON: unexpected EOF"},
+		{`json_decode("[1,")`, "[error]: `json_decode` invalid JSON: unexpected end of JSON input"},
+		{`json_decode("{1: 2}")`, "[error]: `json_decode` invalid JSON: object member name must be a string"},
+		{`json_decode("1 2")`, "[error]: `json_decode` invalid JSON: unexpected data after the value"},
+		{`json_decode("[1]]")`, "[error]: `json_decode` invalid JSON: invalid character ']' looking for beginning of value"},
+		{`json_decode(1)`, "[error]: argument to `json_decode` must be STRING, got INTEGER"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.input, func(t *testing.T) {
+			require.Equal(t, tc.expected, testEval(tc.input).Inspect())
+		})
+	}
+}
+
+// monkey strings can't contain quotes, so documents with strings are decoded by
+// calling the builtin directly.
+func TestJSONDecodeStrings(t *testing.T) {
+	decode, ok := evaluator.LookupBuiltin("json_decode")
+	require.True(t, ok)
+
+	tests := []struct {
+		input    string
+		expected object.Object
+	}{
+		{`"\u00e9\n\"x\""`, &object.String{Value: "é\n\"x\""}},
+		{`{"b": {"c": []}, "a": "x", "b": 1}`, testEval(`{"b": 1, "a": "x"}`)},
+		{`[{}, {"k": null}]`, testEval(`[{}, {"k": if (false) { 1 }}]`)},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.input, func(t *testing.T) {
+			require.Equal(t, tc.expected.Inspect(), decode.Fn(&object.String{Value: tc.input}).Inspect())
+		})
+	}
+
+	err := decode.Fn(&object.String{Value: `{"a": 1e999}`})
+	require.Equal(t, "[error]: `json_decode` invalid JSON: strconv.ParseFloat: parsing \"1e999\": value out of range", err.Inspect())
+}
+
+func TestFileSystemBuiltins(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("secret"), 0o644))
+	require.NoError(t, os.Symlink(outside, filepath.Join(root, "link")))
+
+	access, err := evaluator.NewFileAccess(root)
+	require.NoError(t, err)
+	evaluator.SetFileAccess(access)
+	defer evaluator.SetFileAccess(nil)
+
+	evalIn := func(input string) object.Object {
+		env := object.NewEnvironment()
+		env.Set("root", &object.String{Value: root})
+		env.Set("outside", &object.String{Value: outside})
+		return evaluator.Eval(parser.New(lexer.New([]byte(input))).ParseProgram(), env)
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let f = path.join(root, "a.txt"); write_file(f, "a"); append_file(f, "b"); read_file(f)`, `ab`},
+		{`let d = path.join(root, "x", "y"); mkdir(d); write_file(path.join(d, "b.txt"), ""); list_dir(path.join(root, "x"))`, `[y]`},
+		{`[exists(path.join(root, "x", "y")), exists(path.join(root, "missing"))]`, `[true,false]`},
+		{`let f = path.join(root, "c.txt"); write_file(f, ""); remove(f); exists(f)`, `false`},
+		{`len(glob(path.join(root, "*.txt")))`, `1`},
+		{`glob(path.join(root, "link", "*"))`, `[]`},
+		{`[path.basename("a/b.txt"), path.dirname("a/b.txt"), path.ext("a/b.txt")]`, `[b.txt,a,.txt]`},
+		{`read_file(path.join(root, "missing"))`, "[error]: `read_file` failed: open " + filepath.Join(root, "missing") + ": no such file or directory"},
+		{`remove(path.join(root, "x"))`, "[error]: `remove` failed: remove " + filepath.Join(root, "x") + ": directory not empty"},
+		{`read_file(path.join(outside, "secret.txt"))`, "[error]: `read_file` failed: " + filepath.Join(outside, "secret.txt") + " is outside of " + root},
+		{`read_file(path.join(root, "link", "secret.txt"))`, "[error]: `read_file` failed: " + filepath.Join(root, "link", "secret.txt") + " is outside of " + root},
+		{`write_file(path.join(root, "..", "escape.txt"), "")`, "[error]: `write_file` failed: " + filepath.Join(root, "..", "escape.txt") + " is outside of " + root},
+		{`read_file(1)`, "[error]: argument to `read_file` must be STRING, got INTEGER"},
+		{`path.join("a", 1)`, "[error]: argument 2 to `path.join` must be STRING, got INTEGER"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.input, func(t *testing.T) {
+			result := evalIn(tc.input)
+			if s, ok := result.(*object.String); ok {
+				require.Equal(t, tc.expected, s.Value)
+				return
+			}
+			require.Equal(t, tc.expected, result.Inspect())
+		})
+	}
+
+	evaluator.SetFileAccess(nil)
+	require.Equal(t, "[error]: `exists` needs file system access, run with --allow-fs", testEval(`exists("a")`).Inspect())
+}
+
+// fakeClock is a clock whose time only advances when sleeping.
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time        { return c.now }
+func (c *fakeClock) Sleep(d time.Duration) { c.now = c.now.Add(d) }
+
+func TestTimeBuiltins(t *testing.T) {
+	defer evaluator.SetClock(nil)
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`now()`, "2024-02-29T13:04:05Z"},
+		{`unix()`, "1709211845"},
+		{`let start = clock(); sleep(1500); clock() - start`, "1.5s"},
+		{`let t = now(); sleep(duration(10)); now() - t`, "10ms"},
+		{`let t = now(); [t.year, t.month, t.day, t.hour, t.minute, t.second, t.weekday, t.yearday, t["unix"], t.zone]`, "[2024,2,29,13,4,5,4,60,1709211845,UTC]"},
+		{`let d = duration(90000); [d.hours, d.minutes, d.seconds, d.milliseconds, d.nanoseconds]`, "[0.025,1.5,90.0,90000,90000000000]"},
+		{`format_time(now() + duration(1000 * 60 * 60 * 24), "Jan 2, 2006")`, "Mar 1, 2024"},
+		{`format_time(parse_time("2024-01-01 08:00", "2006-01-02 15:04"))`, "2024-01-01T08:00:00Z"},
+		{`now() - parse_time("2024-02-29T12:00:00Z")`, "1h4m5s"},
+		{`now() - duration(5000) < now()`, "true"},
+		{`[duration(1) + duration(2), duration(3) - duration(1), 2 * duration(1.5), duration(3) / 2, -duration(1)]`, "[3ms,2ms,3ms,1.5ms,-1ms]"},
+		{`duration(3) / duration(2)`, "1.5"},
+		{`[now() == parse_time("2024-02-29T13:04:05Z"), duration(1) != duration(1), now() == duration(1)]`, "[true,false,false]"},
+		{`sort([duration(2), duration(1)])`, "[1ms,2ms]"},
+		{`now() + now()`, "[error]: unknown operator: TIME + TIME"},
+		{`duration(1) / 0`, "[error]: division by zero: 1ms / 0"},
+		{`duration(1) * 2 ** 62`, "[error]: duration out of range: 1ms * 4611686018427387904"},
+		{`duration(5000000000000) + duration(5000000000000)`, "[error]: duration out of range: 1388888h53m20s + 1388888h53m20s"},
+		{`-duration(5000000000000) - duration(5000000000000)`, "[error]: duration out of range: -1388888h53m20s - 1388888h53m20s"},
+		{`now().month_name`, "[error]: unknown TIME field: month_name"},
+		{`duration(1)[0]`, "[error]: DURATION field must be STRING, got INTEGER"},
+		{`parse_time("yesterday")`, `[error]: ` + "`parse_time`" + ` failed: parsing time "yesterday" as "2006-01-02T15:04:05Z07:00": cannot parse "yesterday" as "2006"`},
+		{`format_time(1)`, "[error]: first argument to `format_time` must be TIME, got INTEGER"},
+		{`sleep("1s")`, "[error]: argument to `sleep` must be DURATION, INTEGER or FLOAT, got STRING"},
+		{`duration(duration(1))`, "[error]: argument to `duration` must be INTEGER or FLOAT, got DURATION"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.input, func(t *testing.T) {
+			evaluator.SetClock(&fakeClock{now: time.Date(2024, time.February, 29, 13, 4, 5, 0, time.UTC)})
+			require.Equal(t, tc.expected, testEval(tc.input).Inspect())
+		})
+	}
+}
+
 func TestArrayLiterals(t *testing.T) {
 	input := `[1, 2*2, 3+3]`
 	evaluate := testEval(input)
@@ -471,6 +1017,14 @@ func TestArrayIndexExpressions(t *testing.T) {
 		},
 		{
 			"[1, 2, 3][-1]",
+			3,
+		},
+		{
+			"[1, 2, 3][-3]",
+			1,
+		},
+		{
+			"[1, 2, 3][-4]",
 			nil,
 		},
 	}
@@ -486,6 +1040,43 @@ func TestArrayIndexExpressions(t *testing.T) {
 	}
 }
 
+func TestSliceExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"[1, 2, 3, 4, 5][1:3]", "[2,3]"},
+		{"[1, 2, 3, 4, 5][:2]", "[1,2]"},
+		{"[1, 2, 3, 4, 5][-2:]", "[4,5]"},
+		{"[1, 2, 3, 4, 5][:]", "[1,2,3,4,5]"},
+		{"[1, 2, 3, 4, 5][::2]", "[1,3,5]"},
+		{"[1, 2, 3, 4, 5][1::2]", "[2,4]"},
+		{"[1, 2, 3, 4, 5][::-1]", "[5,4,3,2,1]"},
+		{"[1, 2, 3, 4, 5][3:0:-1]", "[4,3,2]"},
+		{"[1, 2, 3, 4, 5][-100:100]", "[1,2,3,4,5]"},
+		{"[1, 2, 3, 4, 5][3:1]", "[]"},
+		{"let xs = [1, 2, 3]; let i = 1; xs[i:i + 1]", "[2]"},
+		{`"hello world"[2:5]`, "llo"},
+		{`"hello"[-3:]`, "llo"},
+		{`"hello"[::-1]`, "olleh"},
+		{`"hello"[::2]`, "hlo"},
+		{`"hello"[10:]`, ""},
+		{`let s = "héllo"; s[index_of(s, "l"):]`, "llo"},
+		{`"héllo"[:2]`, "hé"},
+		{`"añb"[::-1]`, "bña"},
+		{"[1, 2][::0]", "[error]: slice step cannot be zero"},
+		{`[1, 2]["a":]`, "[error]: slice index must be INTEGER, got STRING"},
+		{"{}[1:2]", "[error]: slice operator not supported: HASH"},
+		{"[1, 2][x:]", "[error]: identifier not found: x"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.input, func(t *testing.T) {
+			require.Equal(t, tc.expected, testEval(tc.input).Inspect())
+		})
+	}
+}
+
 func TestHashLiterals(t *testing.T) {
 	input := `let two = "two";
 	{
@@ -500,27 +1091,42 @@ func TestHashLiterals(t *testing.T) {
 	evaluated := testEval(input)
 	result := evaluated.(*object.Hash)
 
-	expected := map[object.HashKey]int{
-		(&object.String{Value: "one"}).HashKey():   1,
-		(&object.String{Value: "two"}).HashKey():   2,
-		(&object.String{Value: "three"}).HashKey(): 3,
-		(&object.Integer{Value: 4}).HashKey():      4,
-		evaluator.TRUE.HashKey():                   5,
-		evaluator.FALSE.HashKey():                  6,
+	expected := []struct {
+		key   object.Hashable
+		value int
+	}{
+		{&object.String{Value: "one"}, 1},
+		{&object.String{Value: "two"}, 2},
+		{&object.String{Value: "three"}, 3},
+		{&object.Integer{Value: 4}, 4},
+		{evaluator.TRUE, 5},
+		{evaluator.FALSE, 6},
 	}
 
-	require.Len(t, result.Pairs, len(expected))
+	require.Equal(t, len(expected), result.Len())
 
-	for expectedKey, expectedValue := range expected {
-		pair, ok := result.Pairs[expectedKey]
-		if !ok {
-			t.Errorf("no pair for given key in Pairs")
-		}
+	for i, e := range expected {
+		value, ok := result.Get(e.key)
+		require.True(t, ok, "no pair for key %s", e.key.Inspect())
+		testIntegerObject(t, value, e.value)
 
-		testIntegerObject(t, pair.Value, expectedValue)
+		// pairs keep the source order
+		require.Equal(t, e.key.Inspect(), result.Pairs()[i].Key.Inspect())
 	}
 }
 
+func TestHashKeyCollisions(t *testing.T) {
+	hashString := object.HashString
+	object.HashString = func(string) int { return 0 }
+	t.Cleanup(func() { object.HashString = hashString })
+
+	evaluated := testEval(`let h = {"a": 1, "b": 2, "c": 3}; h["a"] + h["b"] * h["c"]`)
+	testIntegerObject(t, evaluated, 7)
+
+	evaluated = testEval(`{"b": 1, "a": 2, "b": 3}`)
+	require.Equal(t, "{\n\"b\": \"3\",\n  \"a\": \"2\"\n}", evaluated.Inspect())
+}
+
 func TestHashIndexExpressions(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -566,3 +1172,64 @@ func TestHashIndexExpressions(t *testing.T) {
 		testNullObject(t, evaluated)
 	}
 }
+
+func TestExit(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int
+	}{
+		{"exit(); 5", 0},
+		{"exit(3); 5", 3},
+		{"let f = fn() { exit(4); 1 }; f() + 1; 5", 4},
+		{"let i = 0; while (true) { let i = i + 1; if (i > 2) { exit(i) } }", 3},
+		{"[1, exit(2), 3]", 2},
+	}
+
+	for _, tc := range tests {
+		evaluated := testEval(tc.input)
+
+		require.IsType(t, &object.Exit{}, evaluated, tc.input)
+		require.Equal(t, tc.expected, evaluated.(*object.Exit).Code)
+	}
+
+	evaluated := testEval(`exit("1")`)
+	require.Equal(t, "argument to `exit` must be INTEGER, got STRING", evaluated.(*object.ErrorValue).Message)
+}
+
+func TestAssertions(t *testing.T) {
+	tests := []struct {
+		input       string
+		expectedMsg string
+	}{
+		{`assert(1 < 2)`, ""},
+		{`assert(1 > 2)`, "assertion failed"},
+		{`assert(false, "one is not greater")`, "assertion failed: one is not greater"},
+		{`assert_eq([1, {"a": "b"}], [1, {"a": "b"}])`, ""},
+		{`assert_eq("1", 1)`, "assertion failed: expected 1, got \"1\""},
+		{`assert_eq([1, 2], [1, 3])`, "assertion failed: expected [1,3], got [1,2]"},
+		{`assert_error(fn() { 1 + true })`, ""},
+		{`assert_error(fn() { 1 + true }, "type mismatch")`, ""},
+		{`assert_error(fn() { 1 })`, "assertion failed: expected an error, got 1"},
+		{
+			`assert_error(fn() { missing }, "type mismatch")`,
+			"assertion failed: expected error containing \"type mismatch\", got \"identifier not found: missing\"",
+		},
+	}
+
+	for _, tc := range tests {
+		evaluated := testEval(tc.input)
+		if tc.expectedMsg == "" {
+			testNullObject(t, evaluated)
+			continue
+		}
+
+		require.IsType(t, &object.ErrorValue{}, evaluated, tc.input)
+		require.Equal(t, tc.expectedMsg, evaluated.(*object.ErrorValue).Message)
+	}
+}
+
+func TestErrorPosition(t *testing.T) {
+	evaluated := testEval("let a = 1;\nlet b = fn() {\n  first(a)\n};\nb()")
+
+	require.Equal(t, token.Position{Line: 3, Column: 3}, evaluated.(*object.ErrorValue).Pos)
+}
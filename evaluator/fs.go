@@ -0,0 +1,239 @@
+package evaluator
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gkampitakis/monkey/object"
+)
+
+// FileAccess grants the file system builtins access to the files under a root
+// directory, or to every file.
+type FileAccess struct {
+	// root is absolute with its symlinks resolved, empty when every file is
+	// accessible
+	root string
+}
+
+// NewFileAccess returns the access to the files under root, every file when
+// root is empty.
+func NewFileAccess(root string) (*FileAccess, error) {
+	if root == "" {
+		return &FileAccess{}, nil
+	}
+
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	if abs, err = filepath.EvalSymlinks(abs); err != nil {
+		return nil, err
+	}
+	if info, err := os.Stat(abs); err != nil {
+		return nil, err
+	} else if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", root)
+	}
+
+	return &FileAccess{root: abs}, nil
+}
+
+var fileAccess *FileAccess
+
+// SetFileAccess grants the file system builtins access a, nil revokes it. The
+// builtins fail until access is granted.
+func SetFileAccess(a *FileAccess) {
+	fileAccess = a
+}
+
+// resolve returns the absolute path of name, failing when it's outside of the
+// root. Symlinks are resolved so they can't point out of the root.
+func (a *FileAccess) resolve(name string) (string, error) {
+	abs, err := filepath.Abs(name)
+	if err != nil || a.root == "" {
+		return abs, err
+	}
+
+	path, err := evalSymlinks(abs)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(a.root, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s is outside of %s", name, a.root)
+	}
+
+	return path, nil
+}
+
+// evalSymlinks resolves the symlinks of the longest existing prefix of path, so
+// files about to be created can be checked too.
+func evalSymlinks(path string) (string, error) {
+	resolved, err := filepath.EvalSymlinks(path)
+	if !errors.Is(err, fs.ErrNotExist) {
+		return resolved, err
+	}
+	// a dangling symlink could be followed out of the root when writing
+	if _, lerr := os.Lstat(path); lerr == nil {
+		return "", err
+	}
+
+	parent := filepath.Dir(path)
+	if parent == path {
+		return path, nil
+	}
+	if parent, err = evalSymlinks(parent); err != nil {
+		return "", err
+	}
+
+	return filepath.Join(parent, filepath.Base(path)), nil
+}
+
+func init() {
+	builtins["read_file"] = fsBuiltin(
+		"read_file", "string -> string", []object.ObjectType{object.STRING},
+		func(path string, _ []object.Object) (object.Object, error) {
+			content, err := os.ReadFile(path)
+			return &object.String{Value: string(content)}, err
+		},
+	)
+	builtins["write_file"] = fsBuiltin(
+		"write_file", "(string, string) -> null", []object.ObjectType{object.STRING, object.STRING},
+		func(path string, args []object.Object) (object.Object, error) {
+			return NULL, os.WriteFile(path, []byte(stringValue(args[1])), 0o644)
+		},
+	)
+	builtins["append_file"] = fsBuiltin(
+		"append_file", "(string, string) -> null", []object.ObjectType{object.STRING, object.STRING},
+		func(path string, args []object.Object) (object.Object, error) {
+			f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+			if err != nil {
+				return nil, err
+			}
+			if _, err := f.WriteString(stringValue(args[1])); err != nil {
+				f.Close()
+				return nil, err
+			}
+			return NULL, f.Close()
+		},
+	)
+	builtins["exists"] = fsBuiltin(
+		"exists", "string -> bool", []object.ObjectType{object.STRING},
+		func(path string, _ []object.Object) (object.Object, error) {
+			_, err := os.Stat(path)
+			if errors.Is(err, fs.ErrNotExist) {
+				return FALSE, nil
+			}
+			return TRUE, err
+		},
+	)
+	// list_dir returns the sorted names of the entries of a directory.
+	builtins["list_dir"] = fsBuiltin(
+		"list_dir", "string -> array<string>", []object.ObjectType{object.STRING},
+		func(path string, _ []object.Object) (object.Object, error) {
+			entries, err := os.ReadDir(path)
+			names := make([]string, len(entries))
+			for i, entry := range entries {
+				names[i] = entry.Name()
+			}
+			return stringArray(names), err
+		},
+	)
+	// mkdir creates a directory along with any missing parents.
+	builtins["mkdir"] = fsBuiltin(
+		"mkdir", "string -> null", []object.ObjectType{object.STRING},
+		func(path string, _ []object.Object) (object.Object, error) {
+			return NULL, os.MkdirAll(path, 0o755)
+		},
+	)
+	// remove removes a file or an empty directory.
+	builtins["remove"] = fsBuiltin(
+		"remove", "string -> null", []object.ObjectType{object.STRING},
+		func(path string, _ []object.Object) (object.Object, error) {
+			return NULL, os.Remove(path)
+		},
+	)
+	// glob returns the files matching a pattern in the syntax of filepath.Match,
+	// leaving out the ones outside of the accessible files.
+	builtins["glob"] = typedBuiltin(
+		"glob", "string -> array<string>", 1,
+		[]object.ObjectType{object.STRING},
+		func(args ...object.Object) object.Object {
+			if fileAccess == nil {
+				return fileAccessError("glob")
+			}
+
+			matches, err := filepath.Glob(stringValue(args[0]))
+			if err != nil {
+				return newError("`glob` failed: %s", err)
+			}
+			allowed := []string{}
+			for _, match := range matches {
+				if _, err := fileAccess.resolve(match); err == nil {
+					allowed = append(allowed, match)
+				}
+			}
+			return stringArray(allowed)
+		},
+	)
+
+	modules["path"] = newModule(map[string]object.Object{
+		"join": &object.Builtin{
+			MinArgs: 1,
+			MaxArgs: object.VARIADIC,
+			Scheme:  "(string, string) -> string",
+			Fn: func(args ...object.Object) object.Object {
+				if err := checkArity(len(args), 1, object.VARIADIC); err != nil {
+					return err
+				}
+				parts := make([]string, len(args))
+				for i, arg := range args {
+					s, ok := arg.(*object.String)
+					if !ok {
+						return newError("argument %d to `path.join` must be STRING, got %s", i+1, arg.Type())
+					}
+					parts[i] = s.Value
+				}
+				return &object.String{Value: filepath.Join(parts...)}
+			},
+		},
+		"basename": stringMapper("path.basename", filepath.Base),
+		"dirname":  stringMapper("path.dirname", filepath.Dir),
+		"ext":      stringMapper("path.ext", filepath.Ext),
+	})
+}
+
+// fsBuiltin returns a builtin whose first argument is a path, fn is called with
+// the resolved path once access is checked. Errors of fn are returned as
+// errors of the builtin.
+func fsBuiltin(name, scheme string, types []object.ObjectType, fn func(path string, args []object.Object) (object.Object, error)) *object.Builtin {
+	return typedBuiltin(name, scheme, len(types), types, func(args ...object.Object) object.Object {
+		if fileAccess == nil {
+			return fileAccessError(name)
+		}
+
+		given := stringValue(args[0])
+		path, err := fileAccess.resolve(given)
+		if err == nil {
+			var result object.Object
+			if result, err = fn(path, args); err == nil {
+				return result
+			}
+		}
+
+		// report the path as the script named it
+		var pathErr *fs.PathError
+		if errors.As(err, &pathErr) {
+			pathErr.Path = given
+		}
+		return newError("`%s` failed: %s", name, err)
+	})
+}
+
+func fileAccessError(name string) *object.ErrorValue {
+	return newError("`%s` needs file system access, run with --allow-fs", name)
+}
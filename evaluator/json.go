@@ -0,0 +1,211 @@
+package evaluator
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"math/big"
+	"strings"
+
+	"github.com/gkampitakis/monkey/object"
+)
+
+func init() {
+	// json_encode encodes a value as JSON, hashes keep the order of their keys.
+	// The optional options hash accepts "indent", the number of spaces to indent
+	// nested values with.
+	builtins["json_encode"] = &object.Builtin{
+		MinArgs: 1,
+		MaxArgs: 2,
+		Scheme:  "(a, hash<string, int>) -> string",
+		Fn: func(args ...object.Object) object.Object {
+			if err := checkArity(len(args), 1, 2); err != nil {
+				return err
+			}
+
+			indent := 0
+			if len(args) == 2 {
+				var err *object.ErrorValue
+				if indent, err = jsonOptions(args[1]); err != nil {
+					return err
+				}
+			}
+
+			var buf bytes.Buffer
+			if err := encodeJSON(&buf, args[0]); err != nil {
+				return err
+			}
+			if indent == 0 {
+				return &object.String{Value: buf.String()}
+			}
+
+			var out bytes.Buffer
+			// the encoded value is valid JSON, so Indent can't fail
+			_ = json.Indent(&out, buf.Bytes(), "", strings.Repeat(" ", indent))
+			return &object.String{Value: out.String()}
+		},
+	}
+	// json_decode decodes a JSON document. Objects become hashes in the order of
+	// their keys, integers become integers and other numbers floats.
+	builtins["json_decode"] = typedBuiltin(
+		"json_decode", "string -> a", 1,
+		[]object.ObjectType{object.STRING},
+		func(args ...object.Object) object.Object {
+			dec := json.NewDecoder(strings.NewReader(stringValue(args[0])))
+			dec.UseNumber()
+
+			value, err := decodeJSON(dec)
+			if err == nil {
+				// only whitespace may follow the value
+				if _, err = dec.Token(); err == io.EOF {
+					return value
+				}
+				if err == nil {
+					err = errors.New("unexpected data after the value")
+				}
+			}
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return newError("`json_decode` invalid JSON: %s", err)
+		},
+	)
+}
+
+// jsonOptions returns the indent set in the options of json_encode.
+func jsonOptions(o object.Object) (int, *object.ErrorValue) {
+	options, ok := o.(*object.Hash)
+	if !ok {
+		return 0, newError("second argument to `json_encode` must be HASH, got %s", o.Type())
+	}
+
+	indent := 0
+	for _, pair := range options.Pairs() {
+		if key, ok := pair.Key.(*object.String); !ok || key.Value != "indent" {
+			return 0, newError("`json_encode` unknown option: %s", pair.Key.Inspect())
+		}
+		n, ok := pair.Value.(*object.Integer)
+		if !ok || n.Value < 0 {
+			return 0, newError("`json_encode` indent must be a non-negative INTEGER, got %s", pair.Value.Inspect())
+		}
+		indent = n.Value
+	}
+
+	return indent, nil
+}
+
+func encodeJSON(buf *bytes.Buffer, o object.Object) *object.ErrorValue {
+	switch o := o.(type) {
+	case *object.Null:
+		buf.WriteString("null")
+	case *object.Boolean, *object.Integer, *object.BigInt:
+		buf.WriteString(o.Inspect())
+	case *object.Float:
+		if math.IsInf(o.Value, 0) || math.IsNaN(o.Value) {
+			return newError("`json_encode` cannot encode %s", o.Inspect())
+		}
+		// floats keep a fraction or exponent, so they decode as floats
+		buf.WriteString(o.Inspect())
+	case *object.String:
+		encodeJSONString(buf, o.Value)
+	case *object.Array:
+		buf.WriteByte('[')
+		for i, el := range o.Elements {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := encodeJSON(buf, el); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case *object.Hash:
+		buf.WriteByte('{')
+		for i, pair := range o.Pairs() {
+			key, ok := pair.Key.(*object.String)
+			if !ok {
+				return newError("`json_encode` hash keys must be STRING, got %s", pair.Key.Type())
+			}
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			encodeJSONString(buf, key.Value)
+			buf.WriteByte(':')
+			if err := encodeJSON(buf, pair.Value); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	default:
+		return newError("`json_encode` cannot encode %s", o.Type())
+	}
+
+	return nil
+}
+
+func encodeJSONString(buf *bytes.Buffer, s string) {
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	// strings always encode
+	_ = enc.Encode(s)
+	// Encode terminates the value with a newline
+	buf.Truncate(buf.Len() - 1)
+}
+
+// decodeJSON decodes the next value of dec, which must use numbers.
+func decodeJSON(dec *json.Decoder) (object.Object, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch tok := tok.(type) {
+	case nil:
+		return NULL, nil
+	case bool:
+		return nativeBoolToBooleanObject(tok), nil
+	case string:
+		return &object.String{Value: tok}, nil
+	case json.Number:
+		if n, ok := new(big.Int).SetString(tok.String(), 10); ok {
+			return newInteger(n), nil
+		}
+		f, err := tok.Float64()
+		if err != nil {
+			return nil, err
+		}
+		return &object.Float{Value: f}, nil
+	case json.Delim:
+		if tok == '[' {
+			elements := []object.Object{}
+			for dec.More() {
+				el, err := decodeJSON(dec)
+				if err != nil {
+					return nil, err
+				}
+				elements = append(elements, el)
+			}
+			_, err := dec.Token()
+			return &object.Array{Elements: elements}, err
+		}
+
+		hash := &object.Hash{}
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeJSON(dec)
+			if err != nil {
+				return nil, err
+			}
+			hash.Set(&object.String{Value: key.(string)}, value)
+		}
+		_, err := dec.Token()
+		return hash, err
+	}
+
+	return nil, errors.New("unexpected token")
+}
@@ -0,0 +1,308 @@
+package evaluator
+
+import (
+	"math"
+	"math/big"
+	"sort"
+
+	"github.com/gkampitakis/monkey/object"
+)
+
+// modules are hashes of builtins and constants, their members are accessed with
+// the dot operator, e.g. math.sqrt(2).
+var modules = map[string]*object.Hash{}
+
+func init() {
+	modules["math"] = newModule(map[string]object.Object{
+		"pi": &object.Float{Value: math.Pi},
+		"e":  &object.Float{Value: math.E},
+		"abs": numericBuiltin("math.abs", "a -> a", 1, 1, func(args []object.Object) object.Object {
+			switch x := args[0].(type) {
+			case *object.Integer:
+				if x.Value == math.MinInt {
+					return newInteger(new(big.Int).Neg(toBigInt(x)))
+				}
+				if x.Value < 0 {
+					return &object.Integer{Value: -x.Value}
+				}
+				return x
+			case *object.BigInt:
+				return newInteger(new(big.Int).Abs(x.Value))
+			default:
+				return &object.Float{Value: math.Abs(toFloat(x))}
+			}
+		}),
+		// pow is ** except that integers raised to a negative exponent are floats
+		"pow": numericBuiltin("math.pow", "", 2, 2, func(args []object.Object) object.Object {
+			if isInteger(args[0]) && isInteger(args[1]) && toBigInt(args[1]).Sign() >= 0 {
+				return evalInfixExpression("**", args[0], args[1])
+			}
+			return &object.Float{Value: math.Pow(toFloat(args[0]), toFloat(args[1]))}
+		}),
+		"sqrt":  floatFunction("math.sqrt", math.Sqrt),
+		"floor": roundingFunction("math.floor", math.Floor),
+		"ceil":  roundingFunction("math.ceil", math.Ceil),
+		"min":   numericExtremum("math.min", -1),
+		"max":   numericExtremum("math.max", 1),
+		"clamp": numericBuiltin("math.clamp", "", 3, 3, func(args []object.Object) object.Object {
+			x, low, high := args[0], args[1], args[2]
+			if compareNumbers(low, high) > 0 {
+				return newError("`math.clamp` lower bound %s is greater than upper bound %s", low.Inspect(), high.Inspect())
+			}
+			switch {
+			case compareNumbers(x, low) < 0:
+				return low
+			case compareNumbers(x, high) > 0:
+				return high
+			default:
+				return x
+			}
+		}),
+		"gcd": integerFunction("math.gcd", func(a, b *big.Int) object.Object {
+			return newInteger(new(big.Int).GCD(nil, nil, new(big.Int).Abs(a), new(big.Int).Abs(b)))
+		}),
+		"lcm": integerFunction("math.lcm", func(a, b *big.Int) object.Object {
+			if a.Sign() == 0 || b.Sign() == 0 {
+				return &object.Integer{Value: 0}
+			}
+			d := new(big.Int).GCD(nil, nil, new(big.Int).Abs(a), new(big.Int).Abs(b))
+			m := new(big.Int).Mul(new(big.Int).Quo(a, d), b)
+			return newInteger(m.Abs(m))
+		}),
+		"sin":  floatFunction("math.sin", math.Sin),
+		"cos":  floatFunction("math.cos", math.Cos),
+		"tan":  floatFunction("math.tan", math.Tan),
+		"asin": floatFunction("math.asin", math.Asin),
+		"acos": floatFunction("math.acos", math.Acos),
+		// atan(y, x) is the arc tangent of y/x using the signs of both to pick
+		// the quadrant
+		"atan": numericBuiltin("math.atan", "(a, b) -> float", 1, 2, func(args []object.Object) object.Object {
+			if len(args) == 2 {
+				return &object.Float{Value: math.Atan2(toFloat(args[0]), toFloat(args[1]))}
+			}
+			return &object.Float{Value: math.Atan(toFloat(args[0]))}
+		}),
+		"exp": floatFunction("math.exp", math.Exp),
+		// log is the natural logarithm, or the logarithm in the optional base
+		"log": numericBuiltin("math.log", "(a, b) -> float", 1, 2, func(args []object.Object) object.Object {
+			x := toFloat(args[0])
+			if len(args) == 2 {
+				base := toFloat(args[1])
+				if base <= 0 || base == 1 {
+					return newError("`math.log` base must be positive and not 1, got %s", args[1].Inspect())
+				}
+				if base == 2 {
+					return domainCheck("math.log", args[0], math.Log2(x))
+				}
+				if base == 10 {
+					return domainCheck("math.log", args[0], math.Log10(x))
+				}
+				return domainCheck("math.log", args[0], math.Log(x)/math.Log(base))
+			}
+			return domainCheck("math.log", args[0], math.Log(x))
+		}),
+	})
+}
+
+// newModule returns a module with the given members, ordered by name. Modules
+// are shared by every program, so they are frozen.
+func newModule(members map[string]object.Object) *object.Hash {
+	names := make([]string, 0, len(members))
+	for name := range members {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	module := &object.Hash{}
+	for _, name := range names {
+		module.Set(&object.String{Value: name}, members[name])
+	}
+	module.Freeze()
+
+	return module
+}
+
+// numericBuiltin returns a builtin taking minArgs to maxArgs integers or floats.
+// fn is only called with valid arguments. Builtins whose result type depends on
+// the mix of integers and floats have no scheme.
+func numericBuiltin(name, scheme string, minArgs, maxArgs int, fn func([]object.Object) object.Object) *object.Builtin {
+	return &object.Builtin{
+		MinArgs: minArgs,
+		MaxArgs: maxArgs,
+		Scheme:  scheme,
+		Fn: func(args ...object.Object) object.Object {
+			if err := checkArity(len(args), minArgs, maxArgs); err != nil {
+				return err
+			}
+
+			for i, arg := range args {
+				if isNumber(arg) {
+					continue
+				}
+				if maxArgs == 1 {
+					return newError("argument to `%s` must be INTEGER or FLOAT, got %s", name, arg.Type())
+				}
+				return newError("argument %d to `%s` must be INTEGER or FLOAT, got %s", i+1, name, arg.Type())
+			}
+
+			return fn(args)
+		},
+	}
+}
+
+// floatFunction returns a builtin applying fn to its argument converted to a
+// float. Arguments outside of the domain of fn are errors.
+func floatFunction(name string, fn func(float64) float64) *object.Builtin {
+	return numericBuiltin(name, "a -> float", 1, 1, func(args []object.Object) object.Object {
+		return domainCheck(name, args[0], fn(toFloat(args[0])))
+	})
+}
+
+// domainCheck returns result, or an error when it's NaN but x isn't.
+func domainCheck(name string, x object.Object, result float64) object.Object {
+	if math.IsNaN(result) && !math.IsNaN(toFloat(x)) {
+		return newError("argument to `%s` out of domain: %s", name, x.Inspect())
+	}
+
+	return &object.Float{Value: result}
+}
+
+// roundingFunction returns a builtin rounding a float to an integer with round,
+// integers are returned as is.
+func roundingFunction(name string, round func(float64) float64) *object.Builtin {
+	return numericBuiltin(name, "a -> int", 1, 1, func(args []object.Object) object.Object {
+		f, ok := args[0].(*object.Float)
+		if !ok {
+			return args[0]
+		}
+
+		r := round(f.Value)
+		if math.IsNaN(r) || math.IsInf(r, 0) {
+			return newError("`%s` of %s is not an integer", name, f.Inspect())
+		}
+		n, _ := big.NewFloat(r).Int(nil)
+		return newInteger(n)
+	})
+}
+
+// numericExtremum returns a builtin finding the smallest of its arguments when
+// sign is -1 and the largest when it's 1.
+func numericExtremum(name string, sign int) *object.Builtin {
+	return numericBuiltin(name, "", 1, object.VARIADIC, func(args []object.Object) object.Object {
+		result := args[0]
+		for _, arg := range args[1:] {
+			if sign*compareNumbers(arg, result) > 0 {
+				result = arg
+			}
+		}
+		return result
+	})
+}
+
+// integerFunction returns a builtin taking two integers, which fn must not
+// modify.
+func integerFunction(name string, fn func(a, b *big.Int) object.Object) *object.Builtin {
+	return &object.Builtin{
+		MinArgs: 2,
+		MaxArgs: 2,
+		Scheme:  "(int, int) -> int",
+		Fn: func(args ...object.Object) object.Object {
+			if err := checkArity(len(args), 2, 2); err != nil {
+				return err
+			}
+			for i, arg := range args {
+				if !isInteger(arg) {
+					return newError("%s argument to `%s` must be INTEGER, got %s", ordinals[i], name, arg.Type())
+				}
+			}
+
+			return fn(toBigInt(args[0]), toBigInt(args[1]))
+		},
+	}
+}
+
+// integerArithmetic applies the operator +, -, * or ** to a and b. It reports
+// false when the result doesn't fit an int or the exponent is negative.
+func integerArithmetic(operator string, a, b int) (int, bool) {
+	switch operator {
+	case "+":
+		c := a + b
+		return c, (c > a) == (b > 0)
+	case "-":
+		c := a - b
+		return c, (c < a) == (b > 0)
+	case "*":
+		if a == 0 || b == 0 {
+			return 0, true
+		}
+		c := a * b
+		if (a == -1 && b == math.MinInt) || (b == -1 && a == math.MinInt) {
+			return c, false
+		}
+		return c, c/b == a
+	case "**":
+		if b < 0 {
+			return 0, false
+		}
+		result, ok := 1, true
+		for ; b > 0 && ok; b >>= 1 {
+			if b&1 == 1 {
+				if result, ok = integerArithmetic("*", result, a); !ok {
+					break
+				}
+			}
+			if b > 1 {
+				a, ok = integerArithmetic("*", a, a)
+			}
+		}
+		return result, ok
+	}
+
+	return 0, false
+}
+
+// evalFloatInfixExpression evaluates an operator on floats, or on a float and
+// an integer converted to a float. Floats follow IEEE 754, so dividing by zero
+// results in an infinity.
+func evalFloatInfixExpression(operator string, left, right float64) object.Object {
+	switch operator {
+	case "+":
+		return &object.Float{Value: left + right}
+	case "-":
+		return &object.Float{Value: left - right}
+	case "*":
+		return &object.Float{Value: left * right}
+	case "/":
+		return &object.Float{Value: left / right}
+	case "**":
+		return &object.Float{Value: math.Pow(left, right)}
+	case "<":
+		return nativeBoolToBooleanObject(left < right)
+	case ">":
+		return nativeBoolToBooleanObject(left > right)
+	case "==":
+		return nativeBoolToBooleanObject(left == right)
+	case "!=":
+		return nativeBoolToBooleanObject(left != right)
+	default:
+		return newError("unknown operator: %s %s %s", object.FLOAT, operator, object.FLOAT)
+	}
+}
+
+func isNumber(o object.Object) bool {
+	return isInteger(o) || o.Type() == object.FLOAT
+}
+
+// toFloat converts an integer or float to a float, big integers are rounded to
+// the nearest float.
+func toFloat(o object.Object) float64 {
+	switch o := o.(type) {
+	case *object.Integer:
+		return float64(o.Value)
+	case *object.BigInt:
+		f, _ := new(big.Float).SetInt(o.Value).Float64()
+		return f
+	default:
+		return o.(*object.Float).Value
+	}
+}
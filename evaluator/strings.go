@@ -0,0 +1,311 @@
+package evaluator
+
+import (
+	"errors"
+	"math/big"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gkampitakis/monkey/object"
+)
+
+// The string builtins work on characters rather than bytes, so indexes, widths
+// and chars are counted in unicode code points.
+func init() {
+	builtins["split"] = typedBuiltin(
+		"split", "(string, string) -> array<string>", 2,
+		[]object.ObjectType{object.STRING, object.STRING},
+		func(args ...object.Object) object.Object {
+			s, sep := stringValue(args[0]), stringValue(args[1])
+			if sep == "" {
+				return chars(s)
+			}
+			return stringArray(strings.Split(s, sep))
+		},
+	)
+	builtins["join"] = typedBuiltin(
+		"join", "(array<string>, string) -> string", 1,
+		[]object.ObjectType{object.ARRAY, object.STRING},
+		func(args ...object.Object) object.Object {
+			elements := args[0].(*object.Array).Elements
+			parts := make([]string, len(elements))
+			for i, el := range elements {
+				s, ok := el.(*object.String)
+				if !ok {
+					return newError("`join` expects STRING elements, got %s", el.Type())
+				}
+				parts[i] = s.Value
+			}
+
+			sep := ""
+			if len(args) == 2 {
+				sep = stringValue(args[1])
+			}
+			return &object.String{Value: strings.Join(parts, sep)}
+		},
+	)
+	builtins["trim"] = trimBuiltin("trim", strings.TrimSpace, strings.Trim)
+	builtins["trim_left"] = trimBuiltin("trim_left", func(s string) string {
+		return strings.TrimLeftFunc(s, unicode.IsSpace)
+	}, strings.TrimLeft)
+	builtins["trim_right"] = trimBuiltin("trim_right", func(s string) string {
+		return strings.TrimRightFunc(s, unicode.IsSpace)
+	}, strings.TrimRight)
+	builtins["replace"] = typedBuiltin(
+		"replace", "(string, string, string, int) -> string", 3,
+		[]object.ObjectType{object.STRING, object.STRING, object.STRING, object.INTEGER},
+		func(args ...object.Object) object.Object {
+			n := -1
+			if len(args) == 4 {
+				n = args[3].(*object.Integer).Value
+			}
+			return &object.String{Value: strings.Replace(stringValue(args[0]), stringValue(args[1]), stringValue(args[2]), n)}
+		},
+	)
+	builtins["contains"] = stringPredicate("contains", strings.Contains)
+	builtins["starts_with"] = stringPredicate("starts_with", strings.HasPrefix)
+	builtins["ends_with"] = stringPredicate("ends_with", strings.HasSuffix)
+	builtins["index_of"] = typedBuiltin(
+		"index_of", "(string, string) -> int", 2,
+		[]object.ObjectType{object.STRING, object.STRING},
+		func(args ...object.Object) object.Object {
+			s := stringValue(args[0])
+			i := strings.Index(s, stringValue(args[1]))
+			if i > 0 {
+				i = utf8.RuneCountInString(s[:i])
+			}
+			return &object.Integer{Value: i}
+		},
+	)
+	builtins["upper"] = stringMapper("upper", strings.ToUpper)
+	builtins["lower"] = stringMapper("lower", strings.ToLower)
+	builtins["repeat"] = typedBuiltin(
+		"repeat", "(string, int) -> string", 2,
+		[]object.ObjectType{object.STRING, object.INTEGER},
+		func(args ...object.Object) object.Object {
+			count := args[1].(*object.Integer).Value
+			if count < 0 {
+				return newError("`repeat` count must not be negative, got %d", count)
+			}
+			s := stringValue(args[0])
+			if s != "" && count > maxStringLength/len(s) {
+				return stringTooLong("repeat")
+			}
+			return &object.String{Value: strings.Repeat(s, count)}
+		},
+	)
+	builtins["pad_left"] = padBuiltin("pad_left", func(s, padding string) string { return padding + s })
+	builtins["pad_right"] = padBuiltin("pad_right", func(s, padding string) string { return s + padding })
+	builtins["chars"] = typedBuiltin(
+		"chars", "string -> array<string>", 1,
+		[]object.ObjectType{object.STRING},
+		func(args ...object.Object) object.Object {
+			return chars(stringValue(args[0]))
+		},
+	)
+	// lines splits on "\n" and "\r\n", a trailing line break doesn't start a line
+	builtins["lines"] = typedBuiltin(
+		"lines", "string -> array<string>", 1,
+		[]object.ObjectType{object.STRING},
+		func(args ...object.Object) object.Object {
+			s := strings.TrimSuffix(stringValue(args[0]), "\n")
+			if s == "" {
+				return &object.Array{Elements: []object.Object{}}
+			}
+
+			lines := strings.Split(s, "\n")
+			for i, line := range lines {
+				lines[i] = strings.TrimSuffix(line, "\r")
+			}
+			return stringArray(lines)
+		},
+	)
+	builtins["to_string"] = &object.Builtin{
+		MinArgs: 1,
+		MaxArgs: 1,
+		Scheme:  "a -> string",
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+			if s, ok := args[0].(*object.String); ok {
+				return s
+			}
+
+			return &object.String{Value: args[0].Inspect()}
+		},
+	}
+	// parse_int returns null when the string isn't an integer in the base, which
+	// defaults to 10.
+	builtins["parse_int"] = typedBuiltin(
+		"parse_int", "(string, int) -> int", 1,
+		[]object.ObjectType{object.STRING, object.INTEGER},
+		func(args ...object.Object) object.Object {
+			base := 10
+			if len(args) == 2 {
+				base = args[1].(*object.Integer).Value
+				if base < 2 || base > 36 {
+					return newError("`parse_int` base must be between 2 and 36, got %d", base)
+				}
+			}
+
+			s := strings.TrimSpace(stringValue(args[0]))
+			value, err := strconv.ParseInt(s, base, strconv.IntSize)
+			if err == nil {
+				return &object.Integer{Value: int(value)}
+			}
+			// integers that don't fit are big integers
+			if errors.Is(err, strconv.ErrRange) {
+				n, _ := new(big.Int).SetString(s, base)
+				return newInteger(n)
+			}
+			return NULL
+		},
+	)
+}
+
+var ordinals = []string{"first", "second", "third", "fourth"}
+
+// typedBuiltin returns a builtin taking arguments of the given types, the ones
+// after the first minArgs are optional. fn is only called with valid arguments.
+func typedBuiltin(name, scheme string, minArgs int, types []object.ObjectType, fn object.BuiltinFunction) *object.Builtin {
+	maxArgs := len(types)
+
+	return &object.Builtin{
+		MinArgs: minArgs,
+		MaxArgs: maxArgs,
+		Scheme:  scheme,
+		Fn: func(args ...object.Object) object.Object {
+			if err := checkArity(len(args), minArgs, maxArgs); err != nil {
+				return err
+			}
+
+			for i, arg := range args {
+				if arg.Type() == types[i] {
+					continue
+				}
+				if maxArgs == 1 {
+					return newError("argument to `%s` must be %s, got %s", name, types[i], arg.Type())
+				}
+				return newError("%s argument to `%s` must be %s, got %s", ordinals[i], name, types[i], arg.Type())
+			}
+
+			return fn(args...)
+		},
+	}
+}
+
+// checkArity returns an error when n arguments are outside of minArgs and
+// maxArgs, which can be object.VARIADIC.
+func checkArity(n, minArgs, maxArgs int) *object.ErrorValue {
+	switch {
+	case n < minArgs && maxArgs == object.VARIADIC:
+		return newError("wrong number of arguments. got=%d, want at least %d", n, minArgs)
+	case n >= minArgs && (n <= maxArgs || maxArgs == object.VARIADIC):
+		return nil
+	case minArgs == maxArgs:
+		return newError("wrong number of arguments. got=%d, want=%d", n, minArgs)
+	case maxArgs-minArgs == 1:
+		return newError("wrong number of arguments. got=%d, want=%d or %d", n, minArgs, maxArgs)
+	default:
+		return newError("wrong number of arguments. got=%d, want=%d to %d", n, minArgs, maxArgs)
+	}
+}
+
+// trimBuiltin returns a builtin removing whitespace from s with trimSpace, or
+// the characters of its optional second argument with trim.
+func trimBuiltin(name string, trimSpace func(string) string, trim func(string, string) string) *object.Builtin {
+	return typedBuiltin(
+		name, "(string, string) -> string", 1,
+		[]object.ObjectType{object.STRING, object.STRING},
+		func(args ...object.Object) object.Object {
+			if len(args) == 2 {
+				return &object.String{Value: trim(stringValue(args[0]), stringValue(args[1]))}
+			}
+			return &object.String{Value: trimSpace(stringValue(args[0]))}
+		},
+	)
+}
+
+func stringPredicate(name string, predicate func(string, string) bool) *object.Builtin {
+	return typedBuiltin(
+		name, "(string, string) -> bool", 2,
+		[]object.ObjectType{object.STRING, object.STRING},
+		func(args ...object.Object) object.Object {
+			return nativeBoolToBooleanObject(predicate(stringValue(args[0]), stringValue(args[1])))
+		},
+	)
+}
+
+func stringMapper(name string, mapper func(string) string) *object.Builtin {
+	return typedBuiltin(
+		name, "string -> string", 1,
+		[]object.ObjectType{object.STRING},
+		func(args ...object.Object) object.Object {
+			return &object.String{Value: mapper(stringValue(args[0]))}
+		},
+	)
+}
+
+// padBuiltin returns a builtin padding s to width characters by repeating its
+// optional third argument, a space by default. add places the padding.
+func padBuiltin(name string, add func(s, padding string) string) *object.Builtin {
+	return typedBuiltin(
+		name, "(string, int, string) -> string", 2,
+		[]object.ObjectType{object.STRING, object.INTEGER, object.STRING},
+		func(args ...object.Object) object.Object {
+			s, width := stringValue(args[0]), args[1].(*object.Integer).Value
+			pad := " "
+			if len(args) == 3 {
+				pad = stringValue(args[2])
+			}
+			if pad == "" {
+				return newError("`%s` padding must not be empty", name)
+			}
+
+			missing := width - utf8.RuneCountInString(s)
+			if missing <= 0 {
+				return args[0]
+			}
+			count := missing/utf8.RuneCountInString(pad) + 1
+			if count > maxStringLength/len(pad) {
+				return stringTooLong(name)
+			}
+			padding := []rune(strings.Repeat(pad, count))[:missing]
+			return &object.String{Value: add(s, string(padding))}
+		},
+	)
+}
+
+// maxStringLength is the length in bytes of the longest string the builtins
+// build, longer results are errors rather than exhausting memory.
+const maxStringLength = 1 << 30
+
+func stringTooLong(name string) *object.ErrorValue {
+	return newError("`%s` result longer than %d bytes", name, maxStringLength)
+}
+
+func stringValue(o object.Object) string {
+	return o.(*object.String).Value
+}
+
+func stringArray(values []string) *object.Array {
+	elements := make([]object.Object, len(values))
+	for i, v := range values {
+		elements[i] = &object.String{Value: v}
+	}
+
+	return &object.Array{Elements: elements}
+}
+
+// chars returns the characters of s, invalid UTF-8 bytes become U+FFFD.
+func chars(s string) *object.Array {
+	elements := []object.Object{}
+	for _, r := range s {
+		elements = append(elements, &object.String{Value: string(r)})
+	}
+
+	return &object.Array{Elements: elements}
+}
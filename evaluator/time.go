@@ -0,0 +1,354 @@
+package evaluator
+
+import (
+	"math"
+	"time"
+
+	"github.com/gkampitakis/monkey/object"
+)
+
+// Clock is the source of time of the time builtins.
+type Clock interface {
+	Now() time.Time
+	Sleep(d time.Duration)
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time        { return time.Now() }
+func (systemClock) Sleep(d time.Duration) { time.Sleep(d) }
+
+var (
+	clock Clock = systemClock{}
+	// clockStart is the instant clock() measures from
+	clockStart = clock.Now()
+)
+
+// SetClock makes the time builtins read the time from c, nil restores the
+// system clock. Tests use it to inject a fake clock.
+func SetClock(c Clock) {
+	if c == nil {
+		c = systemClock{}
+	}
+	clock, clockStart = c, c.Now()
+}
+
+func init() {
+	builtins["now"] = &object.Builtin{
+		MinArgs: 0,
+		MaxArgs: 0,
+		Scheme:  "() -> time",
+		Fn: func(args ...object.Object) object.Object {
+			if err := checkArity(len(args), 0, 0); err != nil {
+				return err
+			}
+			return &object.Time{Value: clock.Now()}
+		},
+	}
+	// unix returns the current Unix time in seconds.
+	builtins["unix"] = &object.Builtin{
+		MinArgs: 0,
+		MaxArgs: 0,
+		Scheme:  "() -> int",
+		Fn: func(args ...object.Object) object.Object {
+			if err := checkArity(len(args), 0, 0); err != nil {
+				return err
+			}
+			return &object.Integer{Value: int(clock.Now().Unix())}
+		},
+	}
+	// clock returns the duration elapsed on a monotonic clock since the program
+	// started, only the difference of two readings is meaningful.
+	builtins["clock"] = &object.Builtin{
+		MinArgs: 0,
+		MaxArgs: 0,
+		Scheme:  "() -> duration",
+		Fn: func(args ...object.Object) object.Object {
+			if err := checkArity(len(args), 0, 0); err != nil {
+				return err
+			}
+			return &object.Duration{Value: clock.Now().Sub(clockStart)}
+		},
+	}
+	// sleep pauses for a duration, or a number of milliseconds.
+	builtins["sleep"] = &object.Builtin{
+		MinArgs: 1,
+		MaxArgs: 1,
+		Scheme:  "a -> null",
+		Fn: func(args ...object.Object) object.Object {
+			if err := checkArity(len(args), 1, 1); err != nil {
+				return err
+			}
+			d, err := toDuration("sleep", args[0])
+			if err != nil {
+				return err
+			}
+			clock.Sleep(d)
+			return NULL
+		},
+	}
+	// duration returns the duration of a number of milliseconds.
+	builtins["duration"] = &object.Builtin{
+		MinArgs: 1,
+		MaxArgs: 1,
+		Scheme:  "a -> duration",
+		Fn: func(args ...object.Object) object.Object {
+			if err := checkArity(len(args), 1, 1); err != nil {
+				return err
+			}
+			if args[0].Type() == object.DURATION {
+				return newError("argument to `duration` must be INTEGER or FLOAT, got %s", args[0].Type())
+			}
+			d, err := toDuration("duration", args[0])
+			if err != nil {
+				return err
+			}
+			return &object.Duration{Value: d}
+		},
+	}
+	// format_time formats a time with a layout of the time package of Go, e.g.
+	// 2006-01-02 15:04:05, RFC 3339 by default.
+	builtins["format_time"] = &object.Builtin{
+		MinArgs: 1,
+		MaxArgs: 2,
+		Scheme:  "(time, string) -> string",
+		Fn: func(args ...object.Object) object.Object {
+			if err := checkArity(len(args), 1, 2); err != nil {
+				return err
+			}
+			t, ok := args[0].(*object.Time)
+			if !ok {
+				return newError("first argument to `format_time` must be TIME, got %s", args[0].Type())
+			}
+			layout, err := timeLayout("format_time", args)
+			if err != nil {
+				return err
+			}
+			return &object.String{Value: t.Value.Format(layout)}
+		},
+	}
+	// parse_time parses a time formatted with the layout, see format_time.
+	builtins["parse_time"] = &object.Builtin{
+		MinArgs: 1,
+		MaxArgs: 2,
+		Scheme:  "(string, string) -> time",
+		Fn: func(args ...object.Object) object.Object {
+			if err := checkArity(len(args), 1, 2); err != nil {
+				return err
+			}
+			s, ok := args[0].(*object.String)
+			if !ok {
+				return newError("first argument to `parse_time` must be STRING, got %s", args[0].Type())
+			}
+			layout, errValue := timeLayout("parse_time", args)
+			if errValue != nil {
+				return errValue
+			}
+			t, err := time.Parse(layout, s.Value)
+			if err != nil {
+				return newError("`parse_time` failed: %s", err)
+			}
+			return &object.Time{Value: t}
+		},
+	}
+}
+
+// timeLayout returns the optional layout argument of name, RFC 3339 when it's
+// missing.
+func timeLayout(name string, args []object.Object) (string, *object.ErrorValue) {
+	if len(args) < 2 {
+		return time.RFC3339, nil
+	}
+
+	layout, ok := args[1].(*object.String)
+	if !ok {
+		return "", newError("second argument to `%s` must be STRING, got %s", name, args[1].Type())
+	}
+
+	return layout.Value, nil
+}
+
+// toDuration converts a duration, or a number of milliseconds, to a
+// time.Duration.
+func toDuration(name string, o object.Object) (time.Duration, *object.ErrorValue) {
+	if d, ok := o.(*object.Duration); ok {
+		return d.Value, nil
+	}
+	if !isNumber(o) {
+		return 0, newError("argument to `%s` must be DURATION, INTEGER or FLOAT, got %s", name, o.Type())
+	}
+
+	d, ok := floatDuration(toFloat(o) * float64(time.Millisecond))
+	if !ok {
+		return 0, newError("`%s` duration out of range: %s", name, o.Inspect())
+	}
+
+	return d, nil
+}
+
+// floatDuration rounds a number of nanoseconds to a time.Duration, reporting
+// false when it doesn't fit.
+func floatDuration(ns float64) (time.Duration, bool) {
+	ns = math.Round(ns)
+	// MaxInt64 rounds up to 2^63 as a float, which doesn't fit
+	if math.IsNaN(ns) || ns < math.MinInt64 || ns >= math.MaxInt64 {
+		return 0, false
+	}
+
+	return time.Duration(ns), true
+}
+
+// isTimeOperation reports whether left and right are operands of time
+// arithmetic: times and durations, or a duration and a number.
+func isTimeOperation(left, right object.Object) bool {
+	isTime := func(o object.Object) bool {
+		return o.Type() == object.TIME || o.Type() == object.DURATION
+	}
+
+	return (isTime(left) && isTime(right)) ||
+		(left.Type() == object.DURATION && isNumber(right)) ||
+		(isNumber(left) && right.Type() == object.DURATION)
+}
+
+// evalTimeInfixExpression evaluates an operator on operands of time arithmetic.
+// Subtracting times results in a duration, which can be added to or subtracted
+// from a time, scaled by a number or divided by another duration.
+func evalTimeInfixExpression(operator string, left, right object.Object) object.Object {
+	switch left := left.(type) {
+	case *object.Time:
+		switch right := right.(type) {
+		case *object.Time:
+			switch operator {
+			case "-":
+				return &object.Duration{Value: left.Value.Sub(right.Value)}
+			case "<":
+				return nativeBoolToBooleanObject(left.Value.Before(right.Value))
+			case ">":
+				return nativeBoolToBooleanObject(left.Value.After(right.Value))
+			case "==":
+				return nativeBoolToBooleanObject(left.Value.Equal(right.Value))
+			case "!=":
+				return nativeBoolToBooleanObject(!left.Value.Equal(right.Value))
+			}
+		case *object.Duration:
+			switch operator {
+			case "+":
+				return &object.Time{Value: left.Value.Add(right.Value)}
+			case "-":
+				return &object.Time{Value: left.Value.Add(-right.Value)}
+			}
+		}
+	case *object.Duration:
+		switch right := right.(type) {
+		case *object.Time:
+			if operator == "+" {
+				return &object.Time{Value: right.Value.Add(left.Value)}
+			}
+		case *object.Duration:
+			switch operator {
+			case "+", "-":
+				sum, ok := integerArithmetic(operator, int(left.Value), int(right.Value))
+				if !ok {
+					return newError("duration out of range: %s %s %s", left.Inspect(), operator, right.Inspect())
+				}
+				return &object.Duration{Value: time.Duration(sum)}
+			case "/":
+				return &object.Float{Value: float64(left.Value) / float64(right.Value)}
+			case "<":
+				return nativeBoolToBooleanObject(left.Value < right.Value)
+			case ">":
+				return nativeBoolToBooleanObject(left.Value > right.Value)
+			case "==":
+				return nativeBoolToBooleanObject(left.Value == right.Value)
+			case "!=":
+				return nativeBoolToBooleanObject(left.Value != right.Value)
+			}
+		default:
+			switch operator {
+			case "*":
+				return scaleDuration(operator, left, right, float64(left.Value)*toFloat(right))
+			case "/":
+				if toFloat(right) == 0 {
+					return newError("division by zero: %s / %s", left.Inspect(), right.Inspect())
+				}
+				return scaleDuration(operator, left, right, float64(left.Value)/toFloat(right))
+			}
+		}
+	default:
+		if operator == "*" {
+			return scaleDuration(operator, left, right, toFloat(left)*float64(right.(*object.Duration).Value))
+		}
+	}
+
+	if operator == "==" || operator == "!=" {
+		return nativeBoolToBooleanObject((operator == "==") == (left == right))
+	}
+	return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+}
+
+// scaleDuration returns the duration of ns nanoseconds, the result of scaling a
+// duration with the operator.
+func scaleDuration(operator string, left, right object.Object, ns float64) object.Object {
+	scaled, ok := floatDuration(ns)
+	if !ok {
+		return newError("duration out of range: %s %s %s", left.Inspect(), operator, right.Inspect())
+	}
+
+	return &object.Duration{Value: scaled}
+}
+
+// timeField returns the field of a time or a duration, e.g. t["year"] or
+// d.seconds.
+func timeField(o, index object.Object) object.Object {
+	name, ok := index.(*object.String)
+	if !ok {
+		return newError("%s field must be STRING, got %s", o.Type(), index.Type())
+	}
+
+	switch o := o.(type) {
+	case *object.Time:
+		t := o.Value
+		switch name.Value {
+		case "year":
+			return &object.Integer{Value: t.Year()}
+		case "month":
+			return &object.Integer{Value: int(t.Month())}
+		case "day":
+			return &object.Integer{Value: t.Day()}
+		case "hour":
+			return &object.Integer{Value: t.Hour()}
+		case "minute":
+			return &object.Integer{Value: t.Minute()}
+		case "second":
+			return &object.Integer{Value: t.Second()}
+		case "nanosecond":
+			return &object.Integer{Value: t.Nanosecond()}
+		// weekday counts from Sunday, 0
+		case "weekday":
+			return &object.Integer{Value: int(t.Weekday())}
+		case "yearday":
+			return &object.Integer{Value: t.YearDay()}
+		case "unix":
+			return &object.Integer{Value: int(t.Unix())}
+		case "zone":
+			zone, _ := t.Zone()
+			return &object.String{Value: zone}
+		}
+	case *object.Duration:
+		d := o.Value
+		switch name.Value {
+		case "hours":
+			return &object.Float{Value: d.Hours()}
+		case "minutes":
+			return &object.Float{Value: d.Minutes()}
+		case "seconds":
+			return &object.Float{Value: d.Seconds()}
+		case "milliseconds":
+			return &object.Integer{Value: int(d.Milliseconds())}
+		case "nanoseconds":
+			return &object.Integer{Value: int(d.Nanoseconds())}
+		}
+	}
+
+	return newError("unknown %s field: %s", o.Type(), name.Value)
+}
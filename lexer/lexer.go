@@ -1,8 +1,19 @@
 package lexer
 
-import "github.com/gkampitakis/monkey/token"
+import (
+	"bytes"
+	"errors"
+	"io"
+
+	"github.com/gkampitakis/monkey/token"
+)
+
+// MIN_READ is the minimum number of bytes a streaming lexer requests from its
+// reader. The buffer only grows past it to fit tokens longer than that.
+const MIN_READ = 4096
 
 type Lexer struct {
+	// source code, or the buffered window of it for streaming lexers
 	input []byte
 	// current position in input (points to current char)
 	position int
@@ -10,29 +21,119 @@ type Lexer struct {
 	readPosition int
 	// current char under examination
 	ch byte
+	// line and column of the current char
+	line   int
+	column int
+	// start of the token being read, streaming lexers keep input from here on
+	mark int
+	// reader of streaming lexers and the error that stopped reading from it
+	r   io.Reader
+	err error
 }
 
 func New(input []byte) *Lexer {
-	l := &Lexer{input: input}
+	l := &Lexer{input: input, line: 1}
+	l.skipShebang()
+	l.readChar()
+	return l
+}
+
+// NewReader returns a lexer reading the source code from r as tokens are
+// requested. Only the token being read is buffered, so memory stays constant
+// regardless of the size of the input.
+func NewReader(r io.Reader) *Lexer {
+	l := &Lexer{input: make([]byte, 0, MIN_READ), line: 1, r: r}
+	l.skipShebang()
 	l.readChar()
 	return l
 }
 
+// Err returns the error that stopped a streaming lexer from reading its input,
+// in which case the input is reported as ending there. It is nil at io.EOF.
+func (l *Lexer) Err() error {
+	if errors.Is(l.err, io.EOF) {
+		return nil
+	}
+
+	return l.err
+}
+
+// skipShebang skips a leading "#!" line, so scripts can be executed directly.
+func (l *Lexer) skipShebang() {
+	if l.byteAt(0) != '#' || l.byteAt(1) != '!' {
+		return
+	}
+
+	for ch := l.byteAt(0); ch != '\n' && ch != 0; ch = l.byteAt(0) {
+		l.readPosition++
+		l.mark = l.readPosition
+	}
+}
+
 func (l *Lexer) readChar() {
-	if l.readPosition >= len(l.input) {
-		l.ch = 0
-	} else {
-		l.ch = l.input[l.readPosition]
+	if l.ch == '\n' {
+		l.line++
+		l.column = 0
 	}
+	l.column++
 
+	l.ch = l.byteAt(0)
 	l.position = l.readPosition
 	l.readPosition++
 }
 
+// byteAt returns the byte offset bytes after the reading position, or 0 past
+// the end of the input.
+func (l *Lexer) byteAt(offset int) byte {
+	for l.readPosition+offset >= len(l.input) {
+		if !l.fill() {
+			return 0
+		}
+	}
+
+	return l.input[l.readPosition+offset]
+}
+
+// fill reads more of the input of a streaming lexer, dropping the bytes before
+// mark. It reports whether any bytes were read.
+func (l *Lexer) fill() bool {
+	if l.r == nil || l.err != nil {
+		return false
+	}
+
+	buf := l.input[:cap(l.input)]
+	if len(l.input)-l.mark+MIN_READ > cap(l.input) {
+		buf = make([]byte, 2*cap(l.input))
+	}
+	n := copy(buf, l.input[l.mark:])
+	l.position -= l.mark
+	l.readPosition -= l.mark
+	l.mark = 0
+
+	read := 0
+	for read == 0 && l.err == nil {
+		read, l.err = l.r.Read(buf[n:])
+	}
+	l.input = buf[:n+read]
+
+	return read > 0
+}
+
+// literal returns the input from mark up to the current char. Streaming lexers
+// reuse their buffer, so they return a copy.
+func (l *Lexer) literal() []byte {
+	if l.r != nil {
+		return bytes.Clone(l.input[l.mark:l.position])
+	}
+
+	return l.input[l.mark:l.position]
+}
+
 func (l *Lexer) NextToken() token.Token {
 	var tok token.Token
 
 	l.eatWhitespace()
+	pos := token.Position{Line: l.line, Column: l.column}
 
 	switch l.ch {
 	case '=':
@@ -60,7 +161,15 @@ func (l *Lexer) NextToken() token.Token {
 	case '+':
 		tok = newToken(token.PLUS, l.ch)
 	case '*':
-		tok = newToken(token.ASTERISK, l.ch)
+		if l.peekChar() == '*' {
+			ch := l.ch
+			l.readChar()
+			tok = token.Token{Type: token.POW, Literal: []byte{ch, l.ch}}
+		} else {
+			tok = newToken(token.ASTERISK, l.ch)
+		}
+	case '.':
+		tok = newToken(token.DOT, l.ch)
 	case '!':
 		if l.peekChar() == '=' {
 			ch := l.ch
@@ -92,20 +201,22 @@ func (l *Lexer) NextToken() token.Token {
 			tok.Literal = l.readIdentifier()
 			tok.Type = token.LookupIdent(tok.Literal)
 		} else if isDigit(l.ch) {
-			tok.Literal = l.readNumber()
-			tok.Type = token.INT
+			tok.Literal, tok.Type = l.readNumber()
 		} else {
 			tok = newToken(token.ILLEGAL, l.ch)
+			l.readChar()
 		}
+		tok.Pos = pos
 		return tok
 	}
 
 	l.readChar()
+	tok.Pos = pos
 	return tok
 }
 
 func (l *Lexer) readString() []byte {
-	position := l.position + 1
+	l.mark = l.position + 1
 	for {
 		l.readChar()
 		if l.ch == '"' || l.ch == 0 {
@@ -113,38 +224,57 @@ func (l *Lexer) readString() []byte {
 		}
 	}
 
-	return l.input[position:l.position]
+	return l.literal()
 }
 
-func (l *Lexer) readNumber() []byte {
-	position := l.position
+// readNumber reads an integer, or a float when the digits are followed by a
+// fraction, e.g. 1.5.
+func (l *Lexer) readNumber() ([]byte, token.TokenType) {
+	l.mark = l.position
+	for isDigit(l.ch) {
+		l.readChar()
+	}
+	if l.ch != '.' || !isDigit(l.peekChar()) {
+		return l.literal(), token.INT
+	}
+
+	l.readChar()
 	for isDigit(l.ch) {
 		l.readChar()
 	}
 
-	return l.input[position:l.position]
+	return l.literal(), token.FLOAT
 }
 
 func (l *Lexer) peekChar() byte {
-	if l.readPosition >= len(l.input) {
-		return 0
-	} else {
-		return l.input[l.readPosition]
-	}
+	return l.byteAt(0)
 }
 
 func (l *Lexer) readIdentifier() []byte {
-	position := l.position
+	l.mark = l.position
 	for isLetter(l.ch) {
 		l.readChar()
 	}
 
-	return l.input[position:l.position]
+	return l.literal()
 }
 
+// eatWhitespace skips whitespace and comments, which start with "//" and run
+// until the end of the line.
 func (l *Lexer) eatWhitespace() {
-	for l.ch == ' ' || l.ch == '\t' || l.ch == '\n' || l.ch == '\r' {
-		l.readChar()
+	for {
+		l.mark = l.position
+		switch {
+		case l.ch == ' ' || l.ch == '\t' || l.ch == '\n' || l.ch == '\r':
+			l.readChar()
+		case l.ch == '/' && l.peekChar() == '/':
+			for l.ch != '\n' && l.ch != 0 {
+				l.mark = l.position
+				l.readChar()
+			}
+		default:
+			return
+		}
 	}
 }
 
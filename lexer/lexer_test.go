@@ -1,13 +1,18 @@
 package lexer_test
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"runtime"
 	"strings"
 	"testing"
+	"testing/iotest"
 
 	"github.com/gkampitakis/go-snaps/snaps"
 	"github.com/gkampitakis/monkey/lexer"
 	"github.com/gkampitakis/monkey/token"
+	"github.com/stretchr/testify/require"
 )
 
 func TokensSnapshot(t *testing.T, input string) {
@@ -86,4 +91,175 @@ func TestNextToken(t *testing.T) {
 
 		TokensSnapshot(t, input)
 	})
+
+	t.Run("comments", func(t *testing.T) {
+		input := `// a comment
+		let a = 5; // trailing comment
+		let b = a / 2;
+		"// not a comment"
+		// comment at the end`
+
+		TokensSnapshot(t, input)
+	})
+
+	t.Run("numbers and operators", func(t *testing.T) {
+		input := `3.14 * 2 ** 10 / 0.5;
+		math.pi;
+		1.x;
+		[1, 2][0:1];`
+
+		TokensSnapshot(t, input)
+	})
+
+	t.Run("illegal characters", func(t *testing.T) {
+		input := `json_decode("{\"a\": 1}")`
+
+		TokensSnapshot(t, input)
+	})
+
+	t.Run("shebang line", func(t *testing.T) {
+		input := `#!/usr/bin/env monkey
+		let a = 5;
+		`
+
+		TokensSnapshot(t, input)
+	})
+}
+
+func TestTokenPositions(t *testing.T) {
+	input := `let five = 5;
+  "a string" != x`
+	expected := []token.Position{
+		{Line: 1, Column: 1},
+		{Line: 1, Column: 5},
+		{Line: 1, Column: 10},
+		{Line: 1, Column: 12},
+		{Line: 1, Column: 13},
+		{Line: 2, Column: 3},
+		{Line: 2, Column: 14},
+		{Line: 2, Column: 17},
+		{Line: 2, Column: 18},
+	}
+
+	l := lexer.New([]byte(input))
+	for _, pos := range expected {
+		require.Equal(t, pos, l.NextToken().Pos)
+	}
+}
+
+func tokens(l *lexer.Lexer) []token.Token {
+	var toks []token.Token
+	for {
+		tok := l.NextToken()
+		toks = append(toks, tok)
+		if tok.Type == token.EOF {
+			return toks
+		}
+	}
+}
+
+func TestNewReader(t *testing.T) {
+	inputs := []string{
+		"",
+		"let five = 5;\nlet add = fn(x, y) { x + y; };\nadd(five, 10) != 9;",
+		`{"foo": "bar", "baz": [1, 2]}`,
+		"#!/usr/bin/env monkey\nlet a = 5; // trailing comment\n// the end",
+		`"unterminated`,
+		"#!",
+		strings.Repeat("a", 3*lexer.MIN_READ) + " " + strings.Repeat("1", lexer.MIN_READ+1),
+		`"` + strings.Repeat("// x\n", lexer.MIN_READ) + `"`,
+		strings.Repeat("// comment\n", lexer.MIN_READ) + "x",
+		strings.Repeat(" ", lexer.MIN_READ-2) + "1.5 ** 2.25",
+	}
+
+	for i, input := range inputs {
+		t.Run(fmt.Sprint(i), func(t *testing.T) {
+			expected := tokens(lexer.New([]byte(input)))
+
+			l := lexer.NewReader(iotest.OneByteReader(strings.NewReader(input)))
+			require.Equal(t, expected, tokens(l))
+			require.NoError(t, l.Err())
+
+			l = lexer.NewReader(iotest.DataErrReader(strings.NewReader(input)))
+			require.Equal(t, expected, tokens(l))
+			require.NoError(t, l.Err())
+		})
+	}
+}
+
+func TestNewReaderError(t *testing.T) {
+	errRead := errors.New("read failed")
+	r := io.MultiReader(strings.NewReader("let a = 5"), iotest.ErrReader(errRead))
+
+	l := lexer.NewReader(r)
+	toks := tokens(l)
+
+	require.Len(t, toks, 5)
+	require.Equal(t, []byte("5"), toks[3].Literal)
+	require.Equal(t, token.EOF, toks[4].Type)
+	require.ErrorIs(t, l.Err(), errRead)
+}
+
+// repeatReader reads src over and over, up to n bytes.
+type repeatReader struct {
+	src []byte
+	off int
+	n   int
+}
+
+func (r *repeatReader) Read(p []byte) (int, error) {
+	if r.n == 0 {
+		return 0, io.EOF
+	}
+	if len(p) > r.n {
+		p = p[:r.n]
+	}
+
+	n := 0
+	for n < len(p) {
+		c := copy(p[n:], r.src[r.off:])
+		n += c
+		r.off = (r.off + c) % len(r.src)
+	}
+	r.n -= n
+
+	return n, nil
+}
+
+var benchmarkSource = []byte(`let fib = fn(n) { if (n < 2) { return n; } fib(n - 1) + fib(n - 2) };
+let h = {"name": "monkey", "values": [1, 2, 3]}; // a comment
+`)
+
+// BenchmarkNewReader lexes inputs of growing size without buffering them. The
+// live-heap-B metric, the peak of memory in use while lexing, stays flat as the
+// input grows.
+func BenchmarkNewReader(b *testing.B) {
+	for _, size := range []int{1 << 20, 8 << 20, 32 << 20} {
+		b.Run(fmt.Sprintf("%dMB", size>>20), func(b *testing.B) {
+			b.SetBytes(int64(size))
+			b.ReportAllocs()
+
+			var peak uint64
+			for i := 0; i < b.N; i++ {
+				runtime.GC()
+				var stats runtime.MemStats
+				runtime.ReadMemStats(&stats)
+				base := stats.HeapAlloc
+
+				l := lexer.NewReader(&repeatReader{src: benchmarkSource, n: size})
+				for n := 0; l.NextToken().Type != token.EOF; n++ {
+					if n%(1<<18) != 0 {
+						continue
+					}
+					runtime.GC()
+					runtime.ReadMemStats(&stats)
+					if stats.HeapAlloc > base && stats.HeapAlloc-base > peak {
+						peak = stats.HeapAlloc - base
+					}
+				}
+			}
+
+			b.ReportMetric(float64(peak), "live-heap-B")
+		})
+	}
 }
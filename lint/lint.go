@@ -0,0 +1,345 @@
+// Package lint reports suspicious constructs in monkey programs.
+//
+// Diagnostics can be suppressed with a comment on the same or the previous line:
+//
+//	// lint:ignore unused,shadow
+//	let i = i + 1;
+//
+// A lint:ignore comment without rules suppresses every rule.
+package lint
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gkampitakis/monkey/ast"
+	"github.com/gkampitakis/monkey/evaluator"
+	"github.com/gkampitakis/monkey/object"
+	"github.com/gkampitakis/monkey/token"
+)
+
+const (
+	UNUSED             = "unused"
+	SHADOW             = "shadow"
+	UNREACHABLE        = "unreachable"
+	ARITY              = "arity"
+	MIXED_COMPARE      = "mixed-compare"
+	CONSTANT_CONDITION = "constant-condition"
+)
+
+// Rules maps every rule to its description.
+var Rules = map[string]string{
+	UNUSED:             "bindings that are never referenced",
+	SHADOW:             "bindings that shadow or redeclare a name already in scope",
+	UNREACHABLE:        "statements after a return",
+	ARITY:              "calls to builtins with the wrong number of arguments",
+	MIXED_COMPARE:      "comparisons between literals of different types",
+	CONSTANT_CONDITION: "if and while conditions that are always true or always false",
+}
+
+type Diagnostic struct {
+	Pos     token.Position
+	Rule    string
+	Message string
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s: %s (%s)", d.Pos, d.Message, d.Rule)
+}
+
+// Config selects the rules to run.
+type Config struct {
+	// Disabled holds the rules that are not reported
+	Disabled map[string]bool
+}
+
+// Lint checks program, parsed from src, and returns the diagnostics sorted by
+// position. src is used to find suppression comments.
+func Lint(src []byte, program *ast.Program, cfg Config) []Diagnostic {
+	l := &linter{}
+	l.checkScope(newScope(nil, true), program.Statements)
+
+	ignores := suppressions(src)
+	diagnostics := []Diagnostic{}
+	for _, d := range l.diagnostics {
+		if cfg.Disabled[d.Rule] || ignores.match(d) {
+			continue
+		}
+		diagnostics = append(diagnostics, d)
+	}
+
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		if diagnostics[i].Pos.Line != diagnostics[j].Pos.Line {
+			return diagnostics[i].Pos.Line < diagnostics[j].Pos.Line
+		}
+		return diagnostics[i].Pos.Column < diagnostics[j].Pos.Column
+	})
+
+	return diagnostics
+}
+
+type binding struct {
+	name *ast.Identifier
+	used bool
+	// function is set for bindings of function literals
+	function bool
+}
+
+// scope holds the bindings of the program or a function body, as blocks don't
+// create scopes in monkey.
+type scope struct {
+	outer    *scope
+	bindings map[string]*binding
+	// all keeps every binding, including the ones redeclared later
+	all      []*binding
+	toplevel bool
+	// functions are checked after the scope, so they can refer to bindings
+	// declared after them
+	functions []*ast.FunctionLiteral
+}
+
+func newScope(outer *scope, toplevel bool) *scope {
+	return &scope{outer: outer, bindings: make(map[string]*binding), toplevel: toplevel}
+}
+
+func (s *scope) lookup(name string) (*binding, bool) {
+	for sc := s; sc != nil; sc = sc.outer {
+		if b, ok := sc.bindings[name]; ok {
+			return b, true
+		}
+	}
+
+	return nil, false
+}
+
+type linter struct {
+	diagnostics []Diagnostic
+}
+
+func (l *linter) report(pos token.Position, rule, format string, a ...interface{}) {
+	l.diagnostics = append(l.diagnostics, Diagnostic{Pos: pos, Rule: rule, Message: fmt.Sprintf(format, a...)})
+}
+
+func (l *linter) checkScope(s *scope, stmts []ast.Statement) {
+	l.checkStatements(s, stmts)
+
+	for len(s.functions) > 0 {
+		fn := s.functions[0]
+		s.functions = s.functions[1:]
+
+		inner := newScope(s, false)
+		for _, param := range fn.Parameters {
+			inner.bindings[string(param.Value)] = &binding{name: param, used: true}
+		}
+		l.checkScope(inner, fn.Body.Statements)
+	}
+
+	for _, b := range s.all {
+		if b.used || strings.HasPrefix(string(b.name.Value), "_") || (s.toplevel && b.function) {
+			continue
+		}
+		l.report(b.name.Token.Pos, UNUSED, "%s is declared but never used", b.name.Value)
+	}
+}
+
+func (l *linter) checkStatements(s *scope, stmts []ast.Statement) {
+	returned, reported := false, false
+
+	for _, stmt := range stmts {
+		// only the first unreachable statement is reported
+		if returned && !reported {
+			l.report(ast.Pos(stmt), UNREACHABLE, "unreachable statement after return")
+			reported = true
+		}
+
+		l.checkStatement(s, stmt)
+		if _, ok := stmt.(*ast.ReturnStatement); ok {
+			returned = true
+		}
+	}
+}
+
+func (l *linter) checkStatement(s *scope, stmt ast.Statement) {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		// the value is evaluated before the name is bound
+		l.checkExpression(s, stmt.Value)
+		l.declare(s, stmt)
+	case *ast.ReturnStatement:
+		l.checkExpression(s, stmt.ReturnValue)
+	case *ast.ExpressionStatement:
+		l.checkExpression(s, stmt.Expression)
+	case *ast.BlockStatement:
+		l.checkStatements(s, stmt.Statements)
+	}
+}
+
+func (l *linter) declare(s *scope, stmt *ast.LetStatement) {
+	name := string(stmt.Name.Value)
+
+	// redeclaring a name in the same scope overwrites the existing binding
+	if prev, ok := s.bindings[name]; ok {
+		l.report(
+			stmt.Name.Token.Pos, SHADOW,
+			"%s redeclares %s from line %d, blocks don't create a new scope",
+			name, name, prev.name.Token.Pos.Line,
+		)
+		return
+	}
+	if prev, ok := s.lookup(name); ok {
+		l.report(
+			stmt.Name.Token.Pos, SHADOW,
+			"%s shadows %s declared at line %d",
+			name, name, prev.name.Token.Pos.Line,
+		)
+	}
+
+	_, isFunction := stmt.Value.(*ast.FunctionLiteral)
+	b := &binding{name: stmt.Name, function: isFunction}
+	s.bindings[name] = b
+	s.all = append(s.all, b)
+}
+
+func (l *linter) checkExpression(s *scope, exp ast.Expression) {
+	switch exp := exp.(type) {
+	case *ast.Identifier:
+		if b, ok := s.lookup(string(exp.Value)); ok {
+			b.used = true
+		}
+	case *ast.PrefixExpression:
+		l.checkExpression(s, exp.Right)
+	case *ast.InfixExpression:
+		l.checkComparison(exp)
+		l.checkExpression(s, exp.Left)
+		l.checkExpression(s, exp.Right)
+	case *ast.IfExpression:
+		l.checkCondition(exp.Token.Pos, "if", exp.Condition)
+		l.checkExpression(s, exp.Condition)
+		l.checkStatements(s, exp.Consequence.Statements)
+		if exp.Alternative != nil {
+			l.checkStatements(s, exp.Alternative.Statements)
+		}
+	case *ast.WhileExpression:
+		l.checkCondition(exp.Token.Pos, "while", exp.Condition)
+		l.checkExpression(s, exp.Condition)
+		l.checkStatements(s, exp.Consequence.Statements)
+	case *ast.FunctionLiteral:
+		s.functions = append(s.functions, exp)
+	case *ast.CallExpression:
+		l.checkArity(s, exp)
+		l.checkExpression(s, exp.Function)
+		for _, arg := range exp.Arguments {
+			l.checkExpression(s, arg)
+		}
+	case *ast.ArrayLiteral:
+		for _, el := range exp.Elements {
+			l.checkExpression(s, el)
+		}
+	case *ast.IndexExpression:
+		l.checkExpression(s, exp.Left)
+		l.checkExpression(s, exp.Index)
+	case *ast.SliceExpression:
+		l.checkExpression(s, exp.Left)
+		l.checkExpression(s, exp.Start)
+		l.checkExpression(s, exp.End)
+		l.checkExpression(s, exp.Step)
+	case *ast.HashLiteral:
+		for key, value := range exp.Pairs {
+			l.checkExpression(s, key)
+			l.checkExpression(s, value)
+		}
+	}
+}
+
+func (l *linter) checkArity(s *scope, call *ast.CallExpression) {
+	ident, ok := call.Function.(*ast.Identifier)
+	if !ok {
+		return
+	}
+	name := string(ident.Value)
+	// a binding with the same name takes precedence over the builtin
+	if _, ok := s.lookup(name); ok {
+		return
+	}
+	builtin, ok := evaluator.LookupBuiltin(name)
+	if !ok {
+		return
+	}
+
+	n := len(call.Arguments)
+	switch {
+	case builtin.MaxArgs == object.VARIADIC && n < builtin.MinArgs:
+		l.report(ident.Token.Pos, ARITY, "%s expects at least %d arguments, got %d", name, builtin.MinArgs, n)
+	case builtin.MaxArgs != object.VARIADIC && (n < builtin.MinArgs || n > builtin.MaxArgs):
+		if builtin.MinArgs == builtin.MaxArgs {
+			l.report(ident.Token.Pos, ARITY, "%s expects %d arguments, got %d", name, builtin.MinArgs, n)
+		} else {
+			l.report(
+				ident.Token.Pos, ARITY,
+				"%s expects %d to %d arguments, got %d", name, builtin.MinArgs, builtin.MaxArgs, n,
+			)
+		}
+	}
+}
+
+func (l *linter) checkComparison(exp *ast.InfixExpression) {
+	switch exp.Operator {
+	case "==", "!=", "<", ">":
+	default:
+		return
+	}
+
+	left, right := literalType(exp.Left), literalType(exp.Right)
+	if left == "" || right == "" || left == right {
+		return
+	}
+	// only equality is defined between values of different types
+	if exp.Operator == "<" || exp.Operator == ">" {
+		l.report(exp.Token.Pos, MIXED_COMPARE, "comparison of %s with %s fails with a type mismatch", left, right)
+		return
+	}
+	l.report(exp.Token.Pos, MIXED_COMPARE, "comparison of %s with %s is always %t",
+		left, right, exp.Operator == "!=")
+}
+
+func (l *linter) checkCondition(pos token.Position, keyword string, condition ast.Expression) {
+	if isConstant(condition) {
+		l.report(pos, CONSTANT_CONDITION, "%s condition %s is constant", keyword, condition.String())
+	}
+}
+
+// literalType returns the type of exp if it's a literal, else an empty string.
+func literalType(exp ast.Expression) string {
+	switch exp.(type) {
+	case *ast.IntegerLiteral:
+		return "integer"
+	case *ast.StringLiteral:
+		return "string"
+	case *ast.Boolean:
+		return "boolean"
+	case *ast.ArrayLiteral:
+		return "array"
+	case *ast.HashLiteral:
+		return "hash"
+	case *ast.FunctionLiteral:
+		return "function"
+	default:
+		return ""
+	}
+}
+
+// isConstant reports whether exp is made only of integer, string and boolean
+// literals, so it always evaluates to the same value.
+func isConstant(exp ast.Expression) bool {
+	switch exp := exp.(type) {
+	case *ast.IntegerLiteral, *ast.StringLiteral, *ast.Boolean:
+		return true
+	case *ast.PrefixExpression:
+		return isConstant(exp.Right)
+	case *ast.InfixExpression:
+		return isConstant(exp.Left) && isConstant(exp.Right)
+	default:
+		return false
+	}
+}
@@ -0,0 +1,140 @@
+package lint_test
+
+import (
+	"testing"
+
+	"github.com/gkampitakis/monkey/lexer"
+	"github.com/gkampitakis/monkey/lint"
+	"github.com/gkampitakis/monkey/parser"
+	"github.com/stretchr/testify/require"
+)
+
+func testLint(t *testing.T, input string, cfg lint.Config) []string {
+	t.Helper()
+
+	p := parser.New(lexer.New([]byte(input)))
+	program := p.ParseProgram()
+	require.Empty(t, p.Errors())
+
+	diagnostics := []string{}
+	for _, d := range lint.Lint([]byte(input), program, cfg) {
+		diagnostics = append(diagnostics, d.String())
+	}
+
+	return diagnostics
+}
+
+func TestRules(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{
+			"unused bindings",
+			`let add = fn(a, b) { let c = 1; a + b };
+let unused = 5;
+let _ignored = 6;
+add(1, 2);`,
+			[]string{
+				"1:26: c is declared but never used (unused)",
+				"2:5: unused is declared but never used (unused)",
+			},
+		},
+		{
+			"functions can refer to bindings declared later",
+			`let f = fn() { g() };
+let g = fn() { 1 };
+let x = 5;
+let h = fn() { x };`,
+			[]string{},
+		},
+		{
+			"redeclared names",
+			`let i = 2;
+while (i < 10) {
+  let i = i + 1;
+}`,
+			[]string{"3:7: i redeclares i from line 1, blocks don't create a new scope (shadow)"},
+		},
+		{
+			"shadowed names",
+			`let x = 1;
+let f = fn() { let x = 2; x };
+f() + x;`,
+			[]string{"2:20: x shadows x declared at line 1 (shadow)"},
+		},
+		{
+			"unreachable statements",
+			`let f = fn(a) {
+  return a;
+  print(a);
+  print(a);
+};
+f(1);`,
+			[]string{"3:3: unreachable statement after return (unreachable)"},
+		},
+		{
+			"builtin arity",
+			`len(); len("a", "b"); push(); exit(1, 2); print(); let first = fn() { 1 }; first();`,
+			[]string{
+				"1:1: len expects 1 arguments, got 0 (arity)",
+				"1:8: len expects 1 arguments, got 2 (arity)",
+				"1:23: push expects at least 1 arguments, got 0 (arity)",
+				"1:31: exit expects 0 to 1 arguments, got 2 (arity)",
+			},
+		},
+		{
+			"mixed comparisons",
+			`1 == "1"; "a" != true; 1 < 2; [1] == {}; 1 > "a";`,
+			[]string{
+				"1:3: comparison of integer with string is always false (mixed-compare)",
+				"1:15: comparison of string with boolean is always true (mixed-compare)",
+				"1:35: comparison of array with hash is always false (mixed-compare)",
+				"1:44: comparison of integer with string fails with a type mismatch (mixed-compare)",
+			},
+		},
+		{
+			"constant conditions",
+			`let x = 1;
+if (true) { x }
+if (1 + 2 > 2) { x }
+while (!false) { exit() }
+if (x > 0) { x }`,
+			[]string{
+				"2:1: if condition true is constant (constant-condition)",
+				"3:1: if condition ((1 + 2) > 2) is constant (constant-condition)",
+				"4:1: while condition (!false) is constant (constant-condition)",
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, testLint(t, tc.input, lint.Config{}))
+		})
+	}
+}
+
+func TestSuppression(t *testing.T) {
+	input := `let a = 1; // lint:ignore unused
+// lint:ignore
+let b = 1 == "1";
+let c = "// lint:ignore";
+let d = 1; // lint:ignore shadow`
+
+	require.Equal(t, []string{
+		"4:5: c is declared but never used (unused)",
+		"5:5: d is declared but never used (unused)",
+	}, testLint(t, input, lint.Config{}))
+}
+
+func TestDisabledRules(t *testing.T) {
+	input := `let a = 1; if (true) { 1 }`
+
+	require.Equal(
+		t,
+		[]string{"1:12: if condition true is constant (constant-condition)"},
+		testLint(t, input, lint.Config{Disabled: map[string]bool{lint.UNUSED: true}}),
+	)
+}
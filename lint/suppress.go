@@ -0,0 +1,69 @@
+package lint
+
+import (
+	"bytes"
+	"strings"
+)
+
+const IGNORE_DIRECTIVE = "lint:ignore"
+
+// ignoreSet maps lines to the rules suppressed in them, an empty set suppresses
+// every rule.
+type ignoreSet map[int]map[string]bool
+
+func (s ignoreSet) match(d Diagnostic) bool {
+	rules, ok := s[d.Pos.Line]
+	if !ok {
+		return false
+	}
+
+	return len(rules) == 0 || rules[d.Rule]
+}
+
+// suppressions finds the lint:ignore comments in src. A comment applies to its
+// own line and, when it's the only content of the line, to the next one.
+func suppressions(src []byte) ignoreSet {
+	set := ignoreSet{}
+
+	for i, line := range bytes.Split(src, []byte("\n")) {
+		comment, ok := lineComment(line)
+		if !ok {
+			continue
+		}
+
+		text := strings.TrimSpace(comment)
+		if !strings.HasPrefix(text, IGNORE_DIRECTIVE) {
+			continue
+		}
+
+		rules := map[string]bool{}
+		for _, rule := range strings.Split(strings.TrimPrefix(text, IGNORE_DIRECTIVE), ",") {
+			if rule = strings.TrimSpace(rule); rule != "" {
+				rules[rule] = true
+			}
+		}
+
+		set[i+1] = rules
+		if len(bytes.TrimSpace(line)) == len(strings.TrimSpace("//"+comment)) {
+			set[i+2] = rules
+		}
+	}
+
+	return set
+}
+
+// lineComment returns the text after "//" in line, skipping string literals.
+func lineComment(line []byte) (string, bool) {
+	inString := false
+
+	for i := 0; i < len(line); i++ {
+		switch {
+		case line[i] == '"':
+			inString = !inString
+		case !inString && line[i] == '/' && i+1 < len(line) && line[i+1] == '/':
+			return string(line[i+2:]), true
+		}
+	}
+
+	return "", false
+}
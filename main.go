@@ -2,16 +2,9 @@ package main
 
 import (
 	"fmt"
-	"log"
+	"io"
 	"os"
-	"os/user"
-
-	"github.com/gkampitakis/monkey/evaluator"
-	"github.com/gkampitakis/monkey/lexer"
-	"github.com/gkampitakis/monkey/object"
-	"github.com/gkampitakis/monkey/parser"
-	"github.com/gkampitakis/monkey/repl"
-	cli "github.com/openengineer/go-repl"
+	"sort"
 )
 
 const MONKEY_FACE = `
@@ -29,61 +22,117 @@ const MONKEY_FACE = `
 
 `
 
-func main() {
-	args := os.Args[1:]
-	if len(args) > 1 {
-		if args[0] != "run" {
-			fmt.Println("only supports 'run'")
-			os.Exit(1)
-		}
+type command struct {
+	usage       string
+	description string
+	run         func(args []string, stdio *stdio) int
+}
 
-		f, err := os.ReadFile(args[1])
-		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
-		}
+// stdio holds the streams commands read from and write to, so they can be
+// replaced in tests.
+type stdio struct {
+	in  io.Reader
+	out io.Writer
+	err io.Writer
+}
 
-		l := lexer.New(f)
-		p := parser.New(l)
-		program := p.ParseProgram()
-		if len(p.Errors()) != 0 {
-			printParserErrors(p.Errors())
-			os.Exit(1)
-		}
+var commands map[string]command
 
-		evaluated := evaluator.Eval(program, object.NewEnvironment())
-		if evaluated != nil && evaluated.Type() != object.NULL {
-			fmt.Println(evaluated)
-		}
-		os.Exit(0)
-	} else {
-		fmt.Println("should run monkey run <file>")
-		return
+func init() {
+	// commands is initialized here as the help command refers back to it
+	commands = map[string]command{
+		"repl": {
+			usage:       "repl",
+			description: "start an interactive session",
+			run:         runRepl,
+		},
+		"run": {
+			usage:       "run [flags] <file|-> [args...]",
+			description: "evaluate a monkey script, - reads it from stdin",
+			run:         runRun,
+		},
+		"eval": {
+			usage:       "eval [--no-opt] -e <expr>",
+			description: "evaluate an expression and print its result",
+			run:         runEval,
+		},
+		"check": {
+			usage:       "check [--types] <file|->...",
+			description: "parse scripts and report syntax or type errors without running them",
+			run:         runCheck,
+		},
+		"infer": {
+			usage:       "infer <file|->...",
+			description: "infer the types of the top-level bindings of scripts",
+			run:         runInfer,
+		},
+		"tokens": {
+			usage:       "tokens [-e <src>] [file|-]",
+			description: "print the tokens of a script",
+			run:         runTokens,
+		},
+		"ast": {
+			usage:       "ast [--json] [-e <src>] [file|-]",
+			description: "print the syntax tree of a script",
+			run:         runAST,
+		},
+		"lint": {
+			usage:       "lint [flags] <file|->...",
+			description: "report suspicious constructs in scripts",
+			run:         runLint,
+		},
+		"test": {
+			usage:       "test [flags] [dir]",
+			description: "run the test_* functions of the *_test.monkey files under dir",
+			run:         runTest,
+		},
+		"help": {
+			usage:       "help",
+			description: "print this message",
+			run: func(_ []string, s *stdio) int {
+				usage(s.out)
+				return 0
+			},
+		},
 	}
+}
 
-	user, err := user.Current()
-	if err != nil {
-		panic(err)
-	}
+func main() {
+	os.Exit(run(os.Args[1:], &stdio{in: os.Stdin, out: os.Stdout, err: os.Stderr}))
+}
 
-	fmt.Printf("Hello %s! This is the monkey programming language!\n", user.Username)
-	fmt.Print(MONKEY_FACE)
-	fmt.Printf("Feel free to type in monkey repl or type \"help\" for more info\n")
-	h := &repl.ReplHandler{
-		Env: object.NewEnvironment(),
+// run dispatches args to the matching command and returns the exit code.
+func run(args []string, s *stdio) int {
+	if len(args) == 0 {
+		return runRepl(nil, s)
 	}
-	h.Repl = cli.NewRepl(h)
 
-	// start the terminal loop
-	if err := h.Repl.Loop(); err != nil {
-		log.Fatal(err)
+	cmd, ok := commands[args[0]]
+	if !ok {
+		if args[0] == "-h" || args[0] == "--help" {
+			usage(s.out)
+			return 0
+		}
+
+		fmt.Fprintf(s.err, "unknown command %q\n\n", args[0])
+		usage(s.err)
+		return 2
 	}
+
+	return cmd.run(args[1:], s)
 }
 
-func printParserErrors(errors []string) {
-	fmt.Println("Woops! We ran into some monkey business here!")
-	fmt.Println(" parser errors:")
-	for _, msg := range errors {
-		fmt.Println("\t" + msg)
+func usage(w io.Writer) {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(w, "Usage: monkey <command> [arguments]")
+	fmt.Fprintln(w, "\nCommands:")
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-32s %s\n", commands[name].usage, commands[name].description)
 	}
+	fmt.Fprintln(w, "\nRun 'monkey <command> --help' for the flags of a command.")
 }
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testRun(t *testing.T, stdin string, args ...string) (string, string, int) {
+	t.Helper()
+
+	out, errOut := &bytes.Buffer{}, &bytes.Buffer{}
+	code := run(args, &stdio{in: strings.NewReader(stdin), out: out, err: errOut})
+
+	return out.String(), errOut.String(), code
+}
+
+func writeScript(t *testing.T, content string) string {
+	t.Helper()
+
+	file := filepath.Join(t.TempDir(), "script.monkey")
+	require.NoError(t, os.WriteFile(file, []byte(content), 0o644))
+
+	return file
+}
+
+func TestEval(t *testing.T) {
+	out, _, code := testRun(t, "", "eval", "-e", "1 + 2 * 3")
+	require.Equal(t, 0, code)
+	require.Equal(t, "7\n", out)
+
+	_, errOut, code := testRun(t, "", "eval", "-e", "1 + true")
+	require.Equal(t, 1, code)
+	require.Equal(t, "[error]: type mismatch: INTEGER + BOOLEAN\n", errOut)
+}
+
+func TestRun(t *testing.T) {
+	t.Run("doesn't print the last expression", func(t *testing.T) {
+		out, _, code := testRun(t, "", "run", writeScript(t, "let a = 5; a * 2"))
+		require.Equal(t, 0, code)
+		require.Equal(t, "", out)
+	})
+
+	t.Run("reads from stdin", func(t *testing.T) {
+		_, errOut, code := testRun(t, "let a = ", "run", "-")
+		require.Equal(t, 1, code)
+		require.Contains(t, errOut, "parser errors in -:")
+	})
+
+	t.Run("exposes args and env", func(t *testing.T) {
+		t.Setenv("MONKEY_GREETING", "hello")
+		script := writeScript(t, `#!/usr/bin/env monkey
+if (env["MONKEY_GREETING"] == "hello") { exit(len(args)) }`)
+
+		_, _, code := testRun(t, "", "run", script, "a", "b")
+		require.Equal(t, 2, code)
+	})
+
+	t.Run("exits non-zero on errors", func(t *testing.T) {
+		_, errOut, code := testRun(t, "", "run", writeScript(t, "let a = 5; a + missing;"))
+		require.Equal(t, 1, code)
+		require.Equal(t, "[error]: identifier not found: missing\n", errOut)
+	})
+
+	t.Run("profiles function calls", func(t *testing.T) {
+		folded := filepath.Join(t.TempDir(), "out.txt")
+		script := writeScript(t, "let fib = fn(n) { if (n < 2) { return n }; fib(n - 1) + fib(n - 2) }; fib(5);")
+
+		_, errOut, code := testRun(t, "", "run", "--profile="+folded, script)
+		require.Equal(t, 0, code)
+		require.Contains(t, errOut, "fib@1:11")
+
+		content, err := os.ReadFile(folded)
+		require.NoError(t, err)
+		require.Contains(t, string(content), "main;fib@1:11;fib@1:11 ")
+	})
+
+	t.Run("gates file system access", func(t *testing.T) {
+		dir := t.TempDir()
+		script := writeScript(t, `let file = path.join(args[0], "out.txt");
+write_file(file, "hello");
+exit(len(read_file(file)));`)
+
+		_, errOut, code := testRun(t, "", "run", script, dir)
+		require.Equal(t, 1, code)
+		require.Equal(t, "[error]: `write_file` needs file system access, run with --allow-fs\n", errOut)
+
+		_, _, code = testRun(t, "", "run", "--allow-fs", script, dir)
+		require.Equal(t, 5, code)
+
+		_, _, code = testRun(t, "", "run", "--allow-fs="+dir, script, dir)
+		require.Equal(t, 5, code)
+
+		_, errOut, code = testRun(t, "", "run", "--allow-fs="+dir, script, filepath.Dir(dir))
+		require.Equal(t, 1, code)
+		require.Contains(t, errOut, "is outside of")
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		_, errOut, code := testRun(t, "", "run", "missing.monkey")
+		require.Equal(t, 1, code)
+		require.Contains(t, errOut, "no such file or directory")
+	})
+}
+
+func TestCheck(t *testing.T) {
+	valid := writeScript(t, "let a = fn(x) { x };")
+	invalid := writeScript(t, "let = 5;")
+
+	_, _, code := testRun(t, "", "check", valid)
+	require.Equal(t, 0, code)
+
+	_, errOut, code := testRun(t, "", "check", valid, invalid)
+	require.Equal(t, 1, code)
+	require.Contains(t, errOut, "parser errors in "+invalid)
+}
+
+func TestTokensAndAST(t *testing.T) {
+	out, _, code := testRun(t, "", "tokens", "-e", "let a = 5;")
+	require.Equal(t, 0, code)
+	require.Equal(t, "[LET]=>\"let\"\n[IDENT]=>\"a\"\n[ASSIGN]=>\"=\"\n[INT]=>\"5\"\n[SEMICOLON]=>\";\"\n", out)
+
+	out, _, code = testRun(t, "1 != x", "tokens", "-")
+	require.Equal(t, 0, code)
+	require.Equal(t, "[INT]=>\"1\"\n[NEQ]=>\"!=\"\n[IDENT]=>\"x\"\n", out)
+
+	out, _, code = testRun(t, "-a", "ast", "-")
+	require.Equal(t, 0, code)
+	require.Equal(t, "Program\n  ExpressionStatement\n    PrefixExpression -\n      Right: Identifier a\n", out)
+}
+
+func TestTest(t *testing.T) {
+	junit := filepath.Join(t.TempDir(), "report.xml")
+
+	out, _, code := testRun(t, "", "test", "-junit", junit, filepath.Join("tester", "testdata"))
+	require.Equal(t, 1, code)
+	require.Contains(t, out, "--- FAIL: test_add_fails")
+	require.Contains(t, out, "tester/testdata/math_test.monkey:12:3: assertion failed: expected 5, got 4")
+	require.Contains(t, out, "FAIL: 5 passed, 1 failed")
+	require.FileExists(t, junit)
+
+	out, _, code = testRun(t, "", "test", "-run", "add$|second", filepath.Join("tester", "testdata"))
+	require.Equal(t, 0, code)
+	require.Equal(t, "PASS: 2 passed\n", out)
+}
+
+func TestCover(t *testing.T) {
+	dir := t.TempDir()
+	profile, html := filepath.Join(dir, "cover.out"), filepath.Join(dir, "cover.html")
+
+	_, errOut, code := testRun(
+		t, "", "test", "-run", "add$", "-coverprofile", profile, "-coverhtml", html,
+		filepath.Join("tester", "testdata", "math_test.monkey"),
+	)
+	require.Equal(t, 0, code)
+	require.Contains(t, errOut, "math_test.monkey: statements")
+	require.FileExists(t, profile)
+	require.FileExists(t, html)
+
+	_, errOut, code = testRun(t, "", "run", "--cover", writeScript(t, "if (1 > 2) { 1 } else { 2 }"))
+	require.Equal(t, 0, code)
+	require.Contains(t, errOut, "script.monkey: statements 66.7% (2/3), branches 50.0% (1/2)")
+}
+
+func TestLint(t *testing.T) {
+	out, _, code := testRun(t, "", "lint", filepath.Join("examples", "hello-world.monkey"))
+	require.Equal(t, 1, code)
+	require.Equal(
+		t,
+		"examples/hello-world.monkey:11:7: i redeclares i from line 9, blocks don't create a new scope (shadow)\n",
+		out,
+	)
+
+	out, _, code = testRun(t, "", "lint", "-disable", "shadow", filepath.Join("examples", "hello-world.monkey"))
+	require.Equal(t, 0, code)
+	require.Equal(t, "", out)
+
+	_, errOut, code := testRun(t, "", "lint", "-enable", "unknown", filepath.Join("examples", "hello-world.monkey"))
+	require.Equal(t, 2, code)
+	require.Equal(t, "unknown rule \"unknown\"\n", errOut)
+}
+
+func TestHelp(t *testing.T) {
+	_, errOut, code := testRun(t, "", "run", "--help")
+	require.Equal(t, 0, code)
+	require.Contains(t, errOut, "Usage: monkey run [flags] <file|-> [args...]")
+
+	_, errOut, code = testRun(t, "", "unknown")
+	require.Equal(t, 2, code)
+	require.Contains(t, errOut, "unknown command \"unknown\"")
+}
+
+func TestCheckTypes(t *testing.T) {
+	script := writeScript(t, "let x: int = \"five\";\nlet y = x + 1;")
+
+	_, _, code := testRun(t, "", "check", script)
+	require.Equal(t, 0, code)
+
+	_, errOut, code := testRun(t, "", "check", "--types", script)
+	require.Equal(t, 1, code)
+	require.Equal(t, script+":1:14: cannot use string as int in let x\n", errOut)
+}
+
+func TestInfer(t *testing.T) {
+	script := writeScript(t, "let id = fn(x) { x };\nlet n = id(1) + \"a\";")
+
+	out, errOut, code := testRun(t, "", "infer", script)
+	require.Equal(t, 1, code)
+	require.Equal(t, "id: a -> a\nn: int\n", out)
+	require.Equal(t, script+":2:17: mismatched types string and int (conflicts with 2:12)\n", errOut)
+}
+
+func TestNoOpt(t *testing.T) {
+	out, _, code := testRun(t, "", "eval", "-e", "if (true) { 60 * 60 } else { 0 }")
+	require.Equal(t, 0, code)
+	require.Equal(t, "3600\n", out)
+
+	out, _, code = testRun(t, "", "eval", "--no-opt", "-e", "if (true) { 60 * 60 } else { 0 }")
+	require.Equal(t, 0, code)
+	require.Equal(t, "3600\n", out)
+
+	script := writeScript(t, "let f = fn(x) { x * 2 };\nexit(f(21) - 40);")
+	_, _, code = testRun(t, "", "run", script)
+	require.Equal(t, 2, code)
+	_, _, code = testRun(t, "", "run", "--no-opt", script)
+	require.Equal(t, 2, code)
+}
+
+func TestASTJSON(t *testing.T) {
+	out, _, code := testRun(t, "", "ast", "--json", "-e", "x;")
+	require.Equal(t, 0, code)
+	require.JSONEq(t, `{
+  "kind": "Program",
+  "statements": [
+    {
+      "kind": "ExpressionStatement",
+      "pos": {"line": 1, "column": 1},
+      "expression": {"kind": "Identifier", "pos": {"line": 1, "column": 1}, "value": "x"}
+    }
+  ]
+}`, out)
+}
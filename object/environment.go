@@ -1,5 +1,7 @@
 package object
 
+import "sort"
+
 type Environment struct {
 	store map[string]Object
 	outer *Environment
@@ -27,7 +29,7 @@ func (e *Environment) Get(name string) (Object, bool) {
 			return o, true
 		}
 
-		env = e.outer
+		env = env.outer
 	}
 
 	return nil, false
@@ -37,3 +39,23 @@ func (e *Environment) Set(name string, val Object) Object {
 	e.store[name] = val
 	return val
 }
+
+// Names returns the sorted names of all bindings visible from the environment,
+// including the ones of enclosing environments.
+func (e *Environment) Names() []string {
+	seen := make(map[string]struct{})
+	names := []string{}
+
+	for env := e; env != nil; env = env.outer {
+		for name := range env.store {
+			if _, ok := seen[name]; ok {
+				continue
+			}
+			seen[name] = struct{}{}
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	return names
+}
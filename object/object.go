@@ -3,13 +3,18 @@ package object
 import (
 	"fmt"
 	"hash/fnv"
+	"math/big"
 	"strings"
+	"time"
 
 	"github.com/gkampitakis/monkey/ast"
+	"github.com/gkampitakis/monkey/token"
 )
 
 var (
 	_ Object   = (*Integer)(nil)
+	_ Object   = (*BigInt)(nil)
+	_ Object   = (*Float)(nil)
 	_ Object   = (*Boolean)(nil)
 	_ Object   = (*Null)(nil)
 	_ Object   = (*ReturnValue)(nil)
@@ -19,14 +24,21 @@ var (
 	_ Object   = (*Array)(nil)
 	_ Object   = (*Builtin)(nil)
 	_ Object   = (*Hash)(nil)
+	_ Object   = (*Exit)(nil)
+	_ Object   = (*Time)(nil)
+	_ Object   = (*Duration)(nil)
 	_ Hashable = (*Boolean)(nil)
 	_ Hashable = (*String)(nil)
 	_ Hashable = (*Integer)(nil)
+	_ Hashable = (*BigInt)(nil)
 )
 
 type BuiltinFunction func(args ...Object) Object
 
+// Hashable objects can be used as hash keys. Keys with equal HashKey can still
+// differ, Hash compares the keys themselves to tell them apart.
 type Hashable interface {
+	Object
 	HashKey() HashKey
 }
 
@@ -35,6 +47,8 @@ type ObjectType uint8
 
 const (
 	INTEGER ObjectType = iota
+	BIG_INTEGER
+	FLOAT
 	BOOLEAN
 	NULL
 	RETURN_VALUE
@@ -44,6 +58,9 @@ const (
 	BUILTIN
 	ARRAY
 	HASH
+	TIME
+	DURATION
+	EXIT
 )
 
 type Object interface {
@@ -59,6 +76,31 @@ func (*Integer) Type() ObjectType   { return INTEGER }
 func (i *Integer) Inspect() string  { return fmt.Sprint(i.Value) }
 func (i *Integer) HashKey() HashKey { return HashKey{Type: i.Type(), Value: i.Value} }
 
+// BigInt is an integer that doesn't fit an Integer. The evaluator promotes
+// integer results that overflow to a BigInt and demotes them back when they fit.
+// A BigInt holding a value that fits is still the same hash key as the Integer.
+type BigInt struct {
+	Value *big.Int
+}
+
+func (*BigInt) Type() ObjectType  { return BIG_INTEGER }
+func (b *BigInt) Inspect() string { return b.Value.String() }
+
+func (b *BigInt) HashKey() HashKey {
+	if b.Value.IsInt64() {
+		return HashKey{Type: INTEGER, Value: int(b.Value.Int64())}
+	}
+
+	return HashKey{Type: INTEGER, Value: HashString(b.Value.String())}
+}
+
+type Float struct {
+	Value float64
+}
+
+func (*Float) Type() ObjectType  { return FLOAT }
+func (f *Float) Inspect() string { return ast.FormatFloat(f.Value) }
+
 type Boolean struct {
 	Value bool
 }
@@ -87,15 +129,30 @@ func (r *ReturnValue) Inspect() string { return r.Value.Inspect() }
 
 type ErrorValue struct {
 	Message string
+	// Pos is the location of the call that raised the error, if known
+	Pos token.Position
 }
 
 func (*ErrorValue) Type() ObjectType  { return ERROR_VALUE }
 func (r *ErrorValue) Inspect() string { return "[error]: " + r.Message }
 
+// Exit is produced by the exit builtin, it unwinds the evaluation like an error
+// and carries the status code the program should exit with.
+type Exit struct {
+	Code int
+}
+
+func (*Exit) Type() ObjectType  { return EXIT }
+func (e *Exit) Inspect() string { return fmt.Sprintf("exit(%d)", e.Code) }
+
 type Function struct {
 	Parameters []*ast.Identifier
 	Body       *ast.BlockStatement
 	Env        *Environment
+	// Name is the name the function was first bound to, empty for anonymous functions
+	Name string
+	// Pos is the location of the function literal
+	Pos token.Position
 }
 
 func (*Function) Type() ObjectType { return FUNCTION }
@@ -113,17 +170,32 @@ type String struct {
 	Value string
 }
 
-func (*String) Type() ObjectType  { return STRING }
-func (s *String) Inspect() string { return s.Value }
-func (s *String) HashKey() HashKey {
+func (*String) Type() ObjectType   { return STRING }
+func (s *String) Inspect() string  { return s.Value }
+func (s *String) HashKey() HashKey { return HashKey{Type: s.Type(), Value: HashString(s.Value)} }
+
+// HashString returns the hash of string keys, tests replace it to force
+// collisions.
+var HashString = func(s string) int {
 	h := fnv.New64a()
-	h.Write([]byte(s.Value))
+	h.Write([]byte(s))
 
-	return HashKey{Type: s.Type(), Value: int(h.Sum64())}
+	return int(h.Sum64())
 }
 
+// VARIADIC is the MaxArgs of builtins accepting any number of arguments.
+const VARIADIC = -1
+
 type Builtin struct {
 	Fn BuiltinFunction
+	// MinArgs and MaxArgs are the number of arguments Fn accepts, used by static
+	// checks. Fn still validates its arguments.
+	MinArgs int
+	MaxArgs int
+	// Scheme is the type of Fn used by type inference, e.g. "array<a> -> a".
+	// Optional parameters are trailing and a variadic builtin repeats its last
+	// parameter. Builtins without a scheme are dynamic.
+	Scheme string
 }
 
 func (*Builtin) Type() ObjectType { return BUILTIN }
@@ -144,6 +216,23 @@ func (a *Array) Inspect() string {
 	return fmt.Sprintf("[%s]", strings.Join(elements, ","))
 }
 
+// Time is an instant, its fields such as year are read with the index
+// operator.
+type Time struct {
+	Value time.Time
+}
+
+func (*Time) Type() ObjectType  { return TIME }
+func (t *Time) Inspect() string { return t.Value.Format(time.RFC3339Nano) }
+
+// Duration is the time elapsed between two instants.
+type Duration struct {
+	Value time.Duration
+}
+
+func (*Duration) Type() ObjectType  { return DURATION }
+func (d *Duration) Inspect() string { return d.Value.String() }
+
 type HashKey struct {
 	Type  ObjectType
 	Value int
@@ -154,17 +243,163 @@ type HashPair struct {
 	Value Object
 }
 
+// Hash maps hashable keys to values, preserving the order keys were first
+// inserted in. The zero value is an empty hash.
 type Hash struct {
-	Pairs map[HashKey]HashPair
+	pairs []HashPair
+	// buckets holds the indexes in pairs of the keys sharing a HashKey
+	buckets map[HashKey][]int
+	// removed holds the indexes in pairs of deleted keys, which stay in pairs
+	// until the next compaction so deleting doesn't move the other pairs
+	removed map[int]bool
+	frozen  bool
 }
 
 func (h *Hash) Type() ObjectType { return HASH }
 func (h *Hash) Inspect() string {
-	pairs := make([]string, 0, len(h.Pairs))
+	pairs := make([]string, h.Len())
 
-	for _, pair := range h.Pairs {
-		pairs = append(pairs, fmt.Sprintf("%q: %q", pair.Key.Inspect(), pair.Value.Inspect()))
+	for i, pair := range h.Pairs() {
+		pairs[i] = fmt.Sprintf("%q: %q", pair.Key.Inspect(), pair.Value.Inspect())
 	}
 
 	return fmt.Sprintf("{\n%s\n}", strings.Join(pairs, ",\n  "))
 }
+
+// Freeze makes h read-only, Set and Delete leave a frozen hash unchanged.
+func (h *Hash) Freeze() { h.frozen = true }
+
+// Frozen reports whether h is read-only.
+func (h *Hash) Frozen() bool { return h.frozen }
+
+// Len returns the number of pairs in h.
+func (h *Hash) Len() int { return len(h.pairs) - len(h.removed) }
+
+// Pairs returns the pairs of h in insertion order. The slice must not be
+// modified.
+func (h *Hash) Pairs() []HashPair {
+	h.compact()
+	return h.pairs
+}
+
+// Get returns the value stored under key.
+func (h *Hash) Get(key Hashable) (Object, bool) {
+	if i := h.index(key); i != -1 {
+		return h.pairs[i].Value, true
+	}
+
+	return nil, false
+}
+
+// Set stores value under key. Keys already present keep their position.
+func (h *Hash) Set(key Hashable, value Object) {
+	if h.frozen {
+		return
+	}
+	if i := h.index(key); i != -1 {
+		h.pairs[i].Value = value
+		return
+	}
+
+	if h.buckets == nil {
+		h.buckets = make(map[HashKey][]int)
+	}
+	hashed := key.HashKey()
+	h.buckets[hashed] = append(h.buckets[hashed], len(h.pairs))
+	h.pairs = append(h.pairs, HashPair{Key: key, Value: value})
+}
+
+// Delete removes key from h, reporting whether it was present.
+func (h *Hash) Delete(key Hashable) bool {
+	i := h.index(key)
+	if i == -1 || h.frozen {
+		return false
+	}
+
+	hashed := key.HashKey()
+	bucket := h.buckets[hashed]
+	for j, idx := range bucket {
+		if idx == i {
+			bucket = append(bucket[:j:j], bucket[j+1:]...)
+			break
+		}
+	}
+	if len(bucket) == 0 {
+		delete(h.buckets, hashed)
+	} else {
+		h.buckets[hashed] = bucket
+	}
+
+	if h.removed == nil {
+		h.removed = make(map[int]bool)
+	}
+	h.removed[i] = true
+	// compacting once half of the pairs are removed keeps deletes amortized O(1)
+	if 2*len(h.removed) > len(h.pairs) {
+		h.compact()
+	}
+
+	return true
+}
+
+// compact drops the removed pairs. The pairs are copied, so slices returned by
+// Pairs before stay unchanged.
+func (h *Hash) compact() {
+	if len(h.removed) == 0 {
+		return
+	}
+
+	pairs := make([]HashPair, 0, h.Len())
+	for i, pair := range h.pairs {
+		if !h.removed[i] {
+			pairs = append(pairs, pair)
+		}
+	}
+	h.pairs, h.removed = pairs, nil
+
+	h.buckets = make(map[HashKey][]int, len(pairs))
+	for i, pair := range pairs {
+		hashed := pair.Key.(Hashable).HashKey()
+		h.buckets[hashed] = append(h.buckets[hashed], i)
+	}
+}
+
+// index returns the position of key in pairs, or -1 when missing.
+func (h *Hash) index(key Hashable) int {
+	for _, i := range h.buckets[key.HashKey()] {
+		if sameKey(h.pairs[i].Key, key) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// sameKey reports whether a and b are the same hash key, it is called for keys
+// with equal HashKey.
+func sameKey(a, b Object) bool {
+	switch a := a.(type) {
+	case *String:
+		b, ok := b.(*String)
+		return ok && a.Value == b.Value
+	case *Integer:
+		switch b := b.(type) {
+		case *Integer:
+			return a.Value == b.Value
+		case *BigInt:
+			return b.Value.IsInt64() && b.Value.Int64() == int64(a.Value)
+		}
+	case *BigInt:
+		switch b := b.(type) {
+		case *Integer:
+			return sameKey(b, a)
+		case *BigInt:
+			return a.Value.Cmp(b.Value) == 0
+		}
+	case *Boolean:
+		b, ok := b.(*Boolean)
+		return ok && a.Value == b.Value
+	}
+
+	return false
+}
@@ -1,6 +1,7 @@
 package object_test
 
 import (
+	"math/big"
 	"testing"
 
 	"github.com/gkampitakis/monkey/object"
@@ -39,3 +40,139 @@ func TestIntegerHashKey(t *testing.T) {
 	require.Equal(t, two1.HashKey(), two2.HashKey())
 	require.NotEqual(t, one1.HashKey(), two1.HashKey())
 }
+
+func TestBigIntHashKey(t *testing.T) {
+	one := &object.BigInt{Value: big.NewInt(1)}
+	huge1 := &object.BigInt{Value: new(big.Int).Lsh(big.NewInt(1), 100)}
+	huge2 := &object.BigInt{Value: new(big.Int).Lsh(big.NewInt(1), 100)}
+
+	require.Equal(t, (&object.Integer{Value: 1}).HashKey(), one.HashKey())
+	require.Equal(t, huge1.HashKey(), huge2.HashKey())
+	require.NotEqual(t, one.HashKey(), huge1.HashKey())
+
+	h := &object.Hash{}
+	h.Set(&object.Integer{Value: 1}, &object.String{Value: "int"})
+	h.Set(one, &object.String{Value: "big"})
+	h.Set(huge1, &object.String{Value: "huge"})
+
+	require.Equal(t, 2, h.Len())
+	value, ok := h.Get(&object.Integer{Value: 1})
+	require.True(t, ok)
+	require.Equal(t, &object.String{Value: "big"}, value)
+	value, ok = h.Get(huge2)
+	require.True(t, ok)
+	require.Equal(t, &object.String{Value: "huge"}, value)
+}
+
+// collide makes every string key hash to the same value for the rest of t.
+func collide(t *testing.T) {
+	t.Helper()
+
+	hashString := object.HashString
+	object.HashString = func(string) int { return 42 }
+	t.Cleanup(func() { object.HashString = hashString })
+}
+
+func TestEnvironmentGet(t *testing.T) {
+	global := object.NewEnvironment()
+	global.Set("a", &object.Integer{Value: 1})
+	inner := object.NewEnclosedEnvironment(object.NewEnclosedEnvironment(global))
+	inner.Set("b", &object.Integer{Value: 2})
+
+	a, ok := inner.Get("a")
+	require.True(t, ok)
+	require.Equal(t, &object.Integer{Value: 1}, a)
+	_, ok = inner.Get("missing")
+	require.False(t, ok)
+	_, ok = global.Get("b")
+	require.False(t, ok)
+}
+
+func TestHashOrder(t *testing.T) {
+	h := &object.Hash{}
+	h.Set(&object.String{Value: "b"}, &object.Integer{Value: 1})
+	h.Set(&object.Integer{Value: 1}, &object.Integer{Value: 2})
+	h.Set(&object.String{Value: "a"}, &object.Integer{Value: 3})
+	h.Set(&object.String{Value: "b"}, &object.Integer{Value: 4})
+
+	require.Equal(t, 3, h.Len())
+	require.Equal(t, "{\n\"b\": \"4\",\n  \"1\": \"2\",\n  \"a\": \"3\"\n}", h.Inspect())
+}
+
+func TestHashFreeze(t *testing.T) {
+	a := &object.String{Value: "a"}
+	h := &object.Hash{}
+	h.Set(a, &object.Integer{Value: 1})
+	h.Freeze()
+
+	h.Set(a, &object.Integer{Value: 2})
+	h.Set(&object.String{Value: "b"}, &object.Integer{Value: 3})
+	require.False(t, h.Delete(a))
+
+	require.True(t, h.Frozen())
+	require.Equal(t, "{\n\"a\": \"1\"\n}", h.Inspect())
+}
+
+func TestHashCollisions(t *testing.T) {
+	collide(t)
+
+	a, b, c := &object.String{Value: "a"}, &object.String{Value: "b"}, &object.String{Value: "c"}
+	require.Equal(t, a.HashKey(), b.HashKey())
+
+	h := &object.Hash{}
+	h.Set(a, &object.Integer{Value: 1})
+	h.Set(b, &object.Integer{Value: 2})
+	h.Set(c, &object.Integer{Value: 3})
+	h.Set(&object.Integer{Value: 42}, &object.Integer{Value: 4})
+
+	require.Equal(t, 4, h.Len())
+	for i, key := range []object.Hashable{a, b, c} {
+		value, ok := h.Get(&object.String{Value: key.Inspect()})
+		require.True(t, ok)
+		require.Equal(t, &object.Integer{Value: i + 1}, value)
+	}
+	_, ok := h.Get(&object.String{Value: "d"})
+	require.False(t, ok)
+
+	require.True(t, h.Delete(&object.String{Value: "a"}))
+	require.False(t, h.Delete(&object.String{Value: "a"}))
+	h.Set(a, &object.Integer{Value: 5})
+
+	value, ok := h.Get(c)
+	require.True(t, ok)
+	require.Equal(t, &object.Integer{Value: 3}, value)
+	require.Equal(t, "{\n\"b\": \"2\",\n  \"c\": \"3\",\n  \"42\": \"4\",\n  \"a\": \"5\"\n}", h.Inspect())
+}
+
+func TestHashDelete(t *testing.T) {
+	h := &object.Hash{}
+	for i := 0; i < 10; i++ {
+		h.Set(&object.Integer{Value: i}, &object.Integer{Value: i * i})
+	}
+	pairs := h.Pairs()
+
+	for i := 0; i < 10; i += 2 {
+		require.True(t, h.Delete(&object.Integer{Value: i}))
+	}
+	require.Equal(t, 5, h.Len())
+	for i := 0; i < 10; i++ {
+		value, ok := h.Get(&object.Integer{Value: i})
+		require.Equal(t, i%2 == 1, ok)
+		if ok {
+			require.Equal(t, &object.Integer{Value: i * i}, value)
+		}
+	}
+	require.Len(t, h.Pairs(), 5)
+	require.Equal(t, "{\n\"1\": \"1\",\n  \"3\": \"9\",\n  \"5\": \"25\",\n  \"7\": \"49\",\n  \"9\": \"81\"\n}", h.Inspect())
+	// pairs returned before deleting are left unchanged
+	require.Len(t, pairs, 10)
+	require.Equal(t, &object.Integer{Value: 0}, pairs[0].Key)
+
+	for i := 1; i < 10; i += 2 {
+		require.True(t, h.Delete(&object.Integer{Value: i}))
+	}
+	require.Zero(t, h.Len())
+	require.Empty(t, h.Pairs())
+	h.Set(&object.Integer{Value: 3}, &object.Integer{Value: 1})
+	require.Equal(t, "{\n\"3\": \"1\"\n}", h.Inspect())
+}
@@ -9,19 +9,25 @@ func _() {
 	// Re-run the stringer command to generate them again.
 	var x [1]struct{}
 	_ = x[INTEGER-0]
-	_ = x[BOOLEAN-1]
-	_ = x[NULL-2]
-	_ = x[RETURN_VALUE-3]
-	_ = x[ERROR_VALUE-4]
-	_ = x[FUNCTION-5]
-	_ = x[STRING-6]
-	_ = x[BUILTIN-7]
-	_ = x[ARRAY-8]
+	_ = x[BIG_INTEGER-1]
+	_ = x[FLOAT-2]
+	_ = x[BOOLEAN-3]
+	_ = x[NULL-4]
+	_ = x[RETURN_VALUE-5]
+	_ = x[ERROR_VALUE-6]
+	_ = x[FUNCTION-7]
+	_ = x[STRING-8]
+	_ = x[BUILTIN-9]
+	_ = x[ARRAY-10]
+	_ = x[HASH-11]
+	_ = x[TIME-12]
+	_ = x[DURATION-13]
+	_ = x[EXIT-14]
 }
 
-const _ObjectType_name = "INTEGERBOOLEANNULLRETURN_VALUEERROR_VALUEFUNCTIONSTRINGBUILTINARRAY"
+const _ObjectType_name = "INTEGERBIG_INTEGERFLOATBOOLEANNULLRETURN_VALUEERROR_VALUEFUNCTIONSTRINGBUILTINARRAYHASHTIMEDURATIONEXIT"
 
-var _ObjectType_index = [...]uint8{0, 7, 14, 18, 30, 41, 49, 55, 62, 67}
+var _ObjectType_index = [...]uint8{0, 7, 18, 23, 30, 34, 46, 57, 65, 71, 78, 83, 87, 91, 99, 103}
 
 func (i ObjectType) String() string {
 	if i >= ObjectType(len(_ObjectType_index)-1) {
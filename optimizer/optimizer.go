@@ -0,0 +1,412 @@
+// Package optimizer rewrites monkey programs into equivalent ones that do less
+// work when evaluated. It folds constant expressions other than powers, removes branches that are
+// never taken and statements after a return, and inlines calls to small
+// non-recursive functions.
+//
+// The observable behavior of the program is preserved, expressions that would
+// fail at runtime are left untouched so errors are reported as before.
+package optimizer
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/gkampitakis/monkey/ast"
+	"github.com/gkampitakis/monkey/evaluator"
+	"github.com/gkampitakis/monkey/object"
+	"github.com/gkampitakis/monkey/token"
+)
+
+// MAX_INLINE_NODES is the size limit of the body of inlined functions.
+const MAX_INLINE_NODES = 32
+
+// Optimize rewrites program in place and returns it.
+func Optimize(program *ast.Program) *ast.Program {
+	counts := map[string]int{}
+	declarations(program, counts)
+
+	o := &optimizer{
+		declarations: counts,
+		inline:       map[string]*ast.FunctionLiteral{},
+		bound:        map[string]bool{},
+		params:       map[string]int{},
+	}
+	program.Statements = o.statements(program.Statements, true)
+
+	return program
+}
+
+type optimizer struct {
+	// declarations counts the let statements and parameters declaring each name
+	declarations map[string]int
+	// inline holds the functions that can be inlined at this point of the program
+	inline map[string]*ast.FunctionLiteral
+	// bound holds the names bound by the top-level let statements seen so far,
+	// which are defined everywhere after them
+	bound map[string]bool
+	// params counts the parameters of the enclosing functions by name
+	params map[string]int
+}
+
+// statements optimizes a list of statements, dropping the ones after a return
+// and splicing the taken branch of constant if expressions into the list, as
+// blocks don't create scopes in monkey.
+func (o *optimizer) statements(stmts []ast.Statement, toplevel bool) []ast.Statement {
+	out := make([]ast.Statement, 0, len(stmts))
+
+	for i, stmt := range stmts {
+		stmt = o.statement(stmt)
+		if toplevel {
+			o.register(stmt)
+		}
+
+		if es, ok := stmt.(*ast.ExpressionStatement); ok {
+			if block, ok := liveBranch(es.Expression); ok {
+				// the value of the list is the value of its last statement, so
+				// an empty branch can only be dropped when it isn't the last
+				if len(block.Statements) > 0 || i < len(stmts)-1 {
+					out = append(out, block.Statements...)
+					if returns(out) {
+						break
+					}
+					continue
+				}
+			}
+		}
+
+		out = append(out, stmt)
+		if returns(out) {
+			break
+		}
+	}
+
+	return out
+}
+
+func (o *optimizer) statement(stmt ast.Statement) ast.Statement {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		stmt.Value = o.expression(stmt.Value)
+	case *ast.ReturnStatement:
+		stmt.ReturnValue = o.expression(stmt.ReturnValue)
+	case *ast.ExpressionStatement:
+		stmt.Expression = o.expression(stmt.Expression)
+	case *ast.BlockStatement:
+		o.block(stmt)
+	}
+
+	return stmt
+}
+
+func (o *optimizer) block(b *ast.BlockStatement) {
+	if b != nil {
+		b.Statements = o.statements(b.Statements, false)
+	}
+}
+
+func (o *optimizer) expression(exp ast.Expression) ast.Expression {
+	switch exp := exp.(type) {
+	case *ast.PrefixExpression:
+		exp.Right = o.expression(exp.Right)
+		return fold(exp)
+	case *ast.InfixExpression:
+		exp.Left = o.expression(exp.Left)
+		exp.Right = o.expression(exp.Right)
+		return fold(exp)
+	case *ast.IfExpression:
+		exp.Condition = o.expression(exp.Condition)
+		o.block(exp.Consequence)
+		o.block(exp.Alternative)
+
+		// a taken branch made of a single expression replaces the if
+		if block, ok := liveBranch(exp); ok && len(block.Statements) == 1 {
+			if es, ok := block.Statements[0].(*ast.ExpressionStatement); ok {
+				return es.Expression
+			}
+		}
+		return exp
+	case *ast.WhileExpression:
+		exp.Condition = o.expression(exp.Condition)
+		o.block(exp.Consequence)
+		return exp
+	case *ast.FunctionLiteral:
+		for _, p := range exp.Parameters {
+			o.params[string(p.Value)]++
+		}
+		o.block(exp.Body)
+		for _, p := range exp.Parameters {
+			o.params[string(p.Value)]--
+		}
+		return exp
+	case *ast.CallExpression:
+		exp.Function = o.expression(exp.Function)
+		for i, arg := range exp.Arguments {
+			exp.Arguments[i] = o.expression(arg)
+		}
+		return o.call(exp)
+	case *ast.ArrayLiteral:
+		for i, el := range exp.Elements {
+			exp.Elements[i] = o.expression(el)
+		}
+		return exp
+	case *ast.IndexExpression:
+		exp.Left = o.expression(exp.Left)
+		exp.Index = o.expression(exp.Index)
+		return exp
+	case *ast.SliceExpression:
+		exp.Left = o.expression(exp.Left)
+		exp.Start = o.expression(exp.Start)
+		exp.End = o.expression(exp.End)
+		exp.Step = o.expression(exp.Step)
+		return exp
+	case *ast.HashLiteral:
+		pairs := make(map[ast.Expression]ast.Expression, len(exp.Pairs))
+		for key, value := range exp.Pairs {
+			pairs[o.expression(key)] = o.expression(value)
+		}
+		exp.Pairs = pairs
+		return exp
+	}
+
+	return exp
+}
+
+// register records the name bound by a top-level let statement, and makes the
+// function it binds available for inlining in the statements after it. Only
+// functions whose name is declared once in the whole program are inlined, so
+// calls can't refer to another binding.
+func (o *optimizer) register(stmt ast.Statement) {
+	let, ok := stmt.(*ast.LetStatement)
+	if !ok {
+		return
+	}
+	o.bound[string(let.Name.Value)] = true
+	fn, ok := let.Value.(*ast.FunctionLiteral)
+	if !ok || o.declarations[string(let.Name.Value)] != 1 || len(fn.Body.Statements) != 1 {
+		return
+	}
+	body, ok := fn.Body.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		return
+	}
+
+	params := make(map[string]bool, len(fn.Parameters))
+	for _, p := range fn.Parameters {
+		params[string(p.Value)] = true
+	}
+	if n, ok := size(body.Expression, params); ok && n <= MAX_INLINE_NODES {
+		o.inline[string(let.Name.Value)] = fn
+	}
+}
+
+// call replaces a call to an inlinable function with its body, the parameters
+// replaced by the arguments. Arguments must be literals or names known to be
+// defined, as evaluating those can't fail or have side effects, so dropping,
+// repeating or reordering them doesn't change the behavior of the program.
+func (o *optimizer) call(call *ast.CallExpression) ast.Expression {
+	ident, ok := call.Function.(*ast.Identifier)
+	if !ok {
+		return call
+	}
+	fn, ok := o.inline[string(ident.Value)]
+	if !ok || len(call.Arguments) < len(fn.Parameters) {
+		return call
+	}
+	for _, arg := range call.Arguments {
+		if !isLiteral(arg) && !o.defined(arg) {
+			return call
+		}
+	}
+
+	args := make(map[string]ast.Expression, len(fn.Parameters))
+	for i, p := range fn.Parameters {
+		args[string(p.Value)] = call.Arguments[i]
+	}
+	body := fn.Body.Statements[0].(*ast.ExpressionStatement).Expression
+
+	return o.expression(substitute(body, args))
+}
+
+// defined reports whether exp is a name that is defined wherever it's
+// evaluated: a parameter of an enclosing function or a name bound by a
+// top-level let statement before it.
+func (o *optimizer) defined(exp ast.Expression) bool {
+	ident, ok := exp.(*ast.Identifier)
+	if !ok {
+		return false
+	}
+	name := string(ident.Value)
+
+	return o.params[name] > 0 || o.bound[name]
+}
+
+// size returns the number of nodes of exp, it fails if exp contains anything
+// other than literals, params and operators. Such expressions don't depend on
+// the environment and can't call other functions.
+func size(exp ast.Expression, params map[string]bool) (int, bool) {
+	switch exp := exp.(type) {
+	case *ast.IntegerLiteral, *ast.FloatLiteral, *ast.StringLiteral, *ast.Boolean:
+		return 1, true
+	case *ast.Identifier:
+		return 1, params[string(exp.Value)]
+	case *ast.PrefixExpression:
+		n, ok := size(exp.Right, params)
+		return n + 1, ok
+	case *ast.InfixExpression:
+		left, ok := size(exp.Left, params)
+		if !ok {
+			return 0, false
+		}
+		right, ok := size(exp.Right, params)
+		return left + right + 1, ok
+	}
+
+	return 0, false
+}
+
+// substitute returns a copy of exp, as accepted by size, with the params
+// replaced by args.
+func substitute(exp ast.Expression, args map[string]ast.Expression) ast.Expression {
+	switch exp := exp.(type) {
+	case *ast.Identifier:
+		return args[string(exp.Value)]
+	case *ast.PrefixExpression:
+		return &ast.PrefixExpression{
+			Token:    exp.Token,
+			Operator: exp.Operator,
+			Right:    substitute(exp.Right, args),
+		}
+	case *ast.InfixExpression:
+		return &ast.InfixExpression{
+			Token:    exp.Token,
+			Operator: exp.Operator,
+			Left:     substitute(exp.Left, args),
+			Right:    substitute(exp.Right, args),
+		}
+	}
+
+	// literals are never modified, so they can be shared
+	return exp
+}
+
+// fold evaluates a prefix or infix expression whose operands are literals and
+// returns the result as a literal. Expressions that fail are returned as is.
+// Powers are never folded, their result can be far larger than the program
+// and the expression may never be evaluated.
+func fold(exp ast.Expression) ast.Expression {
+	switch e := exp.(type) {
+	case *ast.PrefixExpression:
+		if !isLiteral(e.Right) {
+			return exp
+		}
+	case *ast.InfixExpression:
+		if !isLiteral(e.Left) || !isLiteral(e.Right) || e.Operator == "**" {
+			return exp
+		}
+	}
+
+	return literal(evaluator.Eval(exp, object.NewEnvironment()), ast.Pos(exp), exp)
+}
+
+func truthy(lit ast.Expression) bool {
+	return evaluator.IsTruthy(evaluator.Eval(lit, object.NewEnvironment()))
+}
+
+// literal returns the literal evaluating to obj, or fallback when obj isn't an
+// integer, finite float, string or boolean.
+func literal(obj object.Object, pos token.Position, fallback ast.Expression) ast.Expression {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		return &ast.IntegerLiteral{
+			Token: token.Token{Type: token.INT, Literal: []byte(strconv.Itoa(obj.Value)), Pos: pos},
+			Value: obj.Value,
+		}
+	case *object.BigInt:
+		return &ast.IntegerLiteral{
+			Token: token.Token{Type: token.INT, Literal: []byte(obj.Inspect()), Pos: pos},
+			Big:   obj.Value,
+		}
+	case *object.Float:
+		if math.IsInf(obj.Value, 0) || math.IsNaN(obj.Value) {
+			return fallback
+		}
+		return &ast.FloatLiteral{
+			Token: token.Token{Type: token.FLOAT, Literal: []byte(obj.Inspect()), Pos: pos},
+			Value: obj.Value,
+		}
+	case *object.String:
+		return &ast.StringLiteral{
+			Token: token.Token{Type: token.STRING_QUOTE, Literal: []byte(obj.Value), Pos: pos},
+			Value: obj.Value,
+		}
+	case *object.Boolean:
+		tok := token.Token{Type: token.FALSE, Literal: []byte("false"), Pos: pos}
+		if obj.Value {
+			tok = token.Token{Type: token.TRUE, Literal: []byte("true"), Pos: pos}
+		}
+		return &ast.Boolean{Token: tok, Value: obj.Value}
+	}
+
+	return fallback
+}
+
+func isLiteral(exp ast.Expression) bool {
+	switch exp.(type) {
+	case *ast.IntegerLiteral, *ast.FloatLiteral, *ast.StringLiteral, *ast.Boolean:
+		return true
+	}
+
+	return false
+}
+
+// liveBranch returns the block evaluated by an if or while expression with a
+// literal condition.
+func liveBranch(exp ast.Expression) (*ast.BlockStatement, bool) {
+	switch exp := exp.(type) {
+	case *ast.IfExpression:
+		if !isLiteral(exp.Condition) {
+			return nil, false
+		}
+		if truthy(exp.Condition) {
+			return exp.Consequence, true
+		}
+		if exp.Alternative != nil {
+			return exp.Alternative, true
+		}
+		return &ast.BlockStatement{Token: exp.Token}, true
+	case *ast.WhileExpression:
+		if !isLiteral(exp.Condition) || truthy(exp.Condition) {
+			return nil, false
+		}
+		return &ast.BlockStatement{Token: exp.Token}, true
+	}
+
+	return nil, false
+}
+
+// returns reports whether the last of stmts is a return statement.
+func returns(stmts []ast.Statement) bool {
+	if len(stmts) == 0 {
+		return false
+	}
+	_, ok := stmts[len(stmts)-1].(*ast.ReturnStatement)
+
+	return ok
+}
+
+// declarations counts the let statements and parameters declaring each name
+// under node.
+func declarations(node ast.Node, counts map[string]int) {
+	ast.Inspect(node, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.LetStatement:
+			counts[string(node.Name.Value)]++
+		case *ast.FunctionLiteral:
+			for _, p := range node.Parameters {
+				counts[string(p.Value)]++
+			}
+		}
+
+		return true
+	})
+}
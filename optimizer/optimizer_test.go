@@ -0,0 +1,141 @@
+package optimizer_test
+
+import (
+	"testing"
+
+	"github.com/gkampitakis/monkey/ast"
+	"github.com/gkampitakis/monkey/evaluator"
+	"github.com/gkampitakis/monkey/lexer"
+	"github.com/gkampitakis/monkey/object"
+	"github.com/gkampitakis/monkey/optimizer"
+	"github.com/gkampitakis/monkey/parser"
+	"github.com/stretchr/testify/require"
+)
+
+func parse(t *testing.T, input string) *ast.Program {
+	t.Helper()
+
+	p := parser.New(lexer.New([]byte(input)))
+	program := p.ParseProgram()
+	require.Empty(t, p.Errors())
+
+	return program
+}
+
+func TestOptimize(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"integer folding", "60 * 60 * 24;", "86400"},
+		{"prefix folding", "-(2 + 3); !true; !0;", "-5falsetrue"},
+		{"string folding", `"a" + "b" == "ab";`, "true"},
+		{"boolean folding", "true != false;", "true"},
+		{"partial folding", "x + 2 * 3;", "(x + 6)"},
+		{"errors are kept", `"a" + 1; -true; 1 / 0; 2 ** -1;`, "(a + 1)(-true)(1 / 0)(2 ** -1)"},
+		{"big integer folding", "18446744073709551615 + 2; 9223372036854775807 * 2;", "1844674407370955161718446744073709551614"},
+		{"float folding", "0.5 * 3; 1.5 + 1.5; 1.0 / 0;", "1.53.0(1.0 / 0)"},
+		{"powers are kept", "2 ** 10; 2 ** 9223372036854775807;", "(2 ** 10)(2 ** 9223372036854775807)"},
+		{"mixed comparison", `1 == "1";`, "false"},
+		{"taken branch", "if (1 < 2) { x } else { y };", "x"},
+		{"else branch", "if (false) { x } else { y };", "y"},
+		{"spliced branch", "if (true) { let a = 1; a }; a;", "let a = 1;aa"},
+		{"empty branch", "if (false) { x }; y;", "y"},
+		{"empty last branch", "if (false) { x };", "iffalse x"},
+		{"dead loop", "while (false) { x }; y;", "y"},
+		{"unknown condition", "if (x) { 1 + 1 } else { 2 };", "ifx 2else2"},
+		{"after return", "let f = fn() { return 1; 2; 3 };", "let f = fn()return 1;;"},
+		{"after spliced return", "let f = fn() { if (true) { return 1; } 2 };", "let f = fn()return 1;;"},
+		{
+			"inlining",
+			"let square = fn(x) { x * x }; let y = 3; square(4); square(y); square(z); square(y + 1);",
+			"let square = fn(x)(x * x);let y = 3;16(y * y)square(z)square((y + 1))",
+		},
+		{
+			"inlining of parameters",
+			"let inc = fn(x) { x + 1 }; let f = fn(n) { inc(n) * 2 };",
+			"let inc = fn(x)(x + 1);let f = fn(n)((n + 1) * 2);",
+		},
+		{
+			"no inlining before the definition",
+			"square(4); let square = fn(x) { x * x };",
+			"square(4)let square = fn(x)(x * x);",
+		},
+		{
+			"no inlining of redeclared functions",
+			"let f = fn(x) { x }; f(1); let f = fn(x) { x + 1 };",
+			"let f = fn(x)x;f(1)let f = fn(x)(x + 1);",
+		},
+		{
+			"no inlining of free variables",
+			"let k = 1; let f = fn(x) { x + k }; f(1);",
+			"let k = 1;let f = fn(x)(x + k);f(1)",
+		},
+		{
+			"inlining of failing calls",
+			`let f = fn(x) { x + 1 }; f("a"); f();`,
+			"let f = fn(x)(x + 1);(a + 1)f()",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			program := optimizer.Optimize(parse(t, tc.input))
+
+			require.Equal(t, tc.expected, program.String())
+		})
+	}
+}
+
+func TestOptimizePreservesBehavior(t *testing.T) {
+	programs := []string{
+		"60 * 60 * 24",
+		"let x = 5; if (x > 2 * 2) { x * 10 } else { -x }",
+		"if (false) { 1 }",
+		"if (true) { }",
+		"let a = 1; while (false) { a }",
+		"while (false) { 1 }; 5",
+		`let h = {1 + 1: "two", "a" + "b": 3}; [h[2], h["ab"]]`,
+		"let f = fn(n) { if (n < 2) { return n; } return f(n - 1) + f(n - 2); 100 }; f(10)",
+		"let square = fn(x) { x * x }; square(3) + square(2 + 2)",
+		"let add = fn(a, b) { a + b }; add(1, 2, 3)",
+		`let add = fn(a, b) { a + b }; add("a", 1)`,
+		"let add = fn(a, b) { a + b }; add(1)",
+		`let cube = fn(x) { x * x * x }; let g = fn() { cube(2) }; g()`,
+		"let f = fn() { g(1) }; let g = fn(x) { x * 2 }; f()",
+		"let f = fn(x) { x }; let r = f(1); let f = fn(x) { x + 1 }; [r, f(1)]",
+		"let inc = fn(x) { x + 1 }; let y = 2; let g = fn(n) { inc(n) + inc(y) }; g(3)",
+		"let first = fn(a, b) { a }; let y = 1; first(y, y)",
+		"let sub = fn(a, b) { b - a }; let y = 1; sub(y, missing)",
+		`let inc = fn(x) { x + 1 }; let s = "a"; inc(s)`,
+		`"a" + 1`,
+		"if (true) { return 5; }; 6",
+		"let f = fn() { if (1) { return 1; } 2 }; f()",
+		"if (0) { 1 } else { 2 }",
+		"if (0.0) { 1 } else { 2 }",
+		"!0.0",
+		`if ("") { 1 } else { 2 }`,
+		"!-1",
+		"if (false) { 2 ** 9223372036854775807 } else { 1 }",
+		"let pow = fn(x) { x ** 9223372036854775807 }; if (false) { pow(2) }; 3",
+		"2 ** 9223372036854775807",
+	}
+
+	for _, input := range programs {
+		t.Run(input, func(t *testing.T) {
+			expected := evaluator.Eval(parse(t, input), object.NewEnvironment())
+			actual := evaluator.Eval(optimizer.Optimize(parse(t, input)), object.NewEnvironment())
+
+			require.Equal(t, inspect(expected), inspect(actual))
+		})
+	}
+}
+
+func inspect(o object.Object) string {
+	if o == nil {
+		return "<nil>"
+	}
+
+	return o.Inspect()
+}
@@ -2,6 +2,7 @@ package parser
 
 import (
 	"fmt"
+	"math/big"
 	"strconv"
 
 	"github.com/gkampitakis/monkey/ast"
@@ -19,6 +20,7 @@ const (
 	SUM         // +
 	PRODUCT     // *
 	PREFIX      // -x or !x
+	POWER       // x ** y
 	CALL        // myFunction(x)
 	INDEX       // array[index]
 )
@@ -32,8 +34,10 @@ var precedences = map[token.TokenType]ExPrecedence{
 	token.MINUS:    SUM,
 	token.SLASH:    PRODUCT,
 	token.ASTERISK: PRODUCT,
+	token.POW:      POWER,
 	token.LPAREN:   CALL,
 	token.LBRACKET: INDEX,
+	token.DOT:      INDEX,
 }
 
 type (
@@ -63,6 +67,7 @@ func New(l *lexer.Lexer) *Parser {
 
 	p.registerPrefix(token.IDENT, p.parseIdentifier)
 	p.registerPrefix(token.INT, p.parseIntegerLiteral)
+	p.registerPrefix(token.FLOAT, p.parseFloatLiteral)
 	p.registerPrefix(token.BANG, p.parsePrefixExpression)
 	p.registerPrefix(token.MINUS, p.parsePrefixExpression)
 	p.registerPrefix(token.TRUE, p.parseBoolean)
@@ -79,12 +84,14 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerInfix(token.MINUS, p.parseInfixExpression)
 	p.registerInfix(token.ASTERISK, p.parseInfixExpression)
 	p.registerInfix(token.SLASH, p.parseInfixExpression)
+	p.registerInfix(token.POW, p.parseInfixExpression)
 	p.registerInfix(token.EQ, p.parseInfixExpression)
 	p.registerInfix(token.NEQ, p.parseInfixExpression)
 	p.registerInfix(token.LT, p.parseInfixExpression)
 	p.registerInfix(token.GT, p.parseInfixExpression)
 	p.registerInfix(token.LPAREN, p.parseCallExpression)
 	p.registerInfix(token.LBRACKET, p.parseIndexExpression)
+	p.registerInfix(token.DOT, p.parseDotExpression)
 
 	// Read two tokens, so curToken and peekToken are both set
 	p.nextToken()
@@ -193,6 +200,12 @@ func (p *Parser) parseLetStatement() *ast.LetStatement {
 		Token: p.curToken,
 		Value: p.curToken.Literal,
 	}
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken()
+		if stmt.Name.Type = p.parseTypeAnnotation(); stmt.Name.Type == nil {
+			return nil
+		}
+	}
 	if !p.expectPeek(token.ASSIGN) {
 		return nil
 	}
@@ -247,15 +260,36 @@ func (p *Parser) parseIdentifier() ast.Expression {
 
 func (p *Parser) parseIntegerLiteral() ast.Expression {
 	lit := &ast.IntegerLiteral{Token: p.curToken}
-	value, err := strconv.ParseInt(string(p.curToken.Literal), 0, 64)
+	value, err := strconv.ParseInt(string(p.curToken.Literal), 0, strconv.IntSize)
+	if err == nil {
+		lit.Value = int(value)
+		return lit
+	}
+
+	// literals that don't fit an int are big integers
+	if n, ok := new(big.Int).SetString(string(p.curToken.Literal), 0); ok {
+		lit.Big = n
+		return lit
+	}
+	p.errors = append(
+		p.errors,
+		fmt.Sprintf("could not parse %q as integer", string(p.curToken.Literal)),
+	)
+
+	return nil
+}
+
+func (p *Parser) parseFloatLiteral() ast.Expression {
+	lit := &ast.FloatLiteral{Token: p.curToken}
+	value, err := strconv.ParseFloat(string(p.curToken.Literal), 64)
 	if err != nil {
 		p.errors = append(
 			p.errors,
-			fmt.Sprintf("could not parse %q as integer", string(p.curToken.Literal)),
+			fmt.Sprintf("could not parse %q as float", string(p.curToken.Literal)),
 		)
 		return nil
 	}
-	lit.Value = int(value)
+	lit.Value = value
 
 	return lit
 }
@@ -279,6 +313,10 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 	}
 
 	precedence := p.curPrecedence()
+	// ** is right associative, 2 ** 3 ** 2 is 2 ** (3 ** 2)
+	if p.curTokenIs(token.POW) {
+		precedence--
+	}
 	p.nextToken()
 	exp.Right = p.parseExpression(precedence)
 
@@ -389,19 +427,13 @@ func (p *Parser) parseFunctionParameters() []*ast.Identifier {
 	}
 
 	p.nextToken()
-	identifiers = append(identifiers, &ast.Identifier{
-		Token: p.curToken,
-		Value: p.curToken.Literal,
-	})
+	identifiers = append(identifiers, p.parseParameter())
 
 	for p.peekTokenIs(token.COMMA) {
 		p.nextToken()
 		p.nextToken()
 
-		identifiers = append(identifiers, &ast.Identifier{
-			Token: p.curToken,
-			Value: p.curToken.Literal,
-		})
+		identifiers = append(identifiers, p.parseParameter())
 	}
 
 	// if we don't have closing ) we stop parsing
@@ -412,6 +444,56 @@ func (p *Parser) parseFunctionParameters() []*ast.Identifier {
 	return identifiers
 }
 
+// parseParameter parses a function parameter with its optional type annotation.
+func (p *Parser) parseParameter() *ast.Identifier {
+	ident := &ast.Identifier{
+		Token: p.curToken,
+		Value: p.curToken.Literal,
+	}
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken()
+		ident.Type = p.parseTypeAnnotation()
+	}
+
+	return ident
+}
+
+// parseTypeAnnotation parses the type following a ':' token, e.g. `int` or
+// `hash<string, array<int>>`. The fn keyword is accepted as the function type.
+func (p *Parser) parseTypeAnnotation() *ast.TypeExpr {
+	if p.peekTokenIs(token.FUNCTION) {
+		p.nextToken()
+		return &ast.TypeExpr{Token: p.curToken, Name: string(p.curToken.Literal)}
+	}
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	t := &ast.TypeExpr{Token: p.curToken, Name: string(p.curToken.Literal)}
+
+	if !p.peekTokenIs(token.LT) {
+		return t
+	}
+	p.nextToken()
+
+	for {
+		param := p.parseTypeAnnotation()
+		if param == nil {
+			return nil
+		}
+		t.Params = append(t.Params, param)
+
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken()
+	}
+	if !p.expectPeek(token.GT) {
+		return nil
+	}
+
+	return t
+}
+
 func (p *Parser) parseFunctionLiteral() ast.Expression {
 	lit := &ast.FunctionLiteral{
 		Token: p.curToken,
@@ -422,6 +504,13 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
 
 	lit.Parameters = p.parseFunctionParameters()
 
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken()
+		if lit.ReturnType = p.parseTypeAnnotation(); lit.ReturnType == nil {
+			return nil
+		}
+	}
+
 	if !p.expectPeek(token.LBRACE) {
 		return nil
 	}
@@ -479,14 +568,30 @@ func (p *Parser) parseCallExpression(left ast.Expression) ast.Expression {
 	return exp
 }
 
+// parseIndexExpression parses xs[i], or a slice when the brackets contain a
+// colon.
 func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
-	exp := &ast.IndexExpression{
-		Token: p.curToken,
-		Left:  left,
+	tok := p.curToken
+
+	var start ast.Expression
+	if !p.peekTokenIs(token.COLON) {
+		p.nextToken()
+		start = p.parseExpression(LOWEST)
+	}
+	if !p.peekTokenIs(token.COLON) {
+		if !p.expectPeek(token.RBRACKET) {
+			return nil
+		}
+		return &ast.IndexExpression{Token: tok, Left: left, Index: start}
 	}
-	p.nextToken()
 
-	exp.Index = p.parseExpression(LOWEST)
+	exp := &ast.SliceExpression{Token: tok, Left: left, Start: start}
+	p.nextToken()
+	exp.End = p.parseSlicePart()
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken()
+		exp.Step = p.parseSlicePart()
+	}
 	if !p.expectPeek(token.RBRACKET) {
 		return nil
 	}
@@ -494,6 +599,33 @@ func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
 	return exp
 }
 
+// parseDotExpression parses x.name, which is sugar for x["name"].
+func (p *Parser) parseDotExpression(left ast.Expression) ast.Expression {
+	tok := p.curToken
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+
+	name := p.curToken
+	name.Type = token.STRING_QUOTE
+
+	return &ast.IndexExpression{
+		Token: tok,
+		Left:  left,
+		Index: &ast.StringLiteral{Token: name, Value: string(name.Literal)},
+	}
+}
+
+// parseSlicePart parses the optional expression after a colon of a slice.
+func (p *Parser) parseSlicePart() ast.Expression {
+	if p.peekTokenIs(token.COLON) || p.peekTokenIs(token.RBRACKET) {
+		return nil
+	}
+	p.nextToken()
+
+	return p.parseExpression(LOWEST)
+}
+
 func (p *Parser) parseHashLiteral() ast.Expression {
 	exp := &ast.HashLiteral{
 		Token: p.curToken,
@@ -545,6 +677,10 @@ func (p *Parser) ParseProgram() *ast.Program {
 		program.Statements = append(program.Statements, p.parseStatement())
 		p.nextToken()
 	}
+	// a streaming lexer reports a failed read as the end of the input
+	if err := p.l.Err(); err != nil {
+		p.errors = append(p.errors, fmt.Sprintf("error reading input: %s", err))
+	}
 
 	return program
 }
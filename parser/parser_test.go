@@ -1,9 +1,12 @@
 package parser_test
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"strings"
 	"testing"
+	"testing/iotest"
 
 	"github.com/gkampitakis/monkey/ast"
 	"github.com/gkampitakis/monkey/lexer"
@@ -232,6 +235,61 @@ func TestIntegerLiteralExpression(t *testing.T) {
 	require.Equal(t, integer.TokenLiteral(), "5")
 }
 
+func TestBigIntegerLiteralExpression(t *testing.T) {
+	input := `99999999999999999999;`
+
+	l := lexer.New([]byte(input))
+	p := parser.New(l)
+	program := p.ParseProgram()
+
+	assertParseErrors(t, p, 0)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	require.IsType(t, &ast.IntegerLiteral{}, stmt.Expression)
+
+	integer := stmt.Expression.(*ast.IntegerLiteral)
+	require.Equal(t, 0, integer.Value)
+	require.Equal(t, "99999999999999999999", integer.Big.String())
+	require.Equal(t, "99999999999999999999", integer.String())
+}
+
+func TestFloatLiteralExpression(t *testing.T) {
+	input := `3.25;`
+
+	l := lexer.New([]byte(input))
+	p := parser.New(l)
+	program := p.ParseProgram()
+
+	assertParseErrors(t, p, 0)
+	require.Len(t, program.Statements, 1)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	require.IsType(t, &ast.FloatLiteral{}, stmt.Expression)
+
+	float := stmt.Expression.(*ast.FloatLiteral)
+	require.Equal(t, 3.25, float.Value)
+	require.Equal(t, "3.25", float.TokenLiteral())
+}
+
+func TestParsingDotExpressions(t *testing.T) {
+	input := `math.pi`
+	l := lexer.New([]byte(input))
+	p := parser.New(l)
+	program := p.ParseProgram()
+	assertParseErrors(t, p, 0)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	indexExpression := stmt.Expression.(*ast.IndexExpression)
+
+	testIdentifier(t, indexExpression.Left, []byte("math"))
+	require.IsType(t, &ast.StringLiteral{}, indexExpression.Index)
+	require.Equal(t, "pi", indexExpression.Index.(*ast.StringLiteral).Value)
+
+	p = parser.New(lexer.New([]byte(`math.1`)))
+	p.ParseProgram()
+	require.Equal(t, []string{"expected next token to be IDENT, got INT instead"}, p.Errors())
+}
+
 func TestParsingPrefixExpressions(t *testing.T) {
 	prefixTests := []struct {
 		input    string
@@ -422,6 +480,30 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"add(a*b[2],b[1],2*[1,2][1])",
 			"add((a * (b[2])), (b[1]), (2 * ([1, 2][1])))",
 		},
+		{
+			"2 ** 3 ** 2",
+			"(2 ** (3 ** 2))",
+		},
+		{
+			"-2 ** 2 * 3",
+			"((-(2 ** 2)) * 3)",
+		},
+		{
+			"2 ** -1",
+			"(2 ** (-1))",
+		},
+		{
+			"a ** b[1] ** f(x)",
+			"(a ** ((b[1]) ** f(x)))",
+		},
+		{
+			"math.sqrt(2) * math.pi",
+			"((math.sqrt)(2) * (math.pi))",
+		},
+		{
+			"1.5 + a.b.c",
+			"(1.5 + ((a.b).c))",
+		},
 	}
 
 	for _, tc := range tests {
@@ -749,6 +831,37 @@ func TestParsingIndexExpressions(t *testing.T) {
 	testInfixExpression(t, indexExpression.Index, 1, "+", 1)
 }
 
+func TestParsingSliceExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"xs[1:3]", "(xs[1:3])"},
+		{"xs[:2]", "(xs[:2])"},
+		{"xs[-2:]", "(xs[(-2):])"},
+		{"xs[:]", "(xs[:])"},
+		{"xs[::2]", "(xs[::2])"},
+		{"xs[1::]", "(xs[1:])"},
+		{"xs[a + 1:b * 2:-1]", "(xs[(a + 1):(b * 2):(-1)])"},
+		{"xs[1:2][0]", "((xs[1:2])[0])"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.input, func(t *testing.T) {
+			p := parser.New(lexer.New([]byte(tc.input)))
+			program := p.ParseProgram()
+			assertParseErrors(t, p, 0)
+
+			stmt := program.Statements[0].(*ast.ExpressionStatement)
+			require.Equal(t, tc.expected, stmt.Expression.String())
+		})
+	}
+
+	p := parser.New(lexer.New([]byte("xs[1:2")))
+	p.ParseProgram()
+	require.Equal(t, []string{"expected next token to be RBRACKET, got EOF instead"}, p.Errors())
+}
+
 func TestParsingHashLiteralsStringKeys(t *testing.T) {
 	input := `{"one": 1, "two": 2, "three": 3}`
 	l := lexer.New([]byte(input))
@@ -913,3 +1026,70 @@ func TestParseWhileExpression(t *testing.T) {
 	)
 	testIdentifier(t, consequence.Expression, []byte("i"))
 }
+
+func TestTypeAnnotationParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{input: "let x: int = 5;", expected: "let x: int = 5;"},
+		{input: "let x = 5;", expected: "let x = 5;"},
+		{input: "let xs: array<int> = [1];", expected: "let xs: array<int> = [1];"},
+		{input: "let h: hash<string,int> = 1;", expected: "let h: hash<string, int> = 1;"},
+		{
+			input:    "let h: hash<string, array<array<int>>> = 1;",
+			expected: "let h: hash<string, array<array<int>>> = 1;",
+		},
+		{input: "let f: fn = fn(x) { x };", expected: "let f: fn = fn(x)x;"},
+		{
+			input:    "fn(a: int, b: string): bool { true }",
+			expected: "fn(a: int,b: string): booltrue",
+		},
+		{input: "fn(a, b: int) { a }", expected: "fn(a,b: int)a"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.input, func(t *testing.T) {
+			l := lexer.New([]byte(tc.input))
+			p := parser.New(l)
+			program := p.ParseProgram()
+			assertParseErrors(t, p, 0)
+
+			require.Equal(t, tc.expected, program.String())
+		})
+	}
+}
+
+func TestTypeAnnotationParsingErrors(t *testing.T) {
+	tests := []string{
+		"let x: = 5;",
+		"let x: array<int = [];",
+		"fn(a: 5) { a }",
+		"fn(a): { a }",
+	}
+
+	for _, input := range tests {
+		t.Run(input, func(t *testing.T) {
+			p := parser.New(lexer.New([]byte(input)))
+			p.ParseProgram()
+
+			require.NotEmpty(t, p.Errors())
+		})
+	}
+}
+
+func TestParsingFromReader(t *testing.T) {
+	input := strings.Repeat("let x = [1, 2, 3];\n", 1000)
+	p := parser.New(lexer.NewReader(iotest.OneByteReader(strings.NewReader(input))))
+	program := p.ParseProgram()
+	assertParseErrors(t, p, 0)
+	require.Len(t, program.Statements, 1000)
+	require.Equal(t, "let x = [1, 2, 3];", program.Statements[999].String())
+
+	// a failed read is an error rather than the end of the program
+	r := io.MultiReader(strings.NewReader("let x = 1;"), iotest.ErrReader(errors.New("disk failure")))
+	p = parser.New(lexer.NewReader(r))
+	program = p.ParseProgram()
+	require.Len(t, program.Statements, 1)
+	require.Equal(t, []string{"error reading input: disk failure"}, p.Errors())
+}
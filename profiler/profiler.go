@@ -0,0 +1,162 @@
+// Package profiler records where time is spent across the monkey functions of a
+// program. It's installed as the evaluator.Tracer and measures every call.
+package profiler
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gkampitakis/monkey/object"
+)
+
+// ROOT is the name of the frame for code outside any function in folded stacks.
+const ROOT = "main"
+
+// Stat holds the measurements of a single function.
+type Stat struct {
+	// Name is the function name followed by the position of its definition,
+	// e.g. fib@1:11
+	Name  string
+	Calls int
+	// Inclusive is the time spent in the function including the functions it
+	// called, while Exclusive excludes them.
+	Inclusive time.Duration
+	Exclusive time.Duration
+}
+
+type frame struct {
+	key   string
+	start time.Time
+	// children is the inclusive time of the calls made from this frame
+	children time.Duration
+}
+
+type Profiler struct {
+	stack  []frame
+	stats  map[string]*Stat
+	folded map[string]time.Duration
+	// active counts the frames of each function in the stack, so the inclusive
+	// time of recursive functions is only counted once
+	active map[string]int
+	now    func() time.Time
+}
+
+func New() *Profiler {
+	return &Profiler{
+		stats:  make(map[string]*Stat),
+		folded: make(map[string]time.Duration),
+		active: make(map[string]int),
+		now:    time.Now,
+	}
+}
+
+// Key returns the name fn is reported under.
+func Key(fn *object.Function) string {
+	name := fn.Name
+	if name == "" {
+		name = "<anonymous>"
+	}
+
+	return fmt.Sprintf("%s@%s", name, fn.Pos)
+}
+
+func (p *Profiler) Enter(fn *object.Function) {
+	key := Key(fn)
+	p.stack = append(p.stack, frame{key: key, start: p.now()})
+	p.active[key]++
+
+	stat, ok := p.stats[key]
+	if !ok {
+		stat = &Stat{Name: key}
+		p.stats[key] = stat
+	}
+	stat.Calls++
+}
+
+func (p *Profiler) Exit(*object.Function) {
+	if len(p.stack) == 0 {
+		return
+	}
+
+	f := p.stack[len(p.stack)-1]
+	elapsed := p.now().Sub(f.start)
+	exclusive := elapsed - f.children
+
+	stat := p.stats[f.key]
+	stat.Exclusive += exclusive
+	p.active[f.key]--
+	if p.active[f.key] == 0 {
+		stat.Inclusive += elapsed
+	}
+
+	p.folded[p.stackPath()] += exclusive
+
+	p.stack = p.stack[:len(p.stack)-1]
+	if len(p.stack) > 0 {
+		p.stack[len(p.stack)-1].children += elapsed
+	}
+}
+
+func (p *Profiler) stackPath() string {
+	keys := make([]string, len(p.stack)+1)
+	keys[0] = ROOT
+	for i, f := range p.stack {
+		keys[i+1] = f.key
+	}
+
+	return strings.Join(keys, ";")
+}
+
+// Stats returns the measurements of every called function, sorted by exclusive
+// time in descending order.
+func (p *Profiler) Stats() []Stat {
+	stats := make([]Stat, 0, len(p.stats))
+	for _, s := range p.stats {
+		stats = append(stats, *s)
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Exclusive != stats[j].Exclusive {
+			return stats[i].Exclusive > stats[j].Exclusive
+		}
+		return stats[i].Name < stats[j].Name
+	})
+
+	return stats
+}
+
+// WriteTable writes the top n functions by exclusive time as a table, n <= 0
+// writes all of them.
+func (p *Profiler) WriteTable(w io.Writer, n int) {
+	stats := p.Stats()
+	if n > 0 && n < len(stats) {
+		stats = stats[:n]
+	}
+
+	fmt.Fprintf(w, "%-40s %10s %14s %14s\n", "FUNCTION", "CALLS", "INCLUSIVE", "EXCLUSIVE")
+	for _, s := range stats {
+		fmt.Fprintf(w, "%-40s %10d %14s %14s\n", s.Name, s.Calls, s.Inclusive, s.Exclusive)
+	}
+}
+
+// WriteFolded writes the exclusive time of every call stack in the folded
+// format consumed by flame graph tools, one stack per line with frames separated
+// by ';' followed by the time in microseconds.
+func (p *Profiler) WriteFolded(w io.Writer) error {
+	stacks := make([]string, 0, len(p.folded))
+	for stack := range p.folded {
+		stacks = append(stacks, stack)
+	}
+	sort.Strings(stacks)
+
+	for _, stack := range stacks {
+		if _, err := fmt.Fprintf(w, "%s %d\n", stack, p.folded[stack].Microseconds()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,79 @@
+package profiler
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/gkampitakis/monkey/evaluator"
+	"github.com/gkampitakis/monkey/lexer"
+	"github.com/gkampitakis/monkey/object"
+	"github.com/gkampitakis/monkey/parser"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock advances a millisecond every time it's read.
+func fakeClock() func() time.Time {
+	now := time.Unix(0, 0)
+
+	return func() time.Time {
+		now = now.Add(time.Millisecond)
+		return now
+	}
+}
+
+func profile(t *testing.T, input string) *Profiler {
+	t.Helper()
+
+	p := parser.New(lexer.New([]byte(input)))
+	program := p.ParseProgram()
+	require.Empty(t, p.Errors())
+
+	prof := New()
+	prof.now = fakeClock()
+	evaluator.SetTracer(prof)
+	defer evaluator.SetTracer(nil)
+
+	evaluator.Eval(program, object.NewEnvironment())
+
+	return prof
+}
+
+func TestProfiler(t *testing.T) {
+	prof := profile(t, `let leaf = fn() { 1 };
+let outer = fn() { leaf(); leaf() };
+outer();
+fn() { 2 }();`)
+
+	require.Equal(t, []Stat{
+		// entered at 1ms and exited at 6ms, with leaf calls taking 2ms
+		{Name: "outer@2:13", Calls: 1, Inclusive: 5 * time.Millisecond, Exclusive: 3 * time.Millisecond},
+		{Name: "leaf@1:12", Calls: 2, Inclusive: 2 * time.Millisecond, Exclusive: 2 * time.Millisecond},
+		{Name: "<anonymous>@4:1", Calls: 1, Inclusive: time.Millisecond, Exclusive: time.Millisecond},
+	}, prof.Stats())
+
+	b := &bytes.Buffer{}
+	require.NoError(t, prof.WriteFolded(b))
+	require.Equal(t, `main;<anonymous>@4:1 1000
+main;outer@2:13 3000
+main;outer@2:13;leaf@1:12 2000
+`, b.String())
+}
+
+func TestProfilerRecursion(t *testing.T) {
+	prof := profile(t, `let count = fn(n) { if (n > 0) { count(n - 1) } };
+count(2);`)
+
+	stats := prof.Stats()
+	require.Len(t, stats, 1)
+	require.Equal(t, 3, stats[0].Calls)
+	// the recursive calls are only counted once in the inclusive time
+	require.Equal(t, 5*time.Millisecond, stats[0].Inclusive)
+	require.Equal(t, 5*time.Millisecond, stats[0].Exclusive)
+
+	b := &bytes.Buffer{}
+	prof.WriteTable(b, 1)
+	require.Equal(t, `FUNCTION                                      CALLS      INCLUSIVE      EXCLUSIVE
+count@1:13                                        3            5ms            5ms
+`, b.String())
+}
@@ -0,0 +1,249 @@
+package repl
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/gkampitakis/monkey/ast"
+	"github.com/gkampitakis/monkey/evaluator"
+	"github.com/gkampitakis/monkey/lexer"
+	"github.com/gkampitakis/monkey/object"
+	"github.com/gkampitakis/monkey/parser"
+	"github.com/gkampitakis/monkey/token"
+)
+
+type command struct {
+	usage       string
+	description string
+	run         func(r *ReplHandler, arg string) string
+}
+
+var commands map[string]command
+
+func init() {
+	// commands is initialized here as .help refers back to it
+	commands = map[string]command{
+		".help": {
+			usage:       ".help",
+			description: "print this message",
+			run:         func(*ReplHandler, string) string { return help() },
+		},
+		".exit": {
+			usage:       ".exit",
+			description: "exit the repl",
+			run: func(r *ReplHandler, _ string) string {
+				if r.Repl != nil {
+					r.Repl.Quit()
+				}
+				return ""
+			},
+		},
+		".tokens": {
+			usage:       ".tokens <src>",
+			description: "print the tokens produced by the lexer for src",
+			run:         func(_ *ReplHandler, src string) string { return tokens(src) },
+		},
+		".ast": {
+			usage:       ".ast <src>",
+			description: "print the syntax tree of src",
+			run: func(_ *ReplHandler, src string) string {
+				program, errs := parse(src)
+				if errs != "" {
+					return errs
+				}
+				return strings.TrimSuffix(ast.Sprint(program), "\n")
+			},
+		},
+		".env": {
+			usage:       ".env",
+			description: "list the bindings of the session and their types",
+			run:         func(r *ReplHandler, _ string) string { return r.listEnv() },
+		},
+		".load": {
+			usage:       ".load <file>",
+			description: "evaluate file into the session",
+			run:         func(r *ReplHandler, file string) string { return r.load(file) },
+		},
+		".reset": {
+			usage:       ".reset",
+			description: "clear all bindings of the session",
+			run: func(r *ReplHandler, _ string) string {
+				r.reset()
+				return ""
+			},
+		},
+		".history": {
+			usage:       ".history [query]",
+			description: "list the inputs of previous sessions containing query",
+			run:         func(r *ReplHandler, query string) string { return r.searchHistory(query) },
+		},
+		".save": {
+			usage:       ".save <file>",
+			description: "write the successful inputs of the session as a script",
+			run:         func(r *ReplHandler, file string) string { return r.save(file) },
+		},
+		".restore": {
+			usage:       ".restore <file>",
+			description: "start a new session from a saved script",
+			run:         func(r *ReplHandler, file string) string { return r.restore(file) },
+		},
+		".type": {
+			usage:       ".type <expr>",
+			description: "evaluate expr and print the type of the result",
+			run:         func(r *ReplHandler, src string) string { return r.typeOf(src) },
+		},
+	}
+}
+
+// isCommand reports whether line is a meta-command instead of monkey source.
+func isCommand(line string) bool {
+	return strings.HasPrefix(line, ".") && len(line) > 1 && isIdentChar(line[1])
+}
+
+func (r *ReplHandler) runCommand(line string) string {
+	name, arg, _ := strings.Cut(line, " ")
+	cmd, ok := commands[name]
+	if !ok {
+		return fmt.Sprintf("unknown command %s, type .help for the list of commands", name)
+	}
+
+	return cmd.run(r, strings.TrimSpace(arg))
+}
+
+func help() string {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	s := strings.Builder{}
+	s.WriteString("commands:")
+	for _, name := range names {
+		fmt.Fprintf(&s, "\n  %-16s %s", commands[name].usage, commands[name].description)
+	}
+
+	return s.String()
+}
+
+func tokens(src string) string {
+	l := lexer.New([]byte(src))
+	s := strings.Builder{}
+
+	for {
+		tok := l.NextToken()
+		if tok.Type == token.EOF {
+			break
+		}
+		fmt.Fprintf(&s, "[%s]=>%q\n", tok.Type, tok.Literal)
+	}
+
+	return strings.TrimSuffix(s.String(), "\n")
+}
+
+func (r *ReplHandler) listEnv() string {
+	s := strings.Builder{}
+
+	for _, name := range r.envNames() {
+		val, _ := r.Env.Get(name)
+		fmt.Fprintf(&s, "%s: %s\n", name, val.Type())
+	}
+
+	return strings.TrimSuffix(s.String(), "\n")
+}
+
+func (r *ReplHandler) load(file string) string {
+	if file == "" {
+		return "usage: " + commands[".load"].usage
+	}
+
+	src, err := os.ReadFile(file)
+	if err != nil {
+		return err.Error()
+	}
+
+	out, ok := r.eval(string(src))
+	if ok {
+		r.session = append(r.session, string(src))
+	}
+
+	return out
+}
+
+func (r *ReplHandler) reset() {
+	r.Env = object.NewEnvironment()
+	r.pending = nil
+	r.session = nil
+}
+
+func (r *ReplHandler) searchHistory(query string) string {
+	if r.History == nil {
+		return "history is not enabled"
+	}
+
+	s := strings.Builder{}
+	for i, entry := range r.History.Search(query) {
+		fmt.Fprintf(&s, "%4d  %s\n", i+1, strings.ReplaceAll(entry, "\n", "\n      "))
+	}
+
+	return strings.TrimSuffix(s.String(), "\n")
+}
+
+func (r *ReplHandler) save(file string) string {
+	if file == "" {
+		return "usage: " + commands[".save"].usage
+	}
+
+	s := strings.Builder{}
+	for _, input := range r.session {
+		s.WriteString(input)
+		if !strings.HasSuffix(input, ";") && !strings.HasSuffix(input, "}") {
+			s.WriteByte(';')
+		}
+		s.WriteByte('\n')
+	}
+
+	if err := os.WriteFile(file, []byte(s.String()), 0o644); err != nil {
+		return err.Error()
+	}
+
+	return fmt.Sprintf("saved %d inputs to %s", len(r.session), file)
+}
+
+func (r *ReplHandler) restore(file string) string {
+	if file == "" {
+		return "usage: " + commands[".restore"].usage
+	}
+	if _, err := os.Stat(file); err != nil {
+		return err.Error()
+	}
+
+	r.reset()
+	return r.load(file)
+}
+
+func (r *ReplHandler) typeOf(src string) string {
+	program, errs := parse(src)
+	if errs != "" {
+		return errs
+	}
+
+	evaluated := evaluator.Eval(program, r.Env)
+	if evaluated == nil {
+		return object.NULL.String()
+	}
+
+	return evaluated.Type().String()
+}
+
+func parse(src string) (*ast.Program, string) {
+	p := parser.New(lexer.New([]byte(src)))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, printParserErrors(p.Errors())
+	}
+
+	return program, ""
+}
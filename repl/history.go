@@ -0,0 +1,147 @@
+package repl
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// MAX_HISTORY is the number of entries kept in the history file.
+const MAX_HISTORY = 1000
+
+// History is the list of inputs entered in the repl, persisted to a file so it
+// survives across sessions. Each entry is stored as a quoted string in its own
+// line, so multi-line inputs are preserved. go-repl has no way to preload its
+// up-arrow history, so the entries of previous sessions are only reachable with
+// the .history command.
+type History struct {
+	path    string
+	entries []string
+	// lines is the number of lines of the history file
+	lines int
+}
+
+// DefaultHistoryPath returns the location of the history file under the user's
+// config directory.
+func DefaultHistoryPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "monkey", "history"), nil
+}
+
+// NewHistory loads the history stored at path. A missing file results in an
+// empty history.
+func NewHistory(path string) (*History, error) {
+	h := &History{path: path}
+
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return h, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		h.lines++
+		entry, err := strconv.Unquote(scanner.Text())
+		if err != nil {
+			continue
+		}
+		h.entries = append(h.entries, entry)
+	}
+	if len(h.entries) > MAX_HISTORY {
+		h.entries = h.entries[len(h.entries)-MAX_HISTORY:]
+	}
+
+	return h, scanner.Err()
+}
+
+// Add appends entry to the history and the history file. Consecutive duplicates
+// are ignored. Once the file grows past MAX_HISTORY entries it's rewritten with
+// the latest ones.
+func (h *History) Add(entry string) error {
+	if entry == "" || (len(h.entries) > 0 && h.entries[len(h.entries)-1] == entry) {
+		return nil
+	}
+	h.entries = append(h.entries, entry)
+	if len(h.entries) > MAX_HISTORY {
+		h.entries = h.entries[len(h.entries)-MAX_HISTORY:]
+	}
+
+	if err := os.MkdirAll(filepath.Dir(h.path), 0o755); err != nil {
+		return err
+	}
+	if h.lines >= MAX_HISTORY {
+		return h.rewrite()
+	}
+
+	f, err := os.OpenFile(h.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err = f.WriteString(strconv.Quote(entry) + "\n"); err != nil {
+		return err
+	}
+	h.lines++
+
+	return nil
+}
+
+// rewrite replaces the history file with the entries of h. The entries are
+// written to a temporary file first, so a failure leaves the file intact.
+func (h *History) rewrite() error {
+	f, err := os.CreateTemp(filepath.Dir(h.path), filepath.Base(h.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	w := bufio.NewWriter(f)
+	for _, entry := range h.entries {
+		w.WriteString(strconv.Quote(entry) + "\n")
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(f.Name(), 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(f.Name(), h.path); err != nil {
+		return err
+	}
+	h.lines = len(h.entries)
+
+	return nil
+}
+
+// Entries returns all the history entries, oldest first.
+func (h *History) Entries() []string {
+	return h.entries
+}
+
+// Search returns the entries containing query, oldest first.
+func (h *History) Search(query string) []string {
+	matches := []string{}
+	for _, entry := range h.entries {
+		if strings.Contains(entry, query) {
+			matches = append(matches, entry)
+		}
+	}
+
+	return matches
+}
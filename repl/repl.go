@@ -1,47 +1,202 @@
 package repl
 
 import (
+	"fmt"
 	"io"
+	"os"
 	"strings"
 
 	"github.com/gkampitakis/monkey/evaluator"
-	"github.com/gkampitakis/monkey/lexer"
 	"github.com/gkampitakis/monkey/object"
-	"github.com/gkampitakis/monkey/parser"
+	"github.com/gkampitakis/monkey/token"
 	cli "github.com/openengineer/go-repl"
 )
 
+const (
+	PROMPT              = ">> "
+	CONTINUATION_PROMPT = ".. "
+)
+
 type ReplHandler struct {
 	Repl *cli.Repl
 	Env  *object.Environment
+	// History is optional, when set every input is recorded to it. The
+	// up-arrow history of Repl only holds the inputs of the current session,
+	// previous sessions are searched with .history.
+	History *History
+	// Stderr receives the errors of the repl itself, such as failing to save
+	// the history. Defaults to os.Stderr.
+	Stderr io.Writer
+
+	// pending holds the lines of an input that is not complete yet
+	pending []string
+	// session holds the inputs that were evaluated successfully, so they can be
+	// saved as a script
+	session []string
+	// historyFailed is set once saving the history failed, so the error is
+	// reported only once
+	historyFailed bool
 }
 
 func (r *ReplHandler) Prompt() string {
-	return ">> "
+	if len(r.pending) > 0 {
+		return CONTINUATION_PROMPT
+	}
+
+	return PROMPT
 }
 
-func (r *ReplHandler) Tab(_ string) string {
-	return ""
+// Tab completes the identifier under the cursor with keywords, builtins and
+// names bound in the environment. When there are multiple candidates it completes
+// up to their longest common prefix.
+func (r *ReplHandler) Tab(buffer string) string {
+	prefix := lastWord(buffer)
+	if prefix == "" {
+		return ""
+	}
+
+	candidates := []string{}
+	for _, names := range [][]string{token.Keywords(), evaluator.BuiltinNames(), r.envNames()} {
+		for _, name := range names {
+			if strings.HasPrefix(name, prefix) && name != prefix {
+				candidates = append(candidates, name)
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return ""
+	}
+
+	return commonPrefix(candidates)[len(prefix):]
 }
 
 func (r *ReplHandler) Eval(line string) string {
-	if line == ".exit" {
-		r.Repl.Quit()
+	if len(r.pending) == 0 && isCommand(line) {
+		// recorded after running, so .history doesn't list itself
+		defer r.record(line)
+		return r.runCommand(line)
+	}
+
+	r.pending = append(r.pending, line)
+	input := strings.Join(r.pending, "\n")
+	// an empty line forces evaluation, so the user can escape an incomplete input
+	if line != "" && IsIncomplete(input) {
 		return ""
 	}
-	l := lexer.New([]byte(line))
-	p := parser.New(l)
-	program := p.ParseProgram()
-	if len(p.Errors()) != 0 {
-		return printParserErrors(p.Errors())
+	r.pending = nil
+	r.record(input)
+
+	out, ok := r.eval(input)
+	if ok {
+		r.session = append(r.session, input)
+	}
+
+	return out
+}
+
+// eval evaluates input in the session environment, it returns the output and
+// whether the evaluation succeeded.
+func (r *ReplHandler) eval(input string) (string, bool) {
+	program, errs := parse(input)
+	if errs != "" {
+		return errs, false
 	}
 
 	evaluated := evaluator.Eval(program, r.Env)
-	if evaluated != nil {
-		return evaluated.Inspect()
+	if evaluated == nil {
+		return "", true
+	}
+	if evaluated.Type() == object.EXIT {
+		if r.Repl != nil {
+			r.Repl.Quit()
+		}
+		return "", false
+	}
+
+	return evaluated.Inspect(), evaluated.Type() != object.ERROR_VALUE
+}
+
+func (r *ReplHandler) record(input string) {
+	if r.History == nil || strings.TrimSpace(input) == "" {
+		return
+	}
+
+	if err := r.History.Add(input); err != nil && !r.historyFailed {
+		r.historyFailed = true
+		stderr := r.Stderr
+		if stderr == nil {
+			stderr = os.Stderr
+		}
+		fmt.Fprintf(stderr, "could not save history: %s\n", err)
+	}
+}
+
+func (r *ReplHandler) envNames() []string {
+	if r.Env == nil {
+		return nil
+	}
+
+	return r.Env.Names()
+}
+
+// IsIncomplete reports whether input has unbalanced braces, brackets or parentheses
+// or an unterminated string, meaning more lines are needed before it can be evaluated.
+func IsIncomplete(input string) bool {
+	depth := 0
+	inString := false
+
+	for i := 0; i < len(input); i++ {
+		ch := input[i]
+		if inString {
+			if ch == '"' {
+				inString = false
+			}
+			continue
+		}
+
+		switch ch {
+		case '/':
+			// skip comments until the end of the line
+			if i+1 < len(input) && input[i+1] == '/' {
+				for i < len(input) && input[i] != '\n' {
+					i++
+				}
+			}
+		case '"':
+			inString = true
+		case '{', '[', '(':
+			depth++
+		case '}', ']', ')':
+			depth--
+		}
+	}
+
+	return inString || depth > 0
+}
+
+// lastWord returns the trailing identifier characters of buffer.
+func lastWord(buffer string) string {
+	i := len(buffer)
+	for i > 0 && isIdentChar(buffer[i-1]) {
+		i--
+	}
+
+	return buffer[i:]
+}
+
+func isIdentChar(ch byte) bool {
+	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_'
+}
+
+func commonPrefix(words []string) string {
+	prefix := words[0]
+	for _, w := range words[1:] {
+		for !strings.HasPrefix(w, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
 	}
 
-	return ""
+	return prefix
 }
 
 func printParserErrors(errors []string) string {
@@ -0,0 +1,201 @@
+package repl_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gkampitakis/monkey/object"
+	"github.com/gkampitakis/monkey/repl"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsIncomplete(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"let a = 5;", false},
+		{"let add = fn(a, b) {", true},
+		{"let add = fn(a, b) {\n a + b\n}", false},
+		{"[1, 2,", true},
+		{"add(1,", true},
+		{`"hello`, true},
+		{`"hello {"`, false},
+		{"}", false},
+		{"let a = 1; // {", false},
+	}
+
+	for _, tc := range tests {
+		require.Equal(t, tc.expected, repl.IsIncomplete(tc.input), tc.input)
+	}
+}
+
+func TestEvalMultiLine(t *testing.T) {
+	h := &repl.ReplHandler{Env: object.NewEnvironment()}
+
+	require.Equal(t, "", h.Eval("let add = fn(a, b) {"))
+	require.Equal(t, repl.CONTINUATION_PROMPT, h.Prompt())
+	require.Equal(t, "", h.Eval("a + b"))
+	require.Equal(t, "", h.Eval("};"))
+	require.Equal(t, repl.PROMPT, h.Prompt())
+	require.Equal(t, "3", h.Eval("add(1, 2)"))
+}
+
+func TestTab(t *testing.T) {
+	env := object.NewEnvironment()
+	env.Set("counter", &object.Integer{Value: 1})
+	env.Set("count_all", &object.Integer{Value: 2})
+	h := &repl.ReplHandler{Env: env}
+
+	tests := []struct {
+		buffer   string
+		expected string
+	}{
+		{"ret", "urn"},
+		{"let x = wh", "ile"},
+		{"pu", "sh"},
+		{"print(cou", "nt"},
+		{"counte", "r"},
+		{"", ""},
+		{"xyz", ""},
+		{"let", ""},
+	}
+
+	for _, tc := range tests {
+		require.Equal(t, tc.expected, h.Tab(tc.buffer), tc.buffer)
+	}
+}
+
+func TestCommands(t *testing.T) {
+	t.Run(".tokens", func(t *testing.T) {
+		h := &repl.ReplHandler{Env: object.NewEnvironment()}
+
+		require.Equal(t, "[LET]=>\"let\"\n[IDENT]=>\"a\"\n[ASSIGN]=>\"=\"\n[INT]=>\"5\"", h.Eval(".tokens let a = 5"))
+	})
+
+	t.Run(".ast", func(t *testing.T) {
+		h := &repl.ReplHandler{Env: object.NewEnvironment()}
+
+		require.Equal(
+			t,
+			"Program\n  LetStatement a\n    Value: InfixExpression +\n      Left: IntegerLiteral 1\n      Right: Identifier b",
+			h.Eval(".ast let a = 1 + b"),
+		)
+	})
+
+	t.Run(".env and .reset", func(t *testing.T) {
+		h := &repl.ReplHandler{Env: object.NewEnvironment()}
+		h.Eval(`let a = 5; let b = "foo"`)
+
+		require.Equal(t, "a: INTEGER\nb: STRING", h.Eval(".env"))
+		require.Equal(t, "", h.Eval(".reset"))
+		require.Equal(t, "", h.Eval(".env"))
+	})
+
+	t.Run(".type", func(t *testing.T) {
+		h := &repl.ReplHandler{Env: object.NewEnvironment()}
+
+		require.Equal(t, "FUNCTION", h.Eval(".type fn(x) { x }"))
+		require.Equal(t, "ARRAY", h.Eval(".type [1, 2]"))
+	})
+
+	t.Run(".load", func(t *testing.T) {
+		h := &repl.ReplHandler{Env: object.NewEnvironment()}
+		file := filepath.Join(t.TempDir(), "lib.monkey")
+		require.NoError(t, os.WriteFile(file, []byte("let double = fn(x) { x * 2 };"), 0o644))
+
+		h.Eval(".load " + file)
+		require.Equal(t, "10", h.Eval("double(5)"))
+	})
+
+	t.Run("unknown command", func(t *testing.T) {
+		h := &repl.ReplHandler{Env: object.NewEnvironment()}
+
+		require.Equal(t, "unknown command .foo, type .help for the list of commands", h.Eval(".foo"))
+	})
+}
+
+func TestHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "monkey", "history")
+	history, err := repl.NewHistory(path)
+	require.NoError(t, err)
+
+	h := &repl.ReplHandler{Env: object.NewEnvironment(), History: history}
+	h.Eval("let add = fn(a, b) {")
+	h.Eval("a + b }")
+	h.Eval("add(1, 2)")
+	h.Eval("add(1, 2)")
+	h.Eval(".env")
+
+	// a new session sees the entries of the previous one
+	history, err = repl.NewHistory(path)
+	require.NoError(t, err)
+	require.Equal(t, []string{"let add = fn(a, b) {\na + b }", "add(1, 2)", ".env"}, history.Entries())
+	require.Equal(t, []string{"let add = fn(a, b) {\na + b }", "add(1, 2)"}, history.Search("add"))
+
+	h.History = history
+	require.Equal(t, "   1  add(1, 2)", h.Eval(".history add("))
+}
+
+func TestHistoryLimit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+	lines := strings.Repeat(`"old"`+"\n", repl.MAX_HISTORY+5)
+	require.NoError(t, os.WriteFile(path, []byte(lines), 0o644))
+
+	history, err := repl.NewHistory(path)
+	require.NoError(t, err)
+	for i := 0; i < repl.MAX_HISTORY+10; i++ {
+		require.NoError(t, history.Add(fmt.Sprint(i)))
+	}
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, repl.MAX_HISTORY, strings.Count(string(content), "\n"))
+
+	history, err = repl.NewHistory(path)
+	require.NoError(t, err)
+	require.Len(t, history.Entries(), repl.MAX_HISTORY)
+	require.Equal(t, "10", history.Entries()[0])
+	require.Equal(t, fmt.Sprint(repl.MAX_HISTORY+9), history.Entries()[repl.MAX_HISTORY-1])
+}
+
+func TestHistoryError(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "monkey")
+	history, err := repl.NewHistory(filepath.Join(dir, "history"))
+	require.NoError(t, err)
+	// the history file can't be created under a regular file
+	require.NoError(t, os.WriteFile(dir, nil, 0o644))
+
+	stderr := &strings.Builder{}
+	h := &repl.ReplHandler{Env: object.NewEnvironment(), History: history, Stderr: stderr}
+	require.Equal(t, "1", h.Eval("1"))
+	require.Equal(t, "2", h.Eval("2"))
+
+	require.Equal(t, 1, strings.Count(stderr.String(), "could not save history: "))
+	require.Equal(t, []string{"1", "2"}, history.Entries())
+}
+
+func TestSaveRestore(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "session.monkey")
+
+	h := &repl.ReplHandler{Env: object.NewEnvironment()}
+	h.Eval("let a = 5")
+	h.Eval("let b = a * 2;")
+	h.Eval("let c = a + missing")
+	h.Eval("let d = ")
+	h.Eval("let double = fn(x) {")
+	h.Eval("  x * 2")
+	h.Eval("}")
+	require.Equal(t, "saved 3 inputs to "+file, h.Eval(".save "+file))
+
+	content, err := os.ReadFile(file)
+	require.NoError(t, err)
+	require.Equal(t, "let a = 5;\nlet b = a * 2;\nlet double = fn(x) {\n  x * 2\n}\n", string(content))
+
+	h.Eval(".reset")
+	h.Eval(".restore " + file)
+	require.Equal(t, "20", h.Eval("double(b)"))
+}
@@ -0,0 +1,66 @@
+package tester
+
+import (
+	"encoding/xml"
+	"io"
+)
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Time     float64         `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      float64       `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit writes results as a JUnit XML report, with a test suite per file.
+func WriteJUnit(w io.Writer, results []Result) error {
+	report := junitTestSuites{}
+	suites := map[string]int{}
+
+	for _, r := range results {
+		i, ok := suites[r.File]
+		if !ok {
+			i = len(report.Suites)
+			suites[r.File] = i
+			report.Suites = append(report.Suites, junitTestSuite{Name: r.File})
+		}
+
+		suite := &report.Suites[i]
+		tc := junitTestCase{Name: r.Name, ClassName: r.File, Time: r.Duration.Seconds()}
+		if !r.Passed() {
+			tc.Failure = &junitFailure{Message: r.Failure, Text: r.Location() + ": " + r.Failure}
+			suite.Failures++
+		}
+		suite.Tests++
+		suite.Time += tc.Time
+		suite.Cases = append(suite.Cases, tc)
+	}
+
+	io.WriteString(w, xml.Header)
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+
+	return err
+}
@@ -0,0 +1,171 @@
+// Package tester discovers and runs tests written in monkey.
+//
+// A test file is any file ending in _test.monkey, and a test is a top level
+// function whose name starts with test_, e.g.
+//
+//	let test_add = fn() {
+//	  assert_eq(add(1, 2), 3);
+//	};
+//
+// Each test runs in its own environment, where the file has been evaluated from
+// scratch, so tests can't affect each other.
+package tester
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gkampitakis/monkey/ast"
+	"github.com/gkampitakis/monkey/coverage"
+	"github.com/gkampitakis/monkey/evaluator"
+	"github.com/gkampitakis/monkey/lexer"
+	"github.com/gkampitakis/monkey/object"
+	"github.com/gkampitakis/monkey/parser"
+	"github.com/gkampitakis/monkey/token"
+)
+
+const (
+	FILE_SUFFIX = "_test.monkey"
+	TEST_PREFIX = "test_"
+)
+
+// Result is the outcome of a single test.
+type Result struct {
+	File string
+	Name string
+	// Failure is the reason the test failed, empty if it passed
+	Failure string
+	// Pos is the location of the failure, if known
+	Pos      token.Position
+	Duration time.Duration
+}
+
+func (r Result) Passed() bool { return r.Failure == "" }
+
+// Location returns the file and position of the failure.
+func (r Result) Location() string {
+	if !r.Pos.IsValid() {
+		return r.File
+	}
+
+	return fmt.Sprintf("%s:%s", r.File, r.Pos)
+}
+
+// Discover returns the test files under dir, walking it recursively.
+func Discover(dir string) ([]string, error) {
+	files := []string{}
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && strings.HasSuffix(d.Name(), FILE_SUFFIX) {
+			files = append(files, path)
+		}
+
+		return nil
+	})
+
+	return files, err
+}
+
+// Options configures how tests are run.
+type Options struct {
+	// Filter selects the tests to run by name, nil runs all of them
+	Filter *regexp.Regexp
+	// Coverage records the statements executed by the tests, if set
+	Coverage *coverage.Coverage
+}
+
+// RunFile runs the tests of the file at path selected by opts. Tests are run in
+// the order they are defined.
+func RunFile(path string, opts Options) ([]Result, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	p := parser.New(lexer.New(src))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, fmt.Errorf("%s: %s", path, strings.Join(p.Errors(), "\n\t"))
+	}
+
+	if opts.Coverage != nil {
+		opts.Coverage.Add(path, src, program)
+		evaluator.SetCoverage(opts.Coverage)
+		defer evaluator.SetCoverage(nil)
+	}
+
+	results := []Result{}
+	for _, name := range testNames(program) {
+		if opts.Filter != nil && !opts.Filter.MatchString(name) {
+			continue
+		}
+
+		results = append(results, runTest(path, name, program))
+	}
+
+	return results, nil
+}
+
+// testNames returns the names of the top level functions that are tests.
+func testNames(program *ast.Program) []string {
+	names := []string{}
+
+	for _, stmt := range program.Statements {
+		let, ok := stmt.(*ast.LetStatement)
+		if !ok || !strings.HasPrefix(string(let.Name.Value), TEST_PREFIX) {
+			continue
+		}
+		if _, ok := let.Value.(*ast.FunctionLiteral); ok {
+			names = append(names, string(let.Name.Value))
+		}
+	}
+
+	return names
+}
+
+func runTest(path, name string, program *ast.Program) Result {
+	result := Result{File: path, Name: name}
+	start := time.Now()
+	evalTest(name, program, &result)
+	result.Duration = time.Since(start)
+
+	return result
+}
+
+func evalTest(name string, program *ast.Program, result *Result) {
+	env := object.NewEnvironment()
+	if evaluated := evaluator.Eval(program, env); failed(evaluated, result) {
+		result.Failure = "evaluating file: " + result.Failure
+		return
+	}
+
+	fn, _ := env.Get(name)
+	if f, ok := fn.(*object.Function); ok && len(f.Parameters) != 0 {
+		result.Failure = "test functions must not have parameters"
+		return
+	}
+	failed(evaluator.Apply(fn), result)
+}
+
+// failed records the failure in result if o is an error or an exit request.
+func failed(o object.Object, result *Result) bool {
+	switch o := o.(type) {
+	case *object.ErrorValue:
+		result.Failure = o.Message
+		result.Pos = o.Pos
+		return true
+	case *object.Exit:
+		result.Failure = fmt.Sprintf("unexpected call to exit(%d)", o.Code)
+		return true
+	default:
+		return false
+	}
+}
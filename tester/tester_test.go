@@ -0,0 +1,87 @@
+package tester_test
+
+import (
+	"bytes"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/gkampitakis/monkey/tester"
+	"github.com/gkampitakis/monkey/token"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiscover(t *testing.T) {
+	files, err := tester.Discover("testdata")
+
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		filepath.Join("testdata", "math_test.monkey"),
+		filepath.Join("testdata", "nested", "isolation_test.monkey"),
+	}, files)
+}
+
+func TestRunFile(t *testing.T) {
+	t.Run("reports failures", func(t *testing.T) {
+		results, err := tester.RunFile(filepath.Join("testdata", "math_test.monkey"), tester.Options{})
+		require.NoError(t, err)
+
+		require.Len(t, results, 4)
+		for _, r := range results {
+			if r.Name == "test_add_fails" {
+				require.Equal(t, "assertion failed: expected 5, got 4", r.Failure)
+				require.Equal(t, token.Position{Line: 12, Column: 3}, r.Pos)
+				require.Equal(t, "testdata/math_test.monkey:12:3", r.Location())
+				continue
+			}
+			require.True(t, r.Passed(), r.Name+": "+r.Failure)
+		}
+	})
+
+	t.Run("isolates tests", func(t *testing.T) {
+		results, err := tester.RunFile(filepath.Join("testdata", "nested", "isolation_test.monkey"), tester.Options{})
+		require.NoError(t, err)
+
+		require.Len(t, results, 2)
+		require.True(t, results[0].Passed())
+		require.True(t, results[1].Passed())
+	})
+
+	t.Run("filters tests", func(t *testing.T) {
+		results, err := tester.RunFile(
+			filepath.Join("testdata", "math_test.monkey"),
+			tester.Options{Filter: regexp.MustCompile("strings$")},
+		)
+		require.NoError(t, err)
+
+		require.Len(t, results, 1)
+		require.Equal(t, "test_add_strings", results[0].Name)
+	})
+}
+
+func TestWriteJUnit(t *testing.T) {
+	b := &bytes.Buffer{}
+	err := tester.WriteJUnit(b, []tester.Result{
+		{File: "a_test.monkey", Name: "test_one", Duration: time.Second},
+		{
+			File:     "a_test.monkey",
+			Name:     "test_two",
+			Failure:  "assertion failed",
+			Pos:      token.Position{Line: 2, Column: 4},
+			Duration: time.Second,
+		},
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites>
+  <testsuite name="a_test.monkey" tests="2" failures="1" time="2">
+    <testcase name="test_one" classname="a_test.monkey" time="1"></testcase>
+    <testcase name="test_two" classname="a_test.monkey" time="1">
+      <failure message="assertion failed">a_test.monkey:2:4: assertion failed</failure>
+    </testcase>
+  </testsuite>
+</testsuites>
+`, b.String())
+}
@@ -1,19 +1,35 @@
 package token
 
+import (
+	"fmt"
+	"sort"
+)
+
 //go:generate stringer -type=TokenType
 type TokenType uint8
 
 type Token struct {
 	Type    TokenType
 	Literal []byte
+	Pos     Position
 }
 
+// Position is the location of a token in the source, lines and columns start at 1.
+type Position struct {
+	Line   int
+	Column int
+}
+
+func (p Position) IsValid() bool  { return p.Line > 0 }
+func (p Position) String() string { return fmt.Sprintf("%d:%d", p.Line, p.Column) }
+
 const (
 	ILLEGAL TokenType = iota
 	EOF
 	// Identifiers + literals
 	IDENT
 	INT
+	FLOAT
 	// Operators
 	ASSIGN
 	PLUS
@@ -21,6 +37,7 @@ const (
 	BANG
 	ASTERISK
 	SLASH
+	POW
 	LT
 	GT
 	EQ
@@ -35,6 +52,7 @@ const (
 	LBRACKET
 	RBRACKET
 	COLON
+	DOT
 	STRING_QUOTE
 	// Keywords
 	FUNCTION
@@ -58,6 +76,17 @@ var keywords = map[string]TokenType{
 	"false":  FALSE,
 }
 
+// Keywords returns every reserved word of the language in sorted order.
+func Keywords() []string {
+	kw := make([]string, 0, len(keywords))
+	for k := range keywords {
+		kw = append(kw, k)
+	}
+	sort.Strings(kw)
+
+	return kw
+}
+
 func LookupIdent(ident []byte) TokenType {
 	if tok, ok := keywords[string(ident)]; ok {
 		return tok
@@ -12,39 +12,42 @@ func _() {
 	_ = x[EOF-1]
 	_ = x[IDENT-2]
 	_ = x[INT-3]
-	_ = x[ASSIGN-4]
-	_ = x[PLUS-5]
-	_ = x[MINUS-6]
-	_ = x[BANG-7]
-	_ = x[ASTERISK-8]
-	_ = x[SLASH-9]
-	_ = x[LT-10]
-	_ = x[GT-11]
-	_ = x[EQ-12]
-	_ = x[NEQ-13]
-	_ = x[COMMA-14]
-	_ = x[SEMICOLON-15]
-	_ = x[LPAREN-16]
-	_ = x[RPAREN-17]
-	_ = x[LBRACE-18]
-	_ = x[RBRACE-19]
-	_ = x[LBRACKET-20]
-	_ = x[RBRACKET-21]
-	_ = x[COLON-22]
-	_ = x[STRING_QUOTE-23]
-	_ = x[FUNCTION-24]
-	_ = x[LET-25]
-	_ = x[RETURN-26]
-	_ = x[IF-27]
-	_ = x[ELSE-28]
-	_ = x[WHILE-29]
-	_ = x[TRUE-30]
-	_ = x[FALSE-31]
+	_ = x[FLOAT-4]
+	_ = x[ASSIGN-5]
+	_ = x[PLUS-6]
+	_ = x[MINUS-7]
+	_ = x[BANG-8]
+	_ = x[ASTERISK-9]
+	_ = x[SLASH-10]
+	_ = x[POW-11]
+	_ = x[LT-12]
+	_ = x[GT-13]
+	_ = x[EQ-14]
+	_ = x[NEQ-15]
+	_ = x[COMMA-16]
+	_ = x[SEMICOLON-17]
+	_ = x[LPAREN-18]
+	_ = x[RPAREN-19]
+	_ = x[LBRACE-20]
+	_ = x[RBRACE-21]
+	_ = x[LBRACKET-22]
+	_ = x[RBRACKET-23]
+	_ = x[COLON-24]
+	_ = x[DOT-25]
+	_ = x[STRING_QUOTE-26]
+	_ = x[FUNCTION-27]
+	_ = x[LET-28]
+	_ = x[RETURN-29]
+	_ = x[IF-30]
+	_ = x[ELSE-31]
+	_ = x[WHILE-32]
+	_ = x[TRUE-33]
+	_ = x[FALSE-34]
 }
 
-const _TokenType_name = "ILLEGALEOFIDENTINTASSIGNPLUSMINUSBANGASTERISKSLASHLTGTEQNEQCOMMASEMICOLONLPARENRPARENLBRACERBRACELBRACKETRBRACKETCOLONSTRING_QUOTEFUNCTIONLETRETURNIFELSEWHILETRUEFALSE"
+const _TokenType_name = "ILLEGALEOFIDENTINTFLOATASSIGNPLUSMINUSBANGASTERISKSLASHPOWLTGTEQNEQCOMMASEMICOLONLPARENRPARENLBRACERBRACELBRACKETRBRACKETCOLONDOTSTRING_QUOTEFUNCTIONLETRETURNIFELSEWHILETRUEFALSE"
 
-var _TokenType_index = [...]uint8{0, 7, 10, 15, 18, 24, 28, 33, 37, 45, 50, 52, 54, 56, 59, 64, 73, 79, 85, 91, 97, 105, 113, 118, 130, 138, 141, 147, 149, 153, 158, 162, 167}
+var _TokenType_index = [...]uint8{0, 7, 10, 15, 18, 23, 29, 33, 38, 42, 50, 55, 58, 60, 62, 64, 67, 72, 81, 87, 93, 99, 105, 113, 121, 126, 129, 141, 149, 152, 158, 160, 164, 169, 173, 178}
 
 func (i TokenType) String() string {
 	if i >= TokenType(len(_TokenType_index)-1) {
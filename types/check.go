@@ -0,0 +1,488 @@
+package types
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/gkampitakis/monkey/ast"
+	"github.com/gkampitakis/monkey/token"
+)
+
+type Error struct {
+	Pos     token.Position
+	Message string
+	// Conflict is the position of the other type in a unification failure
+	Conflict token.Position
+}
+
+func (e Error) String() string {
+	if e.Conflict.IsValid() {
+		return fmt.Sprintf("%s: %s (conflicts with %s)", e.Pos, e.Message, e.Conflict)
+	}
+
+	return fmt.Sprintf("%s: %s", e.Pos, e.Message)
+}
+
+// Check verifies the annotated code of program and returns the type errors
+// sorted by position.
+func Check(program *ast.Program) []Error {
+	c := &checker{}
+	s := newScope(nil, nil)
+	for _, stmt := range program.Statements {
+		c.statement(s, stmt)
+	}
+
+	sort.SliceStable(c.errors, func(i, j int) bool {
+		if c.errors[i].Pos.Line != c.errors[j].Pos.Line {
+			return c.errors[i].Pos.Line < c.errors[j].Pos.Line
+		}
+		return c.errors[i].Pos.Column < c.errors[j].Pos.Column
+	})
+
+	return c.errors
+}
+
+// scope holds the types of the program or a function body, as blocks don't
+// create scopes in monkey.
+type scope struct {
+	outer *scope
+	types map[string]Type
+	// function is the type of the enclosing function, nil at the top level
+	function *Function
+}
+
+func newScope(outer *scope, function *Function) *scope {
+	return &scope{outer: outer, types: make(map[string]Type), function: function}
+}
+
+func (s *scope) lookup(name string) Type {
+	for sc := s; sc != nil; sc = sc.outer {
+		if t, ok := sc.types[name]; ok {
+			return t
+		}
+	}
+
+	// builtins and undefined names are left to the evaluator
+	return Any
+}
+
+type checker struct {
+	errors []Error
+}
+
+func (c *checker) errorf(pos token.Position, format string, a ...interface{}) {
+	c.errors = append(c.errors, Error{Pos: pos, Message: fmt.Sprintf(format, a...)})
+}
+
+// resolve returns the type an annotation refers to, nil annotations are dynamic.
+func (c *checker) resolve(t *ast.TypeExpr) Type {
+	if t == nil {
+		return Any
+	}
+
+	params := map[string]int{
+		"any": 0, "int": 0, "float": 0, "string": 0, "bool": 0, "null": 0, "time": 0, "duration": 0,
+		"fn": 0, "array": 1, "hash": 2,
+	}
+	n, ok := params[t.Name]
+	if !ok {
+		c.errorf(t.Token.Pos, "unknown type %s", t.Name)
+		return Any
+	}
+	// array and hash without parameters hold values of any type
+	if len(t.Params) != 0 && len(t.Params) != n {
+		c.errorf(t.Token.Pos, "%s expects %d type parameters, got %d", t.Name, n, len(t.Params))
+		return Any
+	}
+
+	switch t.Name {
+	case "int":
+		return Int
+	case "float":
+		return Float
+	case "string":
+		return String
+	case "bool":
+		return Bool
+	case "null":
+		return Null
+	case "time":
+		return Time
+	case "duration":
+		return Duration
+	case "fn":
+		return &Function{Return: Any}
+	case "array":
+		if len(t.Params) == 0 {
+			return &Array{Elem: Any}
+		}
+		return &Array{Elem: c.resolve(t.Params[0])}
+	case "hash":
+		if len(t.Params) == 0 {
+			return &Hash{Key: Any, Value: Any}
+		}
+		key := c.resolve(t.Params[0])
+		if !hashable(key) {
+			c.errorf(t.Params[0].Token.Pos, "invalid hash key type %s", key)
+		}
+		return &Hash{Key: key, Value: c.resolve(t.Params[1])}
+	default:
+		return Any
+	}
+}
+
+func (c *checker) statement(s *scope, stmt ast.Statement) Type {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		c.let(s, stmt)
+	case *ast.ReturnStatement:
+		t := c.expression(s, stmt.ReturnValue)
+		if s.function != nil && !Assignable(t, s.function.Return) {
+			c.errorf(ast.Pos(stmt.ReturnValue), "cannot return %s from function returning %s", t, s.function.Return)
+		}
+	case *ast.ExpressionStatement:
+		return c.expression(s, stmt.Expression)
+	case *ast.BlockStatement:
+		return c.block(s, stmt)
+	}
+
+	return Any
+}
+
+func (c *checker) let(s *scope, stmt *ast.LetStatement) {
+	name := string(stmt.Name.Value)
+	annotation := c.resolve(stmt.Name.Type)
+
+	var t Type
+	if fn, ok := stmt.Value.(*ast.FunctionLiteral); ok {
+		// the name is bound before checking the body so recursive calls are checked
+		sig := c.signature(fn)
+		s.types[name] = sig
+		if stmt.Name.Type != nil {
+			s.types[name] = annotation
+		}
+		t = c.function(s, fn, sig)
+		if !Assignable(t, annotation) {
+			c.errorf(ast.Pos(stmt.Value), "cannot use %s as %s in let %s", t, annotation, name)
+		}
+	} else {
+		t = c.assign(s, stmt.Value, annotation, "let "+name)
+	}
+	// unannotated bindings are dynamic, except functions whose signature is
+	// known from their literal
+	switch {
+	case stmt.Name.Type != nil:
+		s.types[name] = annotation
+	case isFunction(stmt.Value):
+		s.types[name] = t
+	default:
+		s.types[name] = Any
+	}
+}
+
+// block returns the type of the last statement of b.
+func (c *checker) block(s *scope, b *ast.BlockStatement) Type {
+	var t Type = Any
+	for _, stmt := range b.Statements {
+		t = c.statement(s, stmt)
+	}
+
+	return t
+}
+
+func (c *checker) signature(fn *ast.FunctionLiteral) *Function {
+	params := make([]Type, len(fn.Parameters))
+	for i, p := range fn.Parameters {
+		params[i] = c.resolve(p.Type)
+	}
+
+	return &Function{Params: params, Return: c.resolve(fn.ReturnType)}
+}
+
+func (c *checker) function(s *scope, fn *ast.FunctionLiteral, sig *Function) Type {
+	inner := newScope(s, sig)
+	for i, p := range fn.Parameters {
+		inner.types[string(p.Value)] = sig.Params[i]
+	}
+
+	t := c.block(inner, fn.Body)
+	n := len(fn.Body.Statements)
+	if n > 0 && !Assignable(t, sig.Return) {
+		if last, ok := fn.Body.Statements[n-1].(*ast.ExpressionStatement); ok {
+			c.errorf(ast.Pos(last.Expression), "cannot return %s from function returning %s", t, sig.Return)
+		}
+	}
+
+	return sig
+}
+
+func (c *checker) expression(s *scope, exp ast.Expression) Type {
+	switch exp := exp.(type) {
+	case *ast.IntegerLiteral:
+		return Int
+	case *ast.FloatLiteral:
+		return Float
+	case *ast.StringLiteral:
+		return String
+	case *ast.Boolean:
+		return Bool
+	case *ast.Identifier:
+		return s.lookup(string(exp.Value))
+	case *ast.PrefixExpression:
+		return c.prefix(s, exp)
+	case *ast.InfixExpression:
+		return c.infix(s, exp)
+	case *ast.IfExpression:
+		c.expression(s, exp.Condition)
+		consequence := c.block(s, exp.Consequence)
+		if exp.Alternative == nil {
+			return Any
+		}
+		if alternative := c.block(s, exp.Alternative); identical(consequence, alternative) {
+			return consequence
+		}
+		return Any
+	case *ast.WhileExpression:
+		c.expression(s, exp.Condition)
+		c.block(s, exp.Consequence)
+		return Null
+	case *ast.FunctionLiteral:
+		return c.function(s, exp, c.signature(exp))
+	case *ast.CallExpression:
+		return c.call(s, exp)
+	case *ast.ArrayLiteral:
+		var elem Type
+		for _, el := range exp.Elements {
+			elem = unify(elem, c.expression(s, el))
+		}
+		if elem == nil {
+			elem = Any
+		}
+		return &Array{Elem: elem}
+	case *ast.HashLiteral:
+		var key, value Type
+		for _, k := range ast.HashKeys(exp) {
+			kt := c.expression(s, k)
+			if !hashable(kt) {
+				c.errorf(ast.Pos(k), "unusable as hash key: %s", kt)
+			}
+			key = unify(key, kt)
+			value = unify(value, c.expression(s, exp.Pairs[k]))
+		}
+		if key == nil {
+			return &Hash{Key: Any, Value: Any}
+		}
+		return &Hash{Key: key, Value: value}
+	case *ast.IndexExpression:
+		return c.index(s, exp)
+	case *ast.SliceExpression:
+		return c.slice(s, exp)
+	}
+
+	return Any
+}
+
+// assign checks that exp can be used where want is expected and returns its
+// type. The elements of array and hash literals are checked one by one, so
+// errors point to the offending element.
+func (c *checker) assign(s *scope, exp ast.Expression, want Type, context string) Type {
+	switch want := want.(type) {
+	case *Array:
+		if lit, ok := exp.(*ast.ArrayLiteral); ok {
+			for _, el := range lit.Elements {
+				c.assign(s, el, want.Elem, context)
+			}
+			return want
+		}
+	case *Hash:
+		if lit, ok := exp.(*ast.HashLiteral); ok {
+			for _, k := range ast.HashKeys(lit) {
+				c.assign(s, k, want.Key, context)
+				c.assign(s, lit.Pairs[k], want.Value, context)
+			}
+			return want
+		}
+	}
+
+	t := c.expression(s, exp)
+	if !Assignable(t, want) {
+		c.errorf(ast.Pos(exp), "cannot use %s as %s in %s", t, want, context)
+	}
+
+	return t
+}
+
+func (c *checker) prefix(s *scope, exp *ast.PrefixExpression) Type {
+	right := c.expression(s, exp.Right)
+
+	switch exp.Operator {
+	case "!":
+		return Bool
+	case "-":
+		if right != Any && !numeric(right) && right != Duration {
+			c.errorf(exp.Token.Pos, "operator - not defined on %s", right)
+			return Any
+		}
+		return right
+	}
+
+	return Any
+}
+
+func (c *checker) infix(s *scope, exp *ast.InfixExpression) Type {
+	left, right := c.expression(s, exp.Left), c.expression(s, exp.Right)
+
+	// comparing values of different types is false rather than an error
+	if exp.Operator == "==" || exp.Operator == "!=" {
+		return Bool
+	}
+
+	if left == Any || right == Any {
+		if exp.Operator == "<" || exp.Operator == ">" {
+			return Bool
+		}
+		return Any
+	}
+	if isTime(left.String()) || isTime(right.String()) {
+		if name, ok := timeOperation(exp.Operator, left.String(), right.String()); ok {
+			return basic(name)
+		}
+		c.errorf(exp.Token.Pos, "mismatched types %s %s %s", left, exp.Operator, right)
+		return Any
+	}
+	// integers mixed with floats are converted to floats
+	if numeric(left) && numeric(right) && left != right {
+		left, right = Float, Float
+	}
+	if !identical(left, right) {
+		c.errorf(exp.Token.Pos, "mismatched types %s %s %s", left, exp.Operator, right)
+		return Any
+	}
+
+	switch {
+	case numeric(left) && (exp.Operator == "<" || exp.Operator == ">"):
+		return Bool
+	case numeric(left), left == String && exp.Operator == "+":
+		return left
+	}
+
+	c.errorf(exp.Token.Pos, "operator %s not defined on %s", exp.Operator, left)
+	return Any
+}
+
+func (c *checker) call(s *scope, exp *ast.CallExpression) Type {
+	callee := c.expression(s, exp.Function)
+	fn, ok := callee.(*Function)
+	if !ok || fn.Params == nil {
+		for _, arg := range exp.Arguments {
+			c.expression(s, arg)
+		}
+		if callee != Any && !ok {
+			c.errorf(ast.Pos(exp.Function), "cannot call non-function %s of type %s", exp.Function, callee)
+		}
+		if ok {
+			return fn.Return
+		}
+		return Any
+	}
+
+	// extra arguments are ignored by the evaluator
+	if len(exp.Arguments) < len(fn.Params) {
+		c.errorf(
+			ast.Pos(exp.Function), "not enough arguments in call to %s: got %d, want %d",
+			exp.Function, len(exp.Arguments), len(fn.Params),
+		)
+	}
+	for i, arg := range exp.Arguments {
+		if i < len(fn.Params) {
+			c.assign(s, arg, fn.Params[i], fmt.Sprintf("argument %d to %s", i+1, exp.Function))
+		} else {
+			c.expression(s, arg)
+		}
+	}
+
+	return fn.Return
+}
+
+func (c *checker) index(s *scope, exp *ast.IndexExpression) Type {
+	left, index := c.expression(s, exp.Left), c.expression(s, exp.Index)
+
+	switch left := left.(type) {
+	case *Array:
+		if !Assignable(index, Int) {
+			c.errorf(ast.Pos(exp.Index), "cannot index array with %s", index)
+			return Any
+		}
+		return left.Elem
+	case *Hash:
+		if !Assignable(index, left.Key) {
+			c.errorf(ast.Pos(exp.Index), "cannot index %s with %s", left, index)
+			return Any
+		}
+		return left.Value
+	}
+	// the type of a field depends on its name
+	if left == Time || left == Duration {
+		return Any
+	}
+
+	if left != Any {
+		c.errorf(ast.Pos(exp.Left), "index operator not supported: %s", left)
+	}
+	return Any
+}
+
+// slice checks a slice expression, whose type is the type of the sliced value.
+func (c *checker) slice(s *scope, exp *ast.SliceExpression) Type {
+	left := c.expression(s, exp.Left)
+	for _, bound := range []ast.Expression{exp.Start, exp.End, exp.Step} {
+		if bound == nil {
+			continue
+		}
+		if t := c.expression(s, bound); !Assignable(t, Int) {
+			c.errorf(ast.Pos(bound), "cannot slice with %s", t)
+		}
+	}
+
+	if _, ok := left.(*Array); ok || left == String || left == Any {
+		return left
+	}
+
+	c.errorf(ast.Pos(exp.Left), "slice operator not supported: %s", left)
+	return Any
+}
+
+// unify returns the common type of the elements of a literal, a nil a is the
+// type of no elements.
+func unify(a, b Type) Type {
+	if a == nil || identical(a, b) {
+		return b
+	}
+
+	return Any
+}
+
+func hashable(t Type) bool {
+	return t == Any || t == Int || t == String || t == Bool
+}
+
+// basic returns the basic type with the name of one of the results of
+// timeOperation.
+func basic(name string) Type {
+	for _, t := range []*Basic{Int, Float, Bool, Time, Duration} {
+		if t.Name == name {
+			return t
+		}
+	}
+
+	return Any
+}
+
+func numeric(t Type) bool {
+	return t == Int || t == Float
+}
+
+func isFunction(exp ast.Expression) bool {
+	_, ok := exp.(*ast.FunctionLiteral)
+	return ok
+}
@@ -0,0 +1,191 @@
+package types_test
+
+import (
+	"testing"
+
+	"github.com/gkampitakis/monkey/lexer"
+	"github.com/gkampitakis/monkey/parser"
+	"github.com/gkampitakis/monkey/types"
+	"github.com/stretchr/testify/require"
+)
+
+func testCheck(t *testing.T, input string) []string {
+	t.Helper()
+
+	p := parser.New(lexer.New([]byte(input)))
+	program := p.ParseProgram()
+	require.Empty(t, p.Errors())
+
+	errors := []string{}
+	for _, err := range types.Check(program) {
+		errors = append(errors, err.String())
+	}
+
+	return errors
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{
+			"unannotated code is dynamic",
+			`let add = fn(a, b) { a + b };
+let x = add(1, "a");
+let y = x + 1;
+let h = {"a": 1};
+h["b"] - 2;`,
+			[]string{},
+		},
+		{
+			"literal mismatch",
+			`"a" + 1;`,
+			[]string{`1:5: mismatched types string + int`},
+		},
+		{
+			"let annotations",
+			`let x: int = 5;
+let s: string = x;
+let xs: array<int> = [1, 2, "3"];
+let h: hash<string, int> = {"a": 1, "b": 2};
+let b: bool = h["a"];`,
+			[]string{
+				"2:17: cannot use int as string in let s",
+				"3:29: cannot use string as int in let xs",
+				"5:16: cannot use int as bool in let b",
+			},
+		},
+		{
+			"annotated bindings are typed",
+			`let x: int = 5;
+x + "a";
+-"a";
+true < false;`,
+			[]string{
+				"2:3: mismatched types int + string",
+				"3:1: operator - not defined on string",
+				"4:6: operator < not defined on bool",
+			},
+		},
+		{
+			"function signatures",
+			`let add = fn(a: int, b: int): int { a + b };
+add(1, "2");
+add(1);
+let s: string = add(1, 2);
+add(1, 2)(3);`,
+			[]string{
+				"2:8: cannot use string as int in argument 2 to add",
+				"3:1: not enough arguments in call to add: got 1, want 2",
+				"4:20: cannot use int as string in let s",
+				"5:4: cannot call non-function add(1, 2) of type int",
+			},
+		},
+		{
+			"return types",
+			`let f = fn(n: int): string {
+  if (n > 0) { return n; }
+  "negative"
+};
+let g = fn(): bool { 1 };`,
+			[]string{
+				"2:23: cannot return int from function returning string",
+				"5:22: cannot return int from function returning bool",
+			},
+		},
+		{
+			"recursive functions",
+			`let fact = fn(n: int): int { if (n < 2) { 1 } else { n * fact(n - "1") } };`,
+			[]string{"1:65: mismatched types int - string"},
+		},
+		{
+			"function annotations",
+			`let apply = fn(f: fn, x: int): int { f(x) };
+apply(fn(x) { x }, 1);
+apply(1, 1);
+let g: fn = apply;`,
+			[]string{"3:7: cannot use int as fn in argument 1 to apply"},
+		},
+		{
+			"index expressions",
+			`let xs: array<string> = ["a"];
+xs["0"];
+let n: int = xs[0];
+let h: hash<string, int> = {};
+h[1];
+5[0];`,
+			[]string{
+				"2:4: cannot index array with string",
+				"3:16: cannot use string as int in let n",
+				"5:3: cannot index hash<string, int> with int",
+				"6:1: index operator not supported: int",
+			},
+		},
+		{
+			"slice expressions",
+			`let xs: array<string> = ["a"];
+let ys: array<string> = xs[1:];
+let s: int = "abc"[::-1];
+xs["a":];
+{}[1:2];`,
+			[]string{
+				"3:19: cannot use string as int in let s",
+				"4:4: cannot slice with string",
+				"5:1: slice operator not supported: hash<any, any>",
+			},
+		},
+		{
+			"floats",
+			`let a: float = 1.5 * 2;
+let b: int = 2 ** 10;
+let c: int = 1 / 2.0;
+-"a";
+1.5 + "a";`,
+			[]string{
+				"3:16: cannot use float as int in let c",
+				"4:1: operator - not defined on string",
+				"5:5: mismatched types float + string",
+			},
+		},
+		{
+			"times",
+			`let add = fn(t: time, d: duration): time { t + d * 2 };
+let since = fn(t: time, u: time): duration { -(t - u) };
+let ratio = fn(a: duration, b: duration): float { a / b };
+let bad = fn(t: time) { t + t };`,
+			[]string{"4:27: mismatched types time + time"},
+		},
+		{
+			"invalid annotations",
+			`let a: decimal = 1;
+let b: array<int, int> = [];
+let c: hash<array, int> = {};`,
+			[]string{
+				"1:8: unknown type decimal",
+				"2:8: array expects 1 type parameters, got 2",
+				"3:13: invalid hash key type array<any>",
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, testCheck(t, tc.input))
+		})
+	}
+}
+
+func TestAssignable(t *testing.T) {
+	intFn := &types.Function{Params: []types.Type{types.Int}, Return: types.Int}
+
+	require.True(t, types.Assignable(types.Int, types.Any))
+	require.True(t, types.Assignable(types.Any, types.String))
+	require.False(t, types.Assignable(types.Int, types.String))
+	require.True(t, types.Assignable(&types.Array{Elem: types.Int}, &types.Array{Elem: types.Any}))
+	require.False(t, types.Assignable(&types.Array{Elem: types.Int}, &types.Hash{Key: types.Int, Value: types.Int}))
+	require.True(t, types.Assignable(intFn, &types.Function{Return: types.Any}))
+	require.False(t, types.Assignable(intFn, &types.Function{Params: []types.Type{types.String}, Return: types.Int}))
+	require.Equal(t, "fn(int): int", intFn.String())
+}
@@ -0,0 +1,900 @@
+package types
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gkampitakis/monkey/ast"
+	"github.com/gkampitakis/monkey/evaluator"
+	"github.com/gkampitakis/monkey/lexer"
+	"github.com/gkampitakis/monkey/object"
+	"github.com/gkampitakis/monkey/token"
+)
+
+// Signature is the inferred type of a top-level let binding.
+type Signature struct {
+	Name string
+	Pos  token.Position
+	// Type is the type of the binding, type variables are named a, b, c...
+	// e.g. array<a> -> a
+	Type string
+}
+
+func (s Signature) String() string {
+	return fmt.Sprintf("%s: %s", s.Name, s.Type)
+}
+
+// Infer runs Hindley-Milner type inference (Algorithm W) over program and
+// returns the types of its top-level bindings along with the unification
+// failures sorted by position.
+//
+// Monkey is dynamic, so some rules are looser than the evaluator: indexing a
+// value of unknown type with an int assumes an array and with a string a hash,
+// while if expressions without else have the type of their consequence. Values
+// of any types can be compared with == and !=, as they are at runtime.
+func Infer(program *ast.Program) ([]Signature, []Error) {
+	in := &inferer{builtins: make(map[string]*scheme)}
+	e := newEnv(nil)
+	signatures := []Signature{}
+
+	for _, stmt := range program.Statements {
+		in.statement(e, stmt, nil)
+
+		if let, ok := stmt.(*ast.LetStatement); ok {
+			name := string(let.Name.Value)
+			signatures = append(signatures, Signature{
+				Name: name,
+				Pos:  let.Name.Token.Pos,
+				Type: e.schemes[name].t.String(),
+			})
+		}
+	}
+
+	sort.SliceStable(in.errors, func(i, j int) bool {
+		if in.errors[i].Pos.Line != in.errors[j].Pos.Line {
+			return in.errors[i].Pos.Line < in.errors[j].Pos.Line
+		}
+		return in.errors[i].Pos.Column < in.errors[j].Pos.Column
+	})
+
+	return signatures, in.errors
+}
+
+// term is a type of the inference, either a type variable or a type constructor
+// applied to its arguments, e.g. array<a>. Functions are the fn constructor
+// with the parameters followed by the result as arguments.
+type term struct {
+	// name is the constructor, empty for type variables
+	name string
+	args []*term
+	// instance is the type a variable is bound to
+	instance *term
+	// pos is where a constructor originated, reported when it conflicts with
+	// another type
+	pos token.Position
+}
+
+func con(name string, pos token.Position, args ...*term) *term {
+	return &term{name: name, pos: pos, args: args}
+}
+
+// prune returns the type t is bound to, following the bindings of variables.
+func prune(t *term) *term {
+	for t.name == "" && t.instance != nil {
+		t = t.instance
+	}
+
+	return t
+}
+
+func (t *term) String() string {
+	names := map[*term]string{}
+	return t.format(names)
+}
+
+func (t *term) format(names map[*term]string) string {
+	t = prune(t)
+
+	switch {
+	case t.name == "":
+		name, ok := names[t]
+		if !ok {
+			name = variableName(len(names))
+			names[t] = name
+		}
+		return name
+	case t.name == "fn":
+		params := make([]string, len(t.args)-1)
+		for i, p := range t.args[:len(t.args)-1] {
+			params[i] = p.format(names)
+		}
+
+		result := t.args[len(t.args)-1].format(names)
+		if len(params) == 1 && prune(t.args[0]).name != "fn" {
+			return fmt.Sprintf("%s -> %s", params[0], result)
+		}
+		return fmt.Sprintf("(%s) -> %s", strings.Join(params, ", "), result)
+	case len(t.args) == 0:
+		return t.name
+	default:
+		args := make([]string, len(t.args))
+		for i, a := range t.args {
+			args[i] = a.format(names)
+		}
+		return fmt.Sprintf("%s<%s>", t.name, strings.Join(args, ", "))
+	}
+}
+
+// variableName returns a, b, ..., z, a1, b1...
+func variableName(i int) string {
+	name := string(rune('a' + i%26))
+	if i >= 26 {
+		name += fmt.Sprint(i / 26)
+	}
+
+	return name
+}
+
+// scheme is a type quantified over vars, e.g. the type of first is
+// forall a. array<a> -> a.
+type scheme struct {
+	vars []*term
+	t    *term
+}
+
+// env holds the schemes of the program or a function body, as blocks don't
+// create scopes in monkey.
+type env struct {
+	outer   *env
+	schemes map[string]*scheme
+}
+
+func newEnv(outer *env) *env {
+	return &env{outer: outer, schemes: make(map[string]*scheme)}
+}
+
+func (e *env) lookup(name string) (*scheme, bool) {
+	for en := e; en != nil; en = en.outer {
+		if s, ok := en.schemes[name]; ok {
+			return s, true
+		}
+	}
+
+	return nil, false
+}
+
+// freeVariables adds the unbound variables of the schemes in e to vars.
+func (e *env) freeVariables(vars map[*term]bool) {
+	for en := e; en != nil; en = en.outer {
+		for _, s := range en.schemes {
+			free := map[*term]bool{}
+			freeVariables(s.t, free)
+			for _, v := range s.vars {
+				delete(free, v)
+			}
+			for v := range free {
+				vars[v] = true
+			}
+		}
+	}
+}
+
+func freeVariables(t *term, vars map[*term]bool) {
+	t = prune(t)
+	if t.name == "" {
+		vars[t] = true
+		return
+	}
+	for _, a := range t.args {
+		freeVariables(a, vars)
+	}
+}
+
+type inferer struct {
+	errors []Error
+	// builtins caches the parsed schemes of builtins
+	builtins map[string]*scheme
+}
+
+func (in *inferer) errorf(pos, conflict token.Position, format string, a ...interface{}) {
+	if conflict == pos {
+		conflict = token.Position{}
+	}
+	in.errors = append(in.errors, Error{Pos: pos, Message: fmt.Sprintf(format, a...), Conflict: conflict})
+}
+
+// unify makes expected and actual the same type, reporting a failure at pos,
+// where actual is used. The failure conflicts with the origin of actual when it
+// comes from elsewhere, or else with the origin of expected.
+func (in *inferer) unify(expected, actual *term, pos token.Position) {
+	if !mismatch(expected, actual) {
+		return
+	}
+
+	a, b := prune(expected), prune(actual)
+	conflict := a.pos
+	if b.pos.IsValid() && b.pos != pos {
+		conflict = b.pos
+	}
+	switch {
+	case a.name == "":
+		in.errorf(pos, conflict, "cannot construct infinite type %s", b)
+	case b.name == "":
+		in.errorf(pos, conflict, "cannot construct infinite type %s", a)
+	default:
+		in.errorf(pos, conflict, "mismatched types %s and %s", b, a)
+	}
+}
+
+// mismatch binds the variables of a and b so they are the same type, reporting
+// whether they can't be. The bindings made before a mismatch are kept, so only
+// the first conflict of an expression is reported.
+func mismatch(a, b *term) bool {
+	a, b = prune(a), prune(b)
+
+	switch {
+	case a == b:
+		return false
+	case a.name == "":
+		if occurs(a, b) {
+			return true
+		}
+		a.instance = b
+		return false
+	case b.name == "":
+		return mismatch(b, a)
+	case a.name != b.name || len(a.args) != len(b.args):
+		return true
+	}
+
+	for i := range a.args {
+		if mismatch(a.args[i], b.args[i]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// occurs reports whether the variable v appears in t, binding v to t would
+// create an infinite type.
+func occurs(v, t *term) bool {
+	t = prune(t)
+	if t == v {
+		return true
+	}
+	for _, a := range t.args {
+		if occurs(v, a) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// generalize quantifies t over the variables that aren't free in e.
+func generalize(e *env, t *term) *scheme {
+	envVars := map[*term]bool{}
+	e.freeVariables(envVars)
+
+	vars := map[*term]bool{}
+	freeVariables(t, vars)
+
+	s := &scheme{t: t}
+	for v := range vars {
+		if !envVars[v] {
+			s.vars = append(s.vars, v)
+		}
+	}
+
+	return s
+}
+
+// instantiate returns a copy of the type of s with fresh variables. Constructors
+// without an origin, as in the schemes of builtins, originate at pos.
+func instantiate(s *scheme, pos token.Position) *term {
+	fresh := make(map[*term]*term, len(s.vars))
+	for _, v := range s.vars {
+		fresh[v] = &term{}
+	}
+
+	var cp func(t *term) *term
+	cp = func(t *term) *term {
+		t = prune(t)
+		if t.name == "" {
+			if f, ok := fresh[t]; ok {
+				return f
+			}
+			return t
+		}
+
+		c := &term{name: t.name, pos: t.pos, args: make([]*term, len(t.args))}
+		if !c.pos.IsValid() {
+			c.pos = pos
+		}
+		for i, a := range t.args {
+			c.args[i] = cp(a)
+		}
+		return c
+	}
+
+	return cp(s.t)
+}
+
+// annotation returns the type of an annotation, the any and fn annotations are
+// left to inference.
+func annotation(t *ast.TypeExpr) *term {
+	switch t.Name {
+	case "int", "float", "string", "bool", "null", "time", "duration":
+		return con(t.Name, t.Token.Pos)
+	case "array":
+		if len(t.Params) == 1 {
+			return con("array", t.Token.Pos, annotation(t.Params[0]))
+		}
+	case "hash":
+		if len(t.Params) == 2 {
+			return con("hash", t.Token.Pos, annotation(t.Params[0]), annotation(t.Params[1]))
+		}
+	}
+
+	return &term{}
+}
+
+// statement infers the type of stmt, ret is the result type of the enclosing
+// function, nil at the top level.
+func (in *inferer) statement(e *env, stmt ast.Statement, ret *term) *term {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		in.let(e, stmt, ret)
+		return con("null", stmt.Token.Pos)
+	case *ast.ReturnStatement:
+		t := in.expression(e, stmt.ReturnValue, ret)
+		if ret != nil {
+			in.unify(ret, t, ast.Pos(stmt.ReturnValue))
+		}
+		// statements after a return are never evaluated
+		return &term{}
+	case *ast.ExpressionStatement:
+		return in.expression(e, stmt.Expression, ret)
+	case *ast.BlockStatement:
+		return in.block(e, stmt, ret)
+	}
+
+	return &term{}
+}
+
+func (in *inferer) let(e *env, stmt *ast.LetStatement, ret *term) {
+	name := string(stmt.Name.Value)
+
+	var t *term
+	if _, ok := stmt.Value.(*ast.FunctionLiteral); ok {
+		// functions are bound before inferring their body so they can call
+		// themselves, recursive calls are monomorphic
+		self := &term{}
+		e.schemes[name] = &scheme{t: self}
+		t = in.expression(e, stmt.Value, ret)
+		in.unify(self, t, ast.Pos(stmt.Value))
+		delete(e.schemes, name)
+	} else {
+		t = in.expression(e, stmt.Value, ret)
+	}
+
+	if stmt.Name.Type != nil {
+		in.unify(annotation(stmt.Name.Type), t, ast.Pos(stmt.Value))
+	}
+
+	e.schemes[name] = generalize(e, t)
+}
+
+// block returns the type of the last statement of b, null when it's empty.
+func (in *inferer) block(e *env, b *ast.BlockStatement, ret *term) *term {
+	t := con("null", b.Token.Pos)
+	for _, stmt := range b.Statements {
+		t = in.statement(e, stmt, ret)
+	}
+
+	return t
+}
+
+func (in *inferer) expression(e *env, exp ast.Expression, ret *term) *term {
+	switch exp := exp.(type) {
+	case *ast.IntegerLiteral:
+		return con("int", exp.Token.Pos)
+	case *ast.FloatLiteral:
+		return con("float", exp.Token.Pos)
+	case *ast.StringLiteral:
+		return con("string", exp.Token.Pos)
+	case *ast.Boolean:
+		return con("bool", exp.Token.Pos)
+	case *ast.Identifier:
+		return in.identifier(e, exp)
+	case *ast.PrefixExpression:
+		right := in.expression(e, exp.Right, ret)
+		if exp.Operator == "-" && prune(right).name == "duration" {
+			return right
+		}
+		if exp.Operator == "-" {
+			return number(exp.Token.Pos, in.operand(right, exp.Token.Pos, exp.Right))
+		}
+		return con("bool", exp.Token.Pos)
+	case *ast.InfixExpression:
+		return in.infix(e, exp, ret)
+	case *ast.IfExpression:
+		in.expression(e, exp.Condition, ret)
+		consequence := in.block(e, exp.Consequence, ret)
+		if exp.Alternative != nil {
+			in.unify(consequence, in.block(e, exp.Alternative, ret), exp.Alternative.Token.Pos)
+		}
+		return consequence
+	case *ast.WhileExpression:
+		in.expression(e, exp.Condition, ret)
+		in.block(e, exp.Consequence, ret)
+		return con("null", exp.Token.Pos)
+	case *ast.FunctionLiteral:
+		return in.function(e, exp)
+	case *ast.CallExpression:
+		return in.call(e, exp, ret)
+	case *ast.ArrayLiteral:
+		elem := &term{}
+		for _, el := range exp.Elements {
+			in.unify(elem, in.expression(e, el, ret), ast.Pos(el))
+		}
+		return con("array", exp.Token.Pos, elem)
+	case *ast.HashLiteral:
+		key, value := &term{}, &term{}
+		for _, k := range ast.HashKeys(exp) {
+			in.unify(key, in.expression(e, k, ret), ast.Pos(k))
+			in.unify(value, in.expression(e, exp.Pairs[k], ret), ast.Pos(exp.Pairs[k]))
+		}
+		if k := prune(key); k.name != "" && !hashableTerm(k) {
+			in.errorf(k.pos, token.Position{}, "unusable as hash key: %s", k)
+		}
+		return con("hash", exp.Token.Pos, key, value)
+	case *ast.IndexExpression:
+		return in.index(e, exp, ret)
+	case *ast.SliceExpression:
+		return in.slice(e, exp, ret)
+	}
+
+	return &term{}
+}
+
+func (in *inferer) identifier(e *env, ident *ast.Identifier) *term {
+	name := string(ident.Value)
+	if s, ok := e.lookup(name); ok {
+		return instantiate(s, ident.Token.Pos)
+	}
+	if s := in.builtin(name); s != nil {
+		return instantiate(s, ident.Token.Pos)
+	}
+
+	// undefined names and builtins without a scheme are dynamic
+	return &term{}
+}
+
+// builtin returns the scheme of the builtin name, nil if there isn't one.
+func (in *inferer) builtin(name string) *scheme {
+	b, _ := evaluator.LookupBuiltin(name)
+	return in.scheme(name, b)
+}
+
+// scheme returns the scheme of the builtin b called name, e.g. math.sqrt for
+// module members, nil if b is nil or has no scheme.
+func (in *inferer) scheme(name string, b *object.Builtin) *scheme {
+	if s, ok := in.builtins[name]; ok {
+		return s
+	}
+
+	var s *scheme
+	if b != nil && b.Scheme != "" {
+		s = parseScheme(b.Scheme)
+	}
+	in.builtins[name] = s
+
+	return s
+}
+
+// member returns the type of a module member, e.g. math.pi.
+func (in *inferer) member(name string, member object.Object, pos token.Position) *term {
+	switch member := member.(type) {
+	case *object.Builtin:
+		if s := in.scheme(name, member); s != nil {
+			return instantiate(s, pos)
+		}
+	case *object.Integer:
+		return con("int", pos)
+	case *object.Float:
+		return con("float", pos)
+	case *object.String:
+		return con("string", pos)
+	}
+
+	return &term{}
+}
+
+// moduleMember returns the module member exp accesses and its name, e.g.
+// math.sqrt. It reports false when exp isn't a member of a module, including
+// when the name of the module is bound in e.
+func moduleMember(e *env, exp ast.Expression) (object.Object, string, bool) {
+	index, ok := exp.(*ast.IndexExpression)
+	if !ok {
+		return nil, "", false
+	}
+	ident, ok := index.Left.(*ast.Identifier)
+	if !ok {
+		return nil, "", false
+	}
+	key, ok := index.Index.(*ast.StringLiteral)
+	if !ok {
+		return nil, "", false
+	}
+
+	name := string(ident.Value)
+	if _, ok := e.lookup(name); ok {
+		return nil, "", false
+	}
+	// builtins shadow modules in the evaluator
+	if _, ok := evaluator.LookupBuiltin(name); ok {
+		return nil, "", false
+	}
+	module, ok := evaluator.LookupModule(name)
+	if !ok {
+		return nil, "", false
+	}
+	member, ok := module.Get(&object.String{Value: key.Value})
+
+	return member, name + "." + key.Value, ok
+}
+
+func (in *inferer) infix(e *env, exp *ast.InfixExpression, ret *term) *term {
+	left, right := in.expression(e, exp.Left, ret), in.expression(e, exp.Right, ret)
+	pos := exp.Token.Pos
+	if t, ok := in.timeInfix(exp, left, right); ok {
+		return t
+	}
+
+	switch exp.Operator {
+	case "+":
+		if float(left) || float(right) {
+			return in.arithmetic(exp, left, right)
+		}
+		in.unify(left, right, ast.Pos(exp.Right))
+		if t := prune(left); t.name != "" && t.name != "int" && t.name != "string" {
+			in.errorf(pos, t.pos, "operator + not defined on %s", t)
+		}
+		return left
+	case "==", "!=":
+		// values of different types are unequal, not a failure
+		return con("bool", pos)
+	case "<", ">":
+		in.arithmetic(exp, left, right)
+		return con("bool", pos)
+	default:
+		return in.arithmetic(exp, left, right)
+	}
+}
+
+// timeInfix infers an operator on a time or duration, reporting false when
+// neither operand is one. An unknown operand added to or subtracted from a time
+// or duration is a duration, one scaling a duration an int.
+func (in *inferer) timeInfix(exp *ast.InfixExpression, left, right *term) (*term, bool) {
+	l, r := prune(left), prune(right)
+	if !isTime(l.name) && !isTime(r.name) {
+		return nil, false
+	}
+	pos := exp.Token.Pos
+
+	switch exp.Operator {
+	case "==", "!=":
+		return con("bool", pos), true
+	case "<", ">":
+		in.unify(left, right, ast.Pos(exp.Right))
+	case "+", "-":
+		if r.name == "" {
+			in.unify(con("duration", pos), right, pos)
+		} else if l.name == "" {
+			in.unify(con("duration", pos), left, pos)
+		}
+	default:
+		for _, t := range []*term{left, right} {
+			if prune(t).name == "" {
+				in.unify(con("int", pos), t, pos)
+			}
+		}
+	}
+
+	l, r = prune(left), prune(right)
+	name, ok := timeOperation(exp.Operator, l.name, r.name)
+	if !ok {
+		in.errorf(pos, token.Position{}, "mismatched types %s %s %s", l, exp.Operator, r)
+		return &term{}, true
+	}
+
+	return con(name, pos), true
+}
+
+// arithmetic infers the operands of an arithmetic operator, the result is a
+// float when either of them is.
+func (in *inferer) arithmetic(exp *ast.InfixExpression, left, right *term) *term {
+	l := in.operand(left, exp.Token.Pos, exp.Left)
+	r := in.operand(right, exp.Token.Pos, exp.Right)
+
+	return number(exp.Token.Pos, l || r)
+}
+
+// operand infers a number, which is an int unless it's known to be a float. It
+// reports whether it's a float.
+func (in *inferer) operand(t *term, pos token.Position, exp ast.Expression) bool {
+	if float(t) {
+		return true
+	}
+	in.unify(con("int", pos), t, ast.Pos(exp))
+
+	return false
+}
+
+func number(pos token.Position, isFloat bool) *term {
+	if isFloat {
+		return con("float", pos)
+	}
+
+	return con("int", pos)
+}
+
+func float(t *term) bool {
+	return prune(t).name == "float"
+}
+
+func (in *inferer) function(e *env, fn *ast.FunctionLiteral) *term {
+	inner := newEnv(e)
+	args := make([]*term, len(fn.Parameters)+1)
+	for i, p := range fn.Parameters {
+		args[i] = &term{}
+		if p.Type != nil {
+			in.unify(annotation(p.Type), args[i], p.Token.Pos)
+		}
+		// parameters are monomorphic
+		inner.schemes[string(p.Value)] = &scheme{t: args[i]}
+	}
+
+	ret := &term{}
+	if fn.ReturnType != nil {
+		ret = annotation(fn.ReturnType)
+	}
+	args[len(args)-1] = ret
+
+	body := in.block(inner, fn.Body, ret)
+	pos := fn.Body.Token.Pos
+	if n := len(fn.Body.Statements); n > 0 {
+		pos = ast.Pos(fn.Body.Statements[n-1])
+	}
+	in.unify(ret, body, pos)
+
+	return con("fn", fn.Token.Pos, args...)
+}
+
+func (in *inferer) call(e *env, exp *ast.CallExpression, ret *term) *term {
+	callee := in.expression(e, exp.Function, ret)
+	args := make([]*term, len(exp.Arguments)+1)
+	for i, arg := range exp.Arguments {
+		args[i] = in.expression(e, arg, ret)
+	}
+	result := &term{}
+	args[len(args)-1] = result
+
+	fn := prune(callee)
+	if fn.name == "fn" {
+		fn = in.arity(e, exp, fn)
+	}
+	if fn.name == "fn" && len(fn.args) != len(args) {
+		in.errorf(
+			ast.Pos(exp), fn.pos, "wrong number of arguments to %s: got %d, want %d",
+			exp.Function, len(exp.Arguments), len(fn.args)-1,
+		)
+		in.unify(fn.args[len(fn.args)-1], result, ast.Pos(exp))
+		return result
+	}
+	if fn.name != "" && fn.name != "fn" {
+		in.errorf(ast.Pos(exp.Function), fn.pos, "cannot call %s of type %s", exp.Function, fn)
+		return result
+	}
+
+	// arguments are unified one by one so failures point to the argument
+	if fn.name == "fn" {
+		for i := range exp.Arguments {
+			in.unify(fn.args[i], args[i], ast.Pos(exp.Arguments[i]))
+		}
+		in.unify(fn.args[len(fn.args)-1], result, ast.Pos(exp))
+		return result
+	}
+
+	in.unify(fn, con("fn", ast.Pos(exp), args...), ast.Pos(exp))
+	return result
+}
+
+// arity adapts the type of a builtin called by name, or a module member, to the
+// number of arguments of the call, dropping optional parameters and repeating
+// variadic ones.
+func (in *inferer) arity(e *env, exp *ast.CallExpression, fn *term) *term {
+	var b *object.Builtin
+	switch callee := exp.Function.(type) {
+	case *ast.Identifier:
+		if _, ok := e.lookup(string(callee.Value)); ok {
+			return fn
+		}
+		b, _ = evaluator.LookupBuiltin(string(callee.Value))
+	case *ast.IndexExpression:
+		member, _, _ := moduleMember(e, callee)
+		b, _ = member.(*object.Builtin)
+	}
+	if b == nil {
+		return fn
+	}
+
+	n, params := len(exp.Arguments), append([]*term{}, fn.args[:len(fn.args)-1]...)
+	switch {
+	case n < b.MinArgs, b.MaxArgs != object.VARIADIC && n > b.MaxArgs:
+		return fn
+	case n <= len(params):
+		params = params[:n]
+	case b.MaxArgs == object.VARIADIC && len(params) > 0:
+		for len(params) < n {
+			params = append(params, params[len(params)-1])
+		}
+	}
+
+	return con("fn", fn.pos, append(params, fn.args[len(fn.args)-1])...)
+}
+
+func (in *inferer) index(e *env, exp *ast.IndexExpression, ret *term) *term {
+	if member, name, ok := moduleMember(e, exp); ok {
+		return in.member(name, member, exp.Token.Pos)
+	}
+
+	left, index := in.expression(e, exp.Left, ret), in.expression(e, exp.Index, ret)
+	pos := exp.Token.Pos
+
+	l := prune(left)
+	if l.name == "" {
+		switch prune(index).name {
+		case "int":
+			in.unify(left, con("array", pos, &term{}), pos)
+		case "string":
+			in.unify(left, con("hash", pos, con("string", pos), &term{}), pos)
+		default:
+			return &term{}
+		}
+		l = prune(left)
+	}
+
+	switch l.name {
+	case "array":
+		in.unify(con("int", pos), index, ast.Pos(exp.Index))
+		return l.args[0]
+	case "hash":
+		in.unify(l.args[0], index, ast.Pos(exp.Index))
+		return l.args[1]
+	case "time", "duration":
+		// the type of a field depends on its name
+		in.unify(con("string", pos), index, ast.Pos(exp.Index))
+		return &term{}
+	}
+
+	in.errorf(ast.Pos(exp.Left), l.pos, "index operator not supported: %s", l)
+	return &term{}
+}
+
+// slice infers a slice expression, which has the type of the sliced array or
+// string.
+func (in *inferer) slice(e *env, exp *ast.SliceExpression, ret *term) *term {
+	left := in.expression(e, exp.Left, ret)
+	for _, bound := range []ast.Expression{exp.Start, exp.End, exp.Step} {
+		if bound != nil {
+			in.unify(con("int", ast.Pos(bound)), in.expression(e, bound, ret), ast.Pos(bound))
+		}
+	}
+
+	if l := prune(left); l.name != "" && l.name != "array" && l.name != "string" {
+		in.errorf(ast.Pos(exp.Left), l.pos, "slice operator not supported: %s", l)
+		return &term{}
+	}
+
+	return left
+}
+
+func hashableTerm(t *term) bool {
+	return t.name == "int" || t.name == "string" || t.name == "bool"
+}
+
+// parseScheme parses the scheme of a builtin, e.g. (array<a>, a) -> array<a>.
+// Names other than the builtin types are type variables.
+func parseScheme(src string) *scheme {
+	p := &schemeParser{l: lexer.New([]byte(src)), vars: map[string]*term{}}
+	p.next()
+	t := p.parse()
+	if p.tok.Type != token.EOF {
+		panic(fmt.Sprintf("invalid type scheme %q", src))
+	}
+
+	s := &scheme{t: t}
+	for _, v := range p.vars {
+		s.vars = append(s.vars, v)
+	}
+
+	return s
+}
+
+type schemeParser struct {
+	l    *lexer.Lexer
+	tok  token.Token
+	vars map[string]*term
+}
+
+func (p *schemeParser) next() {
+	p.tok = p.l.NextToken()
+}
+
+func (p *schemeParser) expect(t token.TokenType) {
+	if p.tok.Type != t {
+		panic(fmt.Sprintf("invalid type scheme: expected %s, got %q", t, p.tok.Literal))
+	}
+	p.next()
+}
+
+func (p *schemeParser) parse() *term {
+	var params []*term
+	if p.tok.Type == token.LPAREN {
+		p.next()
+		for p.tok.Type != token.RPAREN {
+			params = append(params, p.parse())
+			if p.tok.Type == token.COMMA {
+				p.next()
+			}
+		}
+		p.next()
+		// a parenthesized type without an arrow is a grouping
+		if p.tok.Type != token.MINUS && len(params) == 1 {
+			return params[0]
+		}
+	} else {
+		params = append(params, p.atom())
+		if p.tok.Type != token.MINUS {
+			return params[0]
+		}
+	}
+
+	p.expect(token.MINUS)
+	p.expect(token.GT)
+
+	return con("fn", token.Position{}, append(params, p.parse())...)
+}
+
+func (p *schemeParser) atom() *term {
+	name := string(p.tok.Literal)
+	p.expect(token.IDENT)
+
+	switch name {
+	case "int", "float", "string", "bool", "null", "time", "duration":
+		return con(name, token.Position{})
+	case "array", "hash":
+		t := con(name, token.Position{})
+		p.expect(token.LT)
+		for {
+			t.args = append(t.args, p.parse())
+			if p.tok.Type != token.COMMA {
+				break
+			}
+			p.next()
+		}
+		p.expect(token.GT)
+		return t
+	}
+
+	v, ok := p.vars[name]
+	if !ok {
+		v = &term{}
+		p.vars[name] = v
+	}
+	return v
+}
@@ -0,0 +1,236 @@
+package types_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/gkampitakis/monkey/evaluator"
+	"github.com/gkampitakis/monkey/lexer"
+	"github.com/gkampitakis/monkey/parser"
+	"github.com/gkampitakis/monkey/types"
+	"github.com/stretchr/testify/require"
+)
+
+func testInfer(t *testing.T, input string) ([]string, []string) {
+	t.Helper()
+
+	p := parser.New(lexer.New([]byte(input)))
+	program := p.ParseProgram()
+	require.Empty(t, p.Errors())
+
+	signatures, errs := types.Infer(program)
+	sigs, errors := []string{}, []string{}
+	for _, s := range signatures {
+		sigs = append(sigs, s.String())
+	}
+	for _, err := range errs {
+		errors = append(errors, err.String())
+	}
+
+	return sigs, errors
+}
+
+func TestInfer(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		signatures []string
+		errors     []string
+	}{
+		{
+			"literals",
+			`let a = 1; let b = "s"; let c = !a; let d = [1, 2]; let e = {"a": true};`,
+			[]string{"a: int", "b: string", "c: bool", "d: array<int>", "e: hash<string, bool>"},
+			[]string{},
+		},
+		{
+			"polymorphic functions",
+			`let id = fn(x) { x };
+let compose = fn(f, g) { fn(x) { f(g(x)) } };
+let a = id(1);
+let b = id("s");`,
+			[]string{"id: a -> a", "compose: (a -> b, c -> a) -> c -> b", "a: int", "b: string"},
+			[]string{},
+		},
+		{
+			"builtins",
+			`let head = fn(xs) { first(xs) };
+let append = fn(xs, x) { push(xs, x, x) };
+let size = fn(xs) { len(xs) };
+let quit = fn() { exit() };`,
+			[]string{
+				"head: array<a> -> a",
+				"append: (array<a>, a) -> array<a>",
+				"size: a -> int",
+				"quit: () -> null",
+			},
+			[]string{},
+		},
+		{
+			"recursion",
+			`let map = fn(xs, f) {
+  if (len(xs) == 0) { return []; }
+  push(map(rest(xs), f), f(first(xs)))
+};
+let fact = fn(n) { if (n < 2) { 1 } else { n * fact(n - 1) } };`,
+			[]string{"map: (array<a>, a -> b) -> array<b>", "fact: int -> int"},
+			[]string{},
+		},
+		{
+			"index expressions",
+			`let at = fn(xs, i) { xs[i] };
+let get = fn(h) { h["key"] };
+let second = fn(xs) { xs[1] };`,
+			[]string{"at: (a, b) -> c", "get: hash<string, a> -> a", "second: array<a> -> a"},
+			[]string{},
+		},
+		{
+			"hash builtins",
+			`let names = fn(h) { keys(h) };
+let total = merge({"a": 1}, {"b": 2});
+let seen = has(total, "a");`,
+			[]string{"names: hash<a, b> -> array<a>", "total: hash<string, int>", "seen: bool"},
+			[]string{},
+		},
+		{
+			"collection builtins",
+			`let doubled = map(range(3), fn(x) { x * 2 });
+let total = reduce(doubled, fn(acc, x) { acc + x }, 0);
+let groups = group_by(["a", "bc"], len);
+let weight = sum([0.5, 1.5]);`,
+			[]string{"doubled: array<int>", "total: int", "groups: hash<int, array<string>>", "weight: float"},
+			[]string{},
+		},
+		{
+			"slice expressions",
+			`let tail = fn(xs) { xs[1:] };
+let evens = range(10)[::2];
+let word = "hello"[1:3];`,
+			[]string{"tail: a -> a", "evens: array<int>", "word: string"},
+			[]string{},
+		},
+		{
+			"floats",
+			`let half = fn(x) { x / 2.0 };
+let area = fn(r: float) { 3.14 * r ** 2 };
+let n = -1 + 2 ** 8;`,
+			[]string{"half: int -> float", "area: float -> float", "n: int"},
+			[]string{},
+		},
+		{
+			"times",
+			`let start = now();
+let later = fn(d) { start + d };
+let elapsed = later(duration(60)) - start;
+let ratio = elapsed / (elapsed * 2);
+let year = start.year;
+start * 2;`,
+			[]string{
+				"start: time", "later: duration -> time", "elapsed: duration", "ratio: float", "year: a",
+			},
+			[]string{"6:7: mismatched types time * int"},
+		},
+		{
+			"annotations",
+			`let inc = fn(x: int) { x + 1 };
+let xs: array<string> = [];`,
+			[]string{"inc: int -> int", "xs: array<string>"},
+			[]string{},
+		},
+		{
+			"mismatches report both locations",
+			`let fact = fn(n) { if (n < 2) { 1 } else { n * fact(n - 1) } };
+let bad = fact("x");
+let h = {"a": 1};
+h["a"] + "s";
+[1, "a"];`,
+			[]string{"fact: int -> int", "bad: int", "h: hash<string, int>"},
+			[]string{
+				"2:16: mismatched types string and int (conflicts with 1:26)",
+				"4:10: mismatched types string and int (conflicts with 3:15)",
+				"5:5: mismatched types string and int (conflicts with 5:2)",
+			},
+		},
+		{
+			"mismatches are reported where the type is used",
+			`let w = "s";
+let v: int = w;`,
+			[]string{"w: string", "v: string"},
+			[]string{"2:14: mismatched types string and int (conflicts with 1:9)"},
+		},
+		{
+			"equality of any types",
+			`let same = 1 == "1";
+let eq = fn(a, b) { a != b };
+let late = now() == 1;`,
+			[]string{"same: bool", "eq: (a, b) -> bool", "late: bool"},
+			[]string{},
+		},
+		{
+			"modules",
+			`let root = math.sqrt(2);
+let rounded = math.floor(2.5);
+let file = path.join("a", "b", "c");
+let half = math.pi / 2;
+let larger = math.max(1, 2.5);
+math.gcd("a", 1);`,
+			[]string{"root: float", "rounded: int", "file: string", "half: float", "larger: a"},
+			[]string{"6:10: mismatched types string and int (conflicts with 6:5)"},
+		},
+		{
+			"calls",
+			`let add = fn(a, b) { a + b };
+add(1);
+let n = 5;
+n(1);
+let f = fn(x) { x(x) };`,
+			[]string{"add: (a, a) -> a", "n: int", "f: a -> b"},
+			[]string{
+				"2:4: wrong number of arguments to add: got 1, want 2 (conflicts with 1:11)",
+				"4:1: cannot call n of type int (conflicts with 3:9)",
+				"5:18: cannot construct infinite type a -> b",
+			},
+		},
+		{
+			"operators",
+			`true + false;
+"a" < "b";
+-"a";`,
+			[]string{},
+			[]string{
+				"1:6: operator + not defined on bool (conflicts with 1:1)",
+				"2:1: mismatched types string and int (conflicts with 2:5)",
+				"2:7: mismatched types string and int (conflicts with 2:5)",
+				"3:2: mismatched types string and int (conflicts with 3:1)",
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			signatures, errors := testInfer(t, tc.input)
+
+			require.Equal(t, tc.signatures, signatures)
+			require.Equal(t, tc.errors, errors)
+		})
+	}
+}
+
+func TestInferBuiltinSchemes(t *testing.T) {
+	names := evaluator.BuiltinNames()
+	for _, module := range []string{"math", "path"} {
+		m, ok := evaluator.LookupModule(module)
+		require.True(t, ok)
+		for _, pair := range m.Pairs() {
+			names = append(names, module+"."+pair.Key.Inspect())
+		}
+	}
+
+	for _, name := range names {
+		t.Run(name, func(t *testing.T) {
+			require.NotPanics(t, func() {
+				testInfer(t, fmt.Sprintf("let f = %s;", name))
+			})
+		})
+	}
+}
@@ -0,0 +1,165 @@
+// Package types checks the optional type annotations of monkey programs and
+// infers the types of unannotated ones.
+//
+// Annotations are accepted on let statements, function parameters and function
+// results:
+//
+//	let xs: array<int> = [1, 2];
+//	let add = fn(a: int, b: int): int { a + b };
+//
+// Unannotated bindings and parameters are dynamic, they have the any type which
+// is compatible with every other type, so existing programs keep type checking.
+//
+// Infer instead runs Hindley-Milner inference over the whole program, deriving
+// polymorphic types for let-bound functions, e.g. array<a> -> a.
+package types
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Type is the static type of a monkey expression.
+type Type interface {
+	String() string
+}
+
+// Basic is a type without parameters, e.g. int.
+type Basic struct {
+	Name string
+}
+
+func (b *Basic) String() string { return b.Name }
+
+var (
+	Any    = &Basic{Name: "any"}
+	Int    = &Basic{Name: "int"}
+	Float  = &Basic{Name: "float"}
+	String = &Basic{Name: "string"}
+	Bool   = &Basic{Name: "bool"}
+	Null   = &Basic{Name: "null"}
+	// Time and Duration are the types of the values of the time builtins
+	Time     = &Basic{Name: "time"}
+	Duration = &Basic{Name: "duration"}
+)
+
+type Array struct {
+	Elem Type
+}
+
+func (a *Array) String() string { return fmt.Sprintf("array<%s>", a.Elem) }
+
+type Hash struct {
+	Key   Type
+	Value Type
+}
+
+func (h *Hash) String() string { return fmt.Sprintf("hash<%s, %s>", h.Key, h.Value) }
+
+// Function is the type of function values. Params is nil for the fn annotation,
+// which accepts functions of any signature.
+type Function struct {
+	Params []Type
+	Return Type
+}
+
+func (f *Function) String() string {
+	if f.Params == nil {
+		return "fn"
+	}
+
+	params := make([]string, len(f.Params))
+	for i, p := range f.Params {
+		params[i] = p.String()
+	}
+
+	return fmt.Sprintf("fn(%s): %s", strings.Join(params, ", "), f.Return)
+}
+
+// Assignable reports whether a value of type from can be used where type to is
+// expected.
+func Assignable(from, to Type) bool {
+	if from == Any || to == Any {
+		return true
+	}
+
+	switch to := to.(type) {
+	case *Basic:
+		return from == to
+	case *Array:
+		from, ok := from.(*Array)
+		return ok && Assignable(from.Elem, to.Elem)
+	case *Hash:
+		from, ok := from.(*Hash)
+		return ok && Assignable(from.Key, to.Key) && Assignable(from.Value, to.Value)
+	case *Function:
+		from, ok := from.(*Function)
+		if !ok {
+			return false
+		}
+		if to.Params == nil || from.Params == nil {
+			return true
+		}
+		if len(from.Params) != len(to.Params) {
+			return false
+		}
+		for i := range to.Params {
+			if !Assignable(to.Params[i], from.Params[i]) {
+				return false
+			}
+		}
+		return Assignable(from.Return, to.Return)
+	}
+
+	return false
+}
+
+// identical reports whether a and b are the same type.
+func identical(a, b Type) bool {
+	return a.String() == b.String()
+}
+
+// timeOperation returns the name of the type of an arithmetic or comparison
+// operator on a time or duration and another operand, named left and right. It
+// reports false when the operator isn't defined on them.
+func timeOperation(operator, left, right string) (string, bool) {
+	number := func(name string) bool { return name == "int" || name == "float" }
+
+	switch operator {
+	case "+":
+		switch {
+		case left == "duration" && right == "duration":
+			return "duration", true
+		case left == "time" && right == "duration", left == "duration" && right == "time":
+			return "time", true
+		}
+	case "-":
+		switch {
+		case left == "time" && right == "time", left == "duration" && right == "duration":
+			return "duration", true
+		case left == "time" && right == "duration":
+			return "time", true
+		}
+	case "*":
+		if (left == "duration" && number(right)) || (number(left) && right == "duration") {
+			return "duration", true
+		}
+	case "/":
+		switch {
+		case left == "duration" && right == "duration":
+			return "float", true
+		case left == "duration" && number(right):
+			return "duration", true
+		}
+	case "<", ">":
+		if left == right {
+			return "bool", true
+		}
+	}
+
+	return "", false
+}
+
+func isTime(name string) bool {
+	return name == "time" || name == "duration"
+}